@@ -1,9 +1,9 @@
 package capability_test
 
 import (
-	"io"
 	"testing"
 
+	"github.com/kkrull/gohttp/msg"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,8 +17,9 @@ type ServerCapabilityServerMock struct {
 	optionsCalled bool
 }
 
-func (mock *ServerCapabilityServerMock) Options(writer io.Writer) {
+func (mock *ServerCapabilityServerMock) Options(writer msg.ResponseWriter) error {
 	mock.optionsCalled = true
+	return nil
 }
 
 func (mock *ServerCapabilityServerMock) OptionsShouldHaveBeenCalled() {
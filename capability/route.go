@@ -1,9 +1,8 @@
 package capability
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/clienterror"
 )
 
@@ -37,12 +36,11 @@ type optionsRequest struct {
 	Resource ServerResource
 }
 
-func (request *optionsRequest) Handle(client io.Writer) error {
-	request.Resource.Options(client)
-	return nil
+func (request *optionsRequest) Handle(client msg.ResponseWriter) error {
+	return request.Resource.Options(client)
 }
 
 // Reports the global, generic capabilities of this server, without regard to resource or state
 type ServerResource interface {
-	Options(writer io.Writer)
+	Options(writer msg.ResponseWriter) error
 }
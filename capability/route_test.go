@@ -1,10 +1,9 @@
 package capability_test
 
 import (
-	"bufio"
-
 	"github.com/kkrull/gohttp/capability"
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -50,7 +49,7 @@ var _ = Describe("ServerCapabilityRoute", func() {
 			It("routes OPTIONS to ServerResource", func() {
 				requested = http.NewOptionsMessage("*")
 				routedRequest = router.Route(requested)
-				routedRequest.Handle(&bufio.Writer{})
+				routedRequest.Handle(&httptest.ResponseBuffer{})
 				controller.OptionsShouldHaveBeenCalled()
 			})
 
@@ -1,8 +1,6 @@
 package capability
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/success"
 )
@@ -12,6 +10,7 @@ type StaticCapabilityServer struct {
 	AvailableMethods []string
 }
 
-func (controller *StaticCapabilityServer) Options(client io.Writer) {
+func (controller *StaticCapabilityServer) Options(client msg.ResponseWriter) error {
 	msg.RespondWithAllowHeader(client, success.OKStatus, controller.AvailableMethods)
+	return nil
 }
@@ -1,8 +1,6 @@
 package capability_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/capability"
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
@@ -14,12 +12,12 @@ var _ = Describe("StaticCapabilityServer", func() {
 	var (
 		controller     capability.ServerResource
 		response       *httptest.ResponseMessage
-		responseBuffer *bytes.Buffer
+		responseBuffer *httptest.ResponseBuffer
 	)
 
 	Describe("#Options", func() {
 		BeforeEach(func() {
-			responseBuffer = &bytes.Buffer{}
+			responseBuffer = &httptest.ResponseBuffer{}
 			controller = &capability.StaticCapabilityServer{
 				AvailableMethods: []string{http.CONNECT, http.TRACE},
 			}
@@ -39,7 +37,7 @@ var _ = Describe("StaticCapabilityServer", func() {
 
 		Context("given 1 available method", func() {
 			BeforeEach(func() {
-				responseBuffer = &bytes.Buffer{}
+				responseBuffer = &httptest.ResponseBuffer{}
 				controller = &capability.StaticCapabilityServer{
 					AvailableMethods: []string{http.OPTIONS},
 				}
@@ -54,7 +52,7 @@ var _ = Describe("StaticCapabilityServer", func() {
 
 		Context("given 2 or more available methods", func() {
 			BeforeEach(func() {
-				responseBuffer = &bytes.Buffer{}
+				responseBuffer = &httptest.ResponseBuffer{}
 				controller = &capability.StaticCapabilityServer{
 					AvailableMethods: []string{http.CONNECT, http.TRACE},
 				}
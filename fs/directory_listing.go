@@ -1,7 +1,6 @@
 package fs
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"path"
@@ -15,56 +14,49 @@ import (
 type DirectoryListing struct {
 	Files      []string
 	HrefPrefix string
-	body       *bytes.Buffer
 }
 
 func (listing *DirectoryListing) Name() string {
 	return "Directory Listing"
 }
 
-func (listing *DirectoryListing) Get(client io.Writer, message http.RequestMessage) {
-	listing.Head(client, message)
-	msg.WriteBody(client, listing.body.String())
+func (listing *DirectoryListing) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	success.RespondOKWithStreamedBody(client, "text/html", listing.writeListingOfFiles)
+	return nil
 }
 
-func (listing *DirectoryListing) Head(client io.Writer, message http.RequestMessage) {
-	msg.WriteStatus(client, success.OKStatus)
-	msg.WriteContentTypeHeader(client, "text/html")
-
-	listing.body = listing.messageListingFiles()
-	msg.WriteContentLengthHeader(client, listing.body.Len())
-	msg.WriteEndOfMessageHeader(client)
+// Responds in the same way as Get, leaving it to the ResponseWriter to frame the response and omit the body
+func (listing *DirectoryListing) Head(client msg.ResponseWriter, message http.RequestMessage) error {
+	return listing.Get(client, message)
 }
 
-func (listing DirectoryListing) messageListingFiles() *bytes.Buffer {
-	message := &bytes.Buffer{}
-	message.WriteString("<html>\n")
+func (listing DirectoryListing) writeListingOfFiles(message io.Writer) {
+	io.WriteString(message, "<html>\n")
 	listing.writeHead(message)
 	listing.writeBody(message)
-	message.WriteString("</html>")
-	return message
+	io.WriteString(message, "</html>")
 }
 
-func (listing DirectoryListing) writeHead(message *bytes.Buffer) {
-	message.WriteString("<head>\n")
-	message.WriteString("<title>gohttp</title>\n")
-	message.WriteString("</head>\n")
+func (listing DirectoryListing) writeHead(message io.Writer) {
+	io.WriteString(message, "<head>\n")
+	io.WriteString(message, "<title>gohttp</title>\n")
+	io.WriteString(message, "</head>\n")
 }
 
-func (listing DirectoryListing) writeBody(message *bytes.Buffer) {
-	message.WriteString("<body>\n")
+func (listing DirectoryListing) writeBody(message io.Writer) {
+	io.WriteString(message, "<body>\n")
 	listing.writeFileListing(message)
-	message.WriteString("</body>\n")
+	io.WriteString(message, "</body>\n")
 }
 
-func (listing DirectoryListing) writeFileListing(message *bytes.Buffer) {
-	message.WriteString("<ul>\n")
+func (listing DirectoryListing) writeFileListing(message io.Writer) {
+	io.WriteString(message, "<ul>\n")
 	for _, file := range listing.Files {
-		message.WriteString(makeListItem(listing.makeLink(file)))
-		message.WriteString("\n")
+		io.WriteString(message, makeListItem(listing.makeLink(file)))
+		io.WriteString(message, "\n")
 	}
 
-	message.WriteString("</ul>\n")
+	io.WriteString(message, "</ul>\n")
 }
 
 func makeListItem(text string) string {
@@ -1,8 +1,6 @@
 package fs_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/fs"
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
@@ -14,7 +12,7 @@ var _ = Describe("DirectoryListing", func() {
 	var (
 		resource       *fs.DirectoryListing
 		response       *httptest.ResponseMessage
-		responseBuffer = &bytes.Buffer{}
+		responseBuffer = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -32,10 +30,8 @@ var _ = Describe("DirectoryListing", func() {
 			It("responds with 200 OK", func() {
 				response.StatusShouldBe(200, "OK")
 			})
-			It("sets Content-Length to the size of the message", func() {
-				contentLength, err := response.HeaderAsInt("Content-Length")
-				Expect(err).NotTo(HaveOccurred())
-				Expect(contentLength).To(BeNumerically(">", 0))
+			It("streams the message in chunks to an HTTP/1.1 client, instead of setting Content-Length", func() {
+				response.HeaderShould("Transfer-Encoding", Equal("chunked"))
 			})
 			It("sets Content-Type to text/html", func() {
 				response.HeaderShould("Content-Type", Equal("text/html"))
@@ -71,22 +67,54 @@ var _ = Describe("DirectoryListing", func() {
 	})
 
 	Describe("#Head", func() {
-		BeforeEach(func() {
-			resource = &fs.DirectoryListing{Files: []string{}}
-			resource.Head(responseBuffer, http.NewHeadMessage("/"))
-			response = httptest.ParseResponse(responseBuffer)
-		})
+		var getResponse *httptest.ResponseMessage
 
-		It("returns the same status as #Get", func() {
-			response.StatusShouldBe(200, "OK")
-		})
-		It("sets the same headers as #Get", func() {
-			contentLength, _ := response.HeaderAsInt("Content-Length")
-			Expect(contentLength).To(BeNumerically(">", 0))
-			response.HeaderShould("Content-Type", HavePrefix("text/html"))
+		respond := func(version string) {
+			resource = &fs.DirectoryListing{Files: []string{"one"}}
+			getBuffer := &httptest.ResponseBuffer{
+				Requested: &httptest.RequestMessage{MethodReturns: http.GET, PathReturns: "/", VersionReturns: version},
+			}
+			resource.Get(getBuffer, http.NewGetMessage("/"))
+			getResponse = httptest.ParseResponse(getBuffer)
+
+			headBuffer := &httptest.ResponseBuffer{
+				Requested: &httptest.RequestMessage{MethodReturns: http.HEAD, PathReturns: "/", VersionReturns: version},
+			}
+			resource.Head(headBuffer, http.NewHeadMessage("/"))
+			response = httptest.ParseResponse(headBuffer)
+		}
+
+		Context("given an HTTP/1.1 client", func() {
+			BeforeEach(func() {
+				respond(http.VERSION_1_1)
+			})
+
+			It("returns the same status as #Get", func() {
+				response.StatusShouldBe(200, "OK")
+			})
+			It("sets the same headers as #Get", func() {
+				getResponse.HeaderShould("Transfer-Encoding", Equal("chunked"))
+				response.HeaderShould("Transfer-Encoding", Equal("chunked"))
+				response.HeaderShould("Content-Type", HavePrefix("text/html"))
+			})
+			It("has no body", func() {
+				response.BodyShould(BeEmpty())
+			})
 		})
-		It("has no body", func() {
-			response.BodyShould(BeEmpty())
+
+		Context("given an HTTP/1.0 client, which does not understand chunking", func() {
+			BeforeEach(func() {
+				respond(http.VERSION_1_0)
+			})
+
+			It("closes the connection instead, like #Get", func() {
+				getResponse.HeaderShould("Connection", Equal("close"))
+				response.HeaderShould("Connection", Equal("close"))
+				Expect(response.Text).NotTo(ContainSubstring("Transfer-Encoding"))
+			})
+			It("has no body", func() {
+				response.BodyShould(BeEmpty())
+			})
 		})
 	})
 
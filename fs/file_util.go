@@ -1,11 +1,12 @@
 package fs
 
 import (
-	"io"
+	"context"
 	"mime"
 	"path"
 
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 )
 
 func contentTypeFromFileExtension(filename string) string {
@@ -43,7 +44,9 @@ func (ambiguousConditionalHeadersError) Error() string {
 
 // A view of all/part of a file
 type FileSlice interface {
-	WriteStatus(writer io.Writer)
-	WriteContentHeaders(writer io.Writer)
-	WriteBody(writer io.Writer)
+	WriteStatus(writer msg.ResponseWriter)
+	WriteContentHeaders(writer msg.ResponseWriter)
+
+	// Writes the slice of the file, stopping early once ctx is done
+	WriteBody(ctx context.Context, writer msg.ResponseWriter) error
 }
@@ -3,12 +3,12 @@ package fs_test
 import (
 	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"testing"
 
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -29,16 +29,18 @@ func (mock *FileSystemResourceMock) Name() string {
 	return "File system mock"
 }
 
-func (mock *FileSystemResourceMock) Get(client io.Writer, message http.RequestMessage) {
+func (mock *FileSystemResourceMock) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.getPath = message.Path()
+	return nil
 }
 
 func (mock *FileSystemResourceMock) GetShouldHaveReceived(path string) {
 	ExpectWithOffset(1, mock.getPath).To(Equal(path))
 }
 
-func (mock *FileSystemResourceMock) Head(client io.Writer, message http.RequestMessage) {
+func (mock *FileSystemResourceMock) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.headTarget = message.Target()
+	return nil
 }
 
 func (mock *FileSystemResourceMock) HeadShouldHaveReceived(target string) {
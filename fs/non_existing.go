@@ -2,7 +2,6 @@ package fs
 
 import (
 	"fmt"
-	"io"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg"
@@ -19,16 +18,21 @@ func (nonExisting *NonExisting) Name() string {
 	return "Non-existing file"
 }
 
-func (nonExisting *NonExisting) Get(client io.Writer, message http.RequestMessage) {
-	nonExisting.Head(client, message)
+func (nonExisting *NonExisting) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	if err := nonExisting.Head(client, message); err != nil {
+		return err
+	}
+
 	msg.WriteBody(client, nonExisting.body)
+	return nil
 }
 
-func (nonExisting *NonExisting) Head(client io.Writer, message http.RequestMessage) {
+func (nonExisting *NonExisting) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	msg.WriteStatus(client, clienterror.NotFoundStatus)
 	msg.WriteContentTypeHeader(client, "text/plain")
 
 	nonExisting.body = fmt.Sprintf("Not found: %s", nonExisting.Path)
 	msg.WriteContentLengthHeader(client, len(nonExisting.body))
 	msg.WriteEndOfMessageHeader(client)
+	return nil
 }
@@ -1,8 +1,6 @@
 package fs_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/fs"
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
@@ -14,7 +12,7 @@ var _ = Describe("NonExisting", func() {
 	var (
 		resource       *fs.NonExisting
 		response       *httptest.ResponseMessage
-		responseBuffer = &bytes.Buffer{}
+		responseBuffer = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -1,8 +1,6 @@
 package fs
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg"
 )
@@ -16,17 +14,21 @@ func (readableFile *ReadableFile) Name() string {
 	return "Existing file"
 }
 
-func (readableFile *ReadableFile) Get(client io.Writer, message http.RequestMessage) {
-	readableFile.Head(client, message)
+func (readableFile *ReadableFile) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	if err := readableFile.Head(client, message); err != nil {
+		return err
+	}
+
 	slice := readableFile.makeSliceOfTargetFile(message)
-	slice.WriteBody(client)
+	return slice.WriteBody(message.Context(), client)
 }
 
-func (readableFile *ReadableFile) Head(client io.Writer, message http.RequestMessage) {
+func (readableFile *ReadableFile) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	slice := readableFile.makeSliceOfTargetFile(message)
 	slice.WriteStatus(client)
 	slice.WriteContentHeaders(client)
 	msg.WriteEndOfMessageHeader(client)
+	return nil
 }
 
 func (readableFile *ReadableFile) makeSliceOfTargetFile(message http.RequestMessage) FileSlice {
@@ -1,7 +1,7 @@
 package fs_test
 
 import (
-	"bytes"
+	"context"
 	"os"
 	"path"
 
@@ -19,7 +19,7 @@ var _ = Describe("ReadableFile", func() {
 		existingFile string
 
 		response       *httptest.ResponseMessage
-		responseBuffer = &bytes.Buffer{}
+		responseBuffer = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -52,6 +52,23 @@ var _ = Describe("ReadableFile", func() {
 			})
 		})
 
+		Context("when the request's context is done", func() {
+			It("stops without writing the contents of the file", func() {
+				existingFile = path.Join(basePath, "readable.txt")
+				Expect(createTextFile(existingFile, "A")).To(Succeed())
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				resource = &fs.ReadableFile{Filename: existingFile}
+				resource.Get(responseBuffer, &httptest.RequestMessage{
+					ContextReturns: ctx,
+					MethodReturns:  http.GET,
+					PathReturns:    "/readable.txt",
+				})
+				Expect(responseBuffer.BodyBytesWritten()).To(BeEquivalentTo(0))
+			})
+		})
+
 		Context("when the path is a readable file named with a registered extension", func() {
 			BeforeEach(func() {
 				existingFile = path.Join(basePath, "image.jpeg")
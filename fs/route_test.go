@@ -1,12 +1,12 @@
 package fs_test
 
 import (
-	"bytes"
 	"os"
 	"path"
 
 	"github.com/kkrull/gohttp/fs"
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -30,7 +30,7 @@ var _ = Describe("FileSystemRoute", func() {
 			resource *FileSystemResourceMock
 
 			request        http.Request
-			responseBuffer = &bytes.Buffer{}
+			responseBuffer = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -81,22 +82,28 @@ type PartialSlice struct {
 	LastByteIndex  int64
 }
 
-func (slice *PartialSlice) WriteStatus(writer io.Writer) {
+func (slice *PartialSlice) WriteStatus(writer msg.ResponseWriter) {
 	msg.WriteStatus(writer, success.PartialContentStatus)
 }
 
-func (slice *PartialSlice) WriteContentHeaders(writer io.Writer) {
+func (slice *PartialSlice) WriteContentHeaders(writer msg.ResponseWriter) {
 	msg.WriteHeader(writer, "Content-Length", strconv.FormatInt(slice.len(), base10))
 	msg.WriteHeader(writer, "Content-Range", slice.contentRange())
 	msg.WriteContentTypeHeader(writer, slice.ContentType)
 }
 
-func (slice *PartialSlice) WriteBody(writer io.Writer) {
-	file, _ := os.Open(slice.Path)
+func (slice *PartialSlice) WriteBody(ctx context.Context, writer msg.ResponseWriter) error {
+	file, err := os.Open(slice.Path)
+	if err != nil {
+		return err
+	}
+
 	defer file.Close()
+	if _, err := file.Seek(slice.FirstByteIndex, 0); err != nil {
+		return err
+	}
 
-	file.Seek(slice.FirstByteIndex, 0)
-	io.CopyN(writer, file, slice.len())
+	return msg.CopyToBodyContext(ctx, writer, io.LimitReader(file, slice.len()))
 }
 
 func (slice *PartialSlice) contentRange() string {
@@ -118,16 +125,18 @@ type UnsupportedSlice struct {
 	NumBytes int64
 }
 
-func (slice *UnsupportedSlice) WriteStatus(writer io.Writer) {
+func (slice *UnsupportedSlice) WriteStatus(writer msg.ResponseWriter) {
 	msg.WriteStatus(writer, clienterror.RangeNotSatisfiableStatus)
 }
 
-func (slice *UnsupportedSlice) WriteContentHeaders(writer io.Writer) {
+func (slice *UnsupportedSlice) WriteContentHeaders(writer msg.ResponseWriter) {
 	msg.WriteContentLengthHeader(writer, 0)
 	msg.WriteHeader(writer, "Content-Range", fmt.Sprintf("bytes */%d", slice.NumBytes))
 }
 
-func (slice *UnsupportedSlice) WriteBody(writer io.Writer) { /* do nothing */ }
+func (slice *UnsupportedSlice) WriteBody(ctx context.Context, writer msg.ResponseWriter) error {
+	return nil
+}
 
 // A slice consisting of the entire file
 type WholeFile struct {
@@ -135,20 +144,24 @@ type WholeFile struct {
 	ContentType string
 }
 
-func (slice *WholeFile) WriteStatus(writer io.Writer) {
+func (slice *WholeFile) WriteStatus(writer msg.ResponseWriter) {
 	msg.WriteStatus(writer, success.OKStatus)
 }
 
-func (slice *WholeFile) WriteContentHeaders(writer io.Writer) {
+func (slice *WholeFile) WriteContentHeaders(writer msg.ResponseWriter) {
 	size, _ := sizeInBytes(slice.Path)
 	msg.WriteHeader(writer, "Content-Length", strconv.FormatInt(size, base10))
 	msg.WriteContentTypeHeader(writer, slice.ContentType)
 }
 
-func (slice *WholeFile) WriteBody(writer io.Writer) {
-	file, _ := os.Open(slice.Path)
+func (slice *WholeFile) WriteBody(ctx context.Context, writer msg.ResponseWriter) error {
+	file, err := os.Open(slice.Path)
+	if err != nil {
+		return err
+	}
+
 	defer file.Close()
-	msg.CopyToBody(writer, file)
+	return msg.CopyToBodyContext(ctx, writer, file)
 }
 
 func max(a, b int64) int64 {
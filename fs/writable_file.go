@@ -45,17 +45,21 @@ func (writableFile *WritableFile) Name() string {
 	return "Writable file"
 }
 
-func (writableFile *WritableFile) Get(client io.Writer, message http.RequestMessage) {
-	writableFile.Head(client, message)
+func (writableFile *WritableFile) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	if err := writableFile.Head(client, message); err != nil {
+		return err
+	}
+
 	slice := writableFile.makeSliceOfTargetFile(message)
-	slice.WriteBody(client)
+	return slice.WriteBody(message.Context(), client)
 }
 
-func (writableFile *WritableFile) Head(client io.Writer, message http.RequestMessage) {
+func (writableFile *WritableFile) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	slice := writableFile.makeSliceOfTargetFile(message)
 	slice.WriteStatus(client)
 	slice.WriteContentHeaders(client)
 	msg.WriteEndOfMessageHeader(client)
+	return nil
 }
 
 func (writableFile *WritableFile) makeSliceOfTargetFile(message http.RequestMessage) FileSlice {
@@ -71,37 +75,39 @@ func (writableFile *WritableFile) makeSliceOfTargetFile(message http.RequestMess
 	return ParseByteRange(rangeHeaders[0], writableFile.Filename, contentType)
 }
 
-func (writableFile *WritableFile) Patch(client io.Writer, message http.RequestMessage) {
+func (writableFile *WritableFile) Patch(client msg.ResponseWriter, message http.RequestMessage) error {
 	conditionalHeader, err := onlyConditionalHeader(message)
 	if err != nil {
 		msg.WriteStatus(client, clienterror.ConflictStatus)
 		msg.WriteEndOfMessageHeader(client)
-		return
+		return nil
 	}
 
 	if !writableFile.preconditionMatches(conditionalHeader) {
 		msg.WriteStatus(client, clienterror.PreconditionFailedStatus)
 		msg.WriteEndOfMessageHeader(client)
-		return
+		return nil
 	}
 
 	if err := writableFile.overwriteFile(message.Body()); err != nil {
 		msg.WriteStatus(client, servererror.InternalServerErrorStatus)
 		msg.WriteEndOfMessageHeader(client)
-		return
+		return nil
 	}
 
 	writableFile.successfulPatch(client, message.Path())
+	return nil
 }
 
-func (writableFile *WritableFile) Put(client io.Writer, message http.RequestMessage) {
+func (writableFile *WritableFile) Put(client msg.ResponseWriter, message http.RequestMessage) error {
 	if err := writableFile.overwriteFile(message.Body()); err != nil {
 		msg.WriteStatus(client, servererror.InternalServerErrorStatus)
 		msg.WriteEndOfMessageHeader(client)
-		return
+		return nil
 	}
 
 	writableFile.successfulPut(client, message.Path())
+	return nil
 }
 
 func (writableFile *WritableFile) validatorTag() string {
@@ -125,14 +131,14 @@ func (writableFile *WritableFile) overwriteFile(body []byte) error {
 	return ioutil.WriteFile(writableFile.Filename, body, os.ModePerm)
 }
 
-func (writableFile *WritableFile) successfulPatch(client io.Writer, path string) {
+func (writableFile *WritableFile) successfulPatch(client msg.ResponseWriter, path string) {
 	msg.WriteStatus(client, success.NoContentStatus)
 	msg.WriteHeader(client, "Content-Location", path)
 	msg.WriteHeader(client, "ETag", writableFile.validatorTag())
 	msg.WriteEndOfMessageHeader(client)
 }
 
-func (writableFile *WritableFile) successfulPut(client io.Writer, path string) {
+func (writableFile *WritableFile) successfulPut(client msg.ResponseWriter, path string) {
 	msg.WriteStatus(client, success.OKStatus)
 	msg.WriteEndOfMessageHeader(client)
 }
@@ -1,7 +1,6 @@
 package fs_test
 
 import (
-	"bytes"
 	"io/ioutil"
 	"os"
 	"path"
@@ -20,7 +19,7 @@ var _ = Describe("WritableFile", func() {
 		existingFile string
 
 		response       *httptest.ResponseMessage
-		responseBuffer = &bytes.Buffer{}
+		responseBuffer = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -0,0 +1,274 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kkrull/gohttp/msg/servererror"
+)
+
+// What to do with connections that arrive while every connection handler is busy
+type ExcessConnectionPolicy int
+
+const (
+	// Leaves excess connections in the listener's backlog, until a handler is free to accept them
+	QueueExcessConnections ExcessConnectionPolicy = iota
+
+	// Accepts excess connections right away, only to answer 503 Service Unavailable and close them.
+	// Once maxRejectingConnections are being answered, any more are closed without a response.
+	RejectExcessConnections
+)
+
+// How many excess connections to answer with 503 Service Unavailable at once
+const maxRejectingConnections = 16
+
+// Accepts connections on a listener and hands each one to a ConnectionHandler, with at most maxConnections at a time.
+// It blocks while waiting for a connection or a free handler, and stops once the listener is closed.
+// It keeps track of the connections it is handling, so that it can drain them or close them all at once.
+type acceptor struct {
+	listener      net.Listener
+	handler       ConnectionHandler
+	handlerTokens chan uint
+	rejectTokens  chan struct{}
+
+	excessPolicy      ExcessConnectionPolicy
+	retryAfterSeconds uint
+	onAcceptError     func(err error)
+	onHandlerPanic    func(err error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex             sync.Mutex
+	connections       map[*trackedConn]bool
+	rejecting         map[net.Conn]bool
+	stopped           bool
+	allClosed         chan struct{}
+	numForciblyClosed int
+}
+
+func newAcceptor(listener net.Listener, handler ConnectionHandler, maxConnections uint) *acceptor {
+	handlerTokens := make(chan uint, maxConnections)
+	for i := uint(1); i <= maxConnections; i++ {
+		handlerTokens <- i
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &acceptor{
+		ctx:            ctx,
+		cancel:         cancel,
+		listener:       listener,
+		handler:        handler,
+		handlerTokens:  handlerTokens,
+		rejectTokens:   make(chan struct{}, maxRejectingConnections),
+		onAcceptError:  func(error) {},
+		onHandlerPanic: logError,
+		connections:    make(map[*trackedConn]bool),
+		rejecting:      make(map[net.Conn]bool),
+	}
+}
+
+func (acceptor *acceptor) acceptConnections() {
+	for {
+		token, haveToken := acceptor.awaitHandlerToken()
+		conn, err := acceptor.listener.Accept()
+		switch {
+		case errors.Is(err, net.ErrClosed):
+			acceptor.releaseHandlerToken(token, haveToken)
+			return
+		case err != nil:
+			acceptor.onAcceptError(err)
+			acceptor.releaseHandlerToken(token, haveToken)
+			time.Sleep(acceptErrorDelay)
+		case haveToken:
+			go acceptor.handle(conn, token)
+		default:
+			acceptor.handleOrReject(conn)
+		}
+	}
+}
+
+// How long to wait after an error accepting a connection, such as running out of file descriptors, before trying again
+const acceptErrorDelay = 10 * time.Millisecond
+
+func (acceptor *acceptor) awaitHandlerToken() (token uint, haveToken bool) {
+	if acceptor.excessPolicy == RejectExcessConnections {
+		return 0, false
+	}
+
+	return <-acceptor.handlerTokens, true
+}
+
+func (acceptor *acceptor) releaseHandlerToken(token uint, haveToken bool) {
+	if haveToken {
+		acceptor.handlerTokens <- token
+	}
+}
+
+// Handles a connection that was accepted without waiting for a handler, if one happens to be free.  Otherwise it
+// rejects the connection, or just closes it when too many others are already being rejected.
+func (acceptor *acceptor) handleOrReject(conn net.Conn) {
+	select {
+	case token := <-acceptor.handlerTokens:
+		go acceptor.handle(conn, token)
+		return
+	default:
+	}
+
+	select {
+	case acceptor.rejectTokens <- struct{}{}:
+		go acceptor.reject(conn)
+	default:
+		_ = conn.Close()
+	}
+}
+
+// Handles a connection, returning its handler token and closing it even if the handler panics
+func (acceptor *acceptor) handle(conn net.Conn, token uint) {
+	defer func() { acceptor.handlerTokens <- token }()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			_ = conn.Close()
+			acceptor.onHandlerPanic(newPanicError(recovered))
+		}
+	}()
+
+	tracked, ok := acceptor.track(conn)
+	if !ok {
+		_ = conn.Close()
+		return
+	}
+
+	defer acceptor.untrack(tracked)
+	acceptor.handler.Handle(acceptor.ctx, bufio.NewReader(tracked), tracked)
+	_ = tracked.Close()
+}
+
+func (acceptor *acceptor) track(conn net.Conn) (tracked *trackedConn, ok bool) {
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	if acceptor.stopped {
+		return nil, false
+	}
+
+	tracked = &trackedConn{Conn: conn}
+	acceptor.connections[tracked] = true
+	return tracked, true
+}
+
+func (acceptor *acceptor) untrack(conn *trackedConn) {
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	delete(acceptor.connections, conn)
+	if len(acceptor.connections) == 0 && acceptor.allClosed != nil {
+		close(acceptor.allClosed)
+		acceptor.allClosed = nil
+	}
+}
+
+// Closes idle connections and lets the others finish their current request, waiting up to timeout before closing
+// any that remain.  The listener should already be closed.  Returns how many connections were forcibly closed.
+// Once connections are forcibly closed, here or by closeConnections, it returns without waiting for their handlers to
+// return, since a handler that ignores its context could take any amount of time.
+func (acceptor *acceptor) drain(timeout time.Duration) (numForciblyClosed int) {
+	allClosed := acceptor.drainConnections()
+	if allClosed != nil {
+		select {
+		case <-allClosed:
+		case <-acceptor.ctx.Done():
+		case <-time.After(timeout):
+			acceptor.closeConnections()
+		}
+	}
+
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	return acceptor.numForciblyClosed
+}
+
+// Marks every connection as draining, returning a channel that closes once they are all closed or nil if there are none
+func (acceptor *acceptor) drainConnections() (allClosed <-chan struct{}) {
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	acceptor.stopped = true
+	for conn := range acceptor.connections {
+		conn.drain()
+	}
+
+	if len(acceptor.connections) == 0 {
+		return nil
+	} else if acceptor.allClosed == nil {
+		acceptor.allClosed = make(chan struct{})
+	}
+
+	return acceptor.allClosed
+}
+
+// Forcibly closes every connection, whether or not it is in the middle of a request, and cancels their requests
+func (acceptor *acceptor) closeConnections() {
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	acceptor.cancel()
+	acceptor.stopped = true
+	for conn := range acceptor.connections {
+		if conn.forceClose() {
+			acceptor.numForciblyClosed++
+		}
+	}
+
+	for conn := range acceptor.rejecting {
+		_ = conn.Close()
+	}
+}
+
+// Answers 503 Service Unavailable, then gives the client a moment to read it before closing the connection.
+// It returns its reject token when it is done, and closeConnections can close the connection before then.
+func (acceptor *acceptor) reject(conn net.Conn) {
+	defer func() { <-acceptor.rejectTokens }()
+	defer conn.Close()
+	if !acceptor.trackRejected(conn) {
+		return
+	}
+
+	defer acceptor.untrackRejected(conn)
+	_ = conn.SetWriteDeadline(time.Now().Add(rejectLingerTime))
+	response := NewResponseWriter(conn)
+	response.Header().Set("Connection", "close")
+	unavailable := &servererror.ServiceUnavailable{RetryAfterSeconds: acceptor.retryAfterSeconds}
+	unavailable.WriteTo(response)
+	response.EndMessage()
+
+	if halfCloser, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = halfCloser.CloseWrite()
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(rejectLingerTime))
+	_, _ = io.Copy(ioutil.Discard, conn)
+}
+
+// How long to spend writing the response to a rejected request, and then how long to keep reading (and discarding)
+// the request, so that closing the connection does not reset it before the client has read the response
+const rejectLingerTime = 1 * time.Second
+
+func (acceptor *acceptor) trackRejected(conn net.Conn) (ok bool) {
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	if acceptor.stopped {
+		return false
+	}
+
+	acceptor.rejecting[conn] = true
+	return true
+}
+
+func (acceptor *acceptor) untrackRejected(conn net.Conn) {
+	acceptor.mutex.Lock()
+	defer acceptor.mutex.Unlock()
+	delete(acceptor.rejecting, conn)
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// The first file descriptor of a socket that systemd passes, after standard input, output, and error
+const listenFdsStart = 3
+
+// Listening sockets that were passed to this process with systemd's socket activation protocol, in order.
+// There are none when the process was not started that way, or when LISTEN_PID is for some other process.
+// LISTEN_PID, LISTEN_FDS, and LISTEN_FDNAMES are unset afterwards, so they do not leak into child processes.
+func ActivationListeners() ([]net.Listener, error) {
+	defer unsetActivationEnvironment()
+	pid, pidErr := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if pidErr != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	numFds, fdsErr := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if fdsErr != nil || numFds < 0 {
+		return nil, fmt.Errorf("ActivationListeners: invalid LISTEN_FDS: %s", os.Getenv("LISTEN_FDS"))
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make([]net.Listener, 0, numFds)
+	for i := 0; i < numFds; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := fmt.Sprintf("LISTEN_FD_%d", fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		listener, listenErr := fileListener(uintptr(fd), name)
+		if listenErr != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+
+			return nil, listenErr
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// A listener for the socket with the given file descriptor, which is closed in favor of the listener's own copy
+func fileListener(fd uintptr, name string) (net.Listener, error) {
+	file := os.NewFile(fd, name)
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+func unsetActivationEnvironment() {
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+}
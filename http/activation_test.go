@@ -0,0 +1,118 @@
+package http_test
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kkrull/gohttp/http"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ActivationListeners", func() {
+	AfterEach(func() {
+		Expect(os.Unsetenv("LISTEN_PID")).To(Succeed())
+		Expect(os.Unsetenv("LISTEN_FDS")).To(Succeed())
+	})
+
+	Context("when the process was not socket-activated", func() {
+		It("returns no listeners", func() {
+			Expect(http.ActivationListeners()).To(BeEmpty())
+		})
+	})
+
+	Context("when LISTEN_PID is for some other process", func() {
+		BeforeEach(func() {
+			Expect(os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))).To(Succeed())
+			Expect(os.Setenv("LISTEN_FDS", "1")).To(Succeed())
+		})
+
+		It("returns no listeners", func() {
+			Expect(http.ActivationListeners()).To(BeEmpty())
+		})
+
+		It("unsets the environment variables, so they do not leak into child processes", func() {
+			_, _ = http.ActivationListeners()
+			Expect(os.LookupEnv("LISTEN_PID")).To(BeEmpty())
+			Expect(os.LookupEnv("LISTEN_FDS")).To(BeEmpty())
+		})
+	})
+
+	Context("when LISTEN_FDS is not a number", func() {
+		BeforeEach(func() {
+			Expect(os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))).To(Succeed())
+			Expect(os.Setenv("LISTEN_FDS", "bogus")).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			_, err := http.ActivationListeners()
+			Expect(err).To(MatchError("ActivationListeners: invalid LISTEN_FDS: bogus"))
+		})
+	})
+
+	Context("when a process is started with an inherited listening socket", func() {
+		var (
+			child *exec.Cmd
+			conn  net.Conn
+		)
+
+		BeforeEach(func() {
+			listener, listenErr := net.Listen("tcp", "localhost:0")
+			Expect(listenErr).NotTo(HaveOccurred())
+			file, fileErr := listener.(*net.TCPListener).File()
+			Expect(fileErr).NotTo(HaveOccurred())
+
+			child = exec.Command(os.Args[0], "-test.run=^TestActivationHelperProcess$")
+			child.Env = append(os.Environ(), "GOHTTP_ACTIVATION_HELPER=1", "LISTEN_FDS=1")
+			child.ExtraFiles = []*os.File{file}
+			Expect(child.Start()).To(Succeed())
+
+			Expect(file.Close()).To(Succeed())
+			Expect(listener.Close()).To(Succeed())
+
+			var dialErr error
+			conn, dialErr = net.Dial("tcp", listener.Addr().String())
+			Expect(dialErr).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(conn.Close()).To(Succeed())
+			Expect(child.Process.Kill()).To(Succeed())
+			_ = child.Wait()
+		})
+
+		It("the child process serves HTTP on the socket, without binding to the port itself", func(done Done) {
+			writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+			expectHttpResponse(conn)
+			close(done)
+		}, 10)
+	})
+})
+
+// Runs in the child process of an ActivationListeners spec, serving on the socket it inherits like systemd would
+// start it.  LISTEN_PID is set here, since the parent does not know the child's process ID until it has started.
+func TestActivationHelperProcess(t *testing.T) {
+	if os.Getenv("GOHTTP_ACTIVATION_HELPER") != "1" {
+		return
+	}
+
+	_ = os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	listeners, err := http.ActivationListeners()
+	if err != nil || len(listeners) != 1 {
+		os.Exit(2)
+	}
+
+	server := http.TCPServerBuilder("localhost").
+		ListeningWith(http.NewExistingListener(listeners[0])).
+		Build()
+	if server.Start() != nil {
+		os.Exit(3)
+	}
+
+	time.Sleep(10 * time.Second)
+	os.Exit(0)
+}
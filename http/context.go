@@ -0,0 +1,46 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// Makes a context for one request, which is cancelled when the write timeout passes or ctx is done
+func requestContext(ctx context.Context, writeTimeout time.Duration) (context.Context, context.CancelFunc) {
+	if writeTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, writeTimeout)
+}
+
+// Reads ahead on the connection while a request is being handled, calling cancel if the connection fails, such as when
+// the client resets it.  A client that closes its side of the connection after sending the request is only done
+// sending, so the watch ends at io.EOF without calling cancel and the client still gets the whole response.
+// The returned function stops watching, and must be called before reading the next request.
+// Connections that do not support deadlines are not watched, since there would be no way to stop reading them.
+func watchForDisconnect(requestReader *bufio.Reader, deadlines *connectionDeadlines,
+	cancel context.CancelFunc) (stopWatching func()) {
+	if deadlines.conn == nil {
+		return func() {}
+	}
+
+	deadlines.readWithin(0)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		_, err := requestReader.Peek(1)
+		if err != nil && err != io.EOF && !errors.Is(err, os.ErrDeadlineExceeded) {
+			cancel()
+		}
+	}()
+
+	return func() {
+		deadlines.interruptRead()
+		<-stopped
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net"
+	"sync"
+)
+
+// A connection that a server may close while it is idle between requests, such as when it is draining.
+// Connection handlers that support it mark the connection idle while they wait for the next request.
+type idleConnection interface {
+	// Marks the connection as idle, returning false instead when the server wants the connection closed
+	StartIdle() bool
+	EndIdle()
+
+	// Whether the server wants the connection closed after the current response
+	Draining() bool
+}
+
+// A connection that the acceptor keeps track of, so that it can drain or forcibly close it later
+type trackedConn struct {
+	net.Conn
+	mutex    sync.Mutex
+	idle     bool
+	draining bool
+	closed   bool
+}
+
+func (conn *trackedConn) StartIdle() bool {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.draining {
+		return false
+	}
+
+	conn.idle = true
+	return true
+}
+
+func (conn *trackedConn) EndIdle() {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.idle = false
+}
+
+func (conn *trackedConn) Draining() bool {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	return conn.draining
+}
+
+// Closes the connection right away if it is idle, or marks it to close after the response it is working on
+func (conn *trackedConn) drain() {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.draining = true
+	if conn.idle && !conn.closed {
+		conn.closed = true
+		_ = conn.Conn.Close()
+	}
+}
+
+// Closes the connection, returning true if it was still open
+func (conn *trackedConn) forceClose() bool {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.closed {
+		return false
+	}
+
+	conn.closed = true
+	_ = conn.Conn.Close()
+	return true
+}
+
+func (conn *trackedConn) Close() error {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.closed {
+		return nil
+	}
+
+	conn.closed = true
+	return conn.Conn.Close()
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+var errNoResponse = errors.New("http: request was handled without writing a response")
+
+// A panic that was recovered while handling a connection, with the stack trace of the goroutine that panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func newPanicError(recovered interface{}) *PanicError {
+	return &PanicError{Value: recovered, Stack: debug.Stack()}
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("http: panic handling request: %v\n%s", err.Value, err.Stack)
+}
+
+// Logs an error to standard error
+func logError(err error) {
+	log.Println(err)
+}
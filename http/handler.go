@@ -2,48 +2,213 @@ package http
 
 import (
 	"bufio"
+	"context"
 	"io"
+	"strings"
+	"sync/atomic"
 
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/clienterror"
 	"github.com/kkrull/gohttp/msg/servererror"
 )
 
 func NewConnectionHandler(router Router) ConnectionHandler {
-	return &blockingConnectionHandler{Router: router}
+	return newBlockingConnectionHandler(router, DefaultRequestLimits, DefaultTimeouts)
 }
 
-// A ConnectionHandler that uses blocking I/O
+func newBlockingConnectionHandler(router Router, limits RequestLimits, timeouts Timeouts) *blockingConnectionHandler {
+	handler := &blockingConnectionHandler{
+		Parser:   &LineRequestParser{Limits: limits},
+		Timeouts: timeouts,
+		OnError:  logError,
+	}
+	handler.SetRouter(router)
+	return handler
+}
+
+// A ConnectionHandler that uses blocking I/O to handle 1 or more requests on the same connection.
+// Pipelined requests are handled one at a time, so responses are written in the order the requests were received.
+// Timeouts only apply when responseWriter is a connection that supports deadlines, like net.Conn.
+// Errors and panics from routing or handling a request are passed to OnError, and answered with 500 Internal Server
+// Error if nothing has been written yet.  Otherwise the connection is closed, since the response is incomplete.
+// The Router can be replaced while connections are open, which applies to the next request on each connection.
 type blockingConnectionHandler struct {
-	Router Router
+	Parser   RequestParser
+	router   atomic.Value
+	Timeouts Timeouts
+	OnError  func(err error)
+}
+
+// Holds a Router in an atomic.Value, which needs every value to have the same concrete type
+type routerValue struct {
+	Router
+}
+
+// Routes each request after this one with the given Router.  Requests that were already routed finish with the old one.
+func (handler *blockingConnectionHandler) SetRouter(router Router) {
+	handler.router.Store(routerValue{router})
+}
+
+func (handler *blockingConnectionHandler) currentRouter() Router {
+	return handler.router.Load().(routerValue).Router
+}
+
+func (handler *blockingConnectionHandler) Handle(ctx context.Context, requestReader *bufio.Reader,
+	responseWriter io.Writer) {
+	deadlines := deadlinesFor(responseWriter)
+	for {
+		if keepAlive := handler.handleRequest(ctx, requestReader, responseWriter, deadlines); !keepAlive {
+			return
+		} else if !handler.awaitNextRequest(requestReader, deadlines) {
+			return
+		}
+	}
 }
 
-func (handler *blockingConnectionHandler) Handle(requestReader *bufio.Reader, responseWriter io.Writer) {
-	request, routeErrorResponse := handler.Router.RouteRequest(requestReader)
+func (handler *blockingConnectionHandler) handleRequest(ctx context.Context, requestReader *bufio.Reader,
+	responseWriter io.Writer, deadlines *connectionDeadlines) (keepAlive bool) {
+	deadlines.readWithin(handler.Timeouts.ReadHeader)
+	requested, parseErrorResponse := handler.Parser.Parse(requestReader, func() {
+		deadlines.readWithin(handler.Timeouts.ReadBody)
+	})
+
+	deadlines.writeWithin(handler.Timeouts.Write)
+	if parseErrorResponse != nil {
+		if deadlines.readTimedOut() {
+			parseErrorResponse = &clienterror.RequestTimeout{}
+		}
+
+		response := NewResponseWriter(responseWriter)
+		response.Header().Set("Connection", "close")
+		parseErrorResponse.WriteTo(response)
+		response.EndMessage()
+		return false
+	}
+
+	requestCtx, cancel := requestContext(ctx, handler.Timeouts.Write)
+	defer cancel()
+	defer watchForDisconnect(requestReader, deadlines, cancel)()
+	requested.SetContext(requestCtx)
+
+	response := newResponseWriterFor(responseWriter, requested)
+	keepAlive = isPersistent(requested) && !isDraining(responseWriter)
+	if !keepAlive {
+		response.Header().Set("Connection", "close")
+	} else if requested.Version() == VERSION_1_0 {
+		response.Header().Set("Connection", "keep-alive")
+	}
+
+	if err := handler.respond(requested, response); err != nil {
+		if requestCtx.Err() == nil {
+			handler.OnError(err)
+		}
+
+		if !response.discardHeader() {
+			return false
+		}
+
+		keepAlive = false
+		response.Header().Set("Connection", "close")
+		internalError := servererror.InternalServerError{}
+		internalError.WriteTo(response)
+	}
+
+	if err := response.EndMessage(); err != nil {
+		return false
+	}
+
+	return keepAlive && !closesConnection(response)
+}
+
+// Routes the request and handles it, returning any error or recovered panic
+func (handler *blockingConnectionHandler) respond(requested RequestMessage, response msg.ResponseWriter) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = newPanicError(recovered)
+		}
+	}()
+
+	request, routeErrorResponse := handler.currentRouter().RouteRequest(requested)
 	if routeErrorResponse != nil {
-		routeErrorResponse.WriteTo(responseWriter)
-		return
+		return routeErrorResponse.WriteTo(response)
+	} else if err := request.Handle(response); err != nil {
+		return err
+	} else if response.Status() == (msg.Status{}) {
+		return errNoResponse
 	}
 
-	requestError := request.Handle(responseWriter)
-	if requestError != nil {
-		response := servererror.InternalServerError{}
-		response.WriteTo(responseWriter)
+	return nil
+}
+
+// Waits up to the idle timeout for the client to start sending another request
+func (handler *blockingConnectionHandler) awaitNextRequest(requestReader *bufio.Reader, deadlines *connectionDeadlines) bool {
+	if conn, ok := deadlines.conn.(idleConnection); ok {
+		if !conn.StartIdle() {
+			return false
+		}
+
+		defer conn.EndIdle()
 	}
+
+	deadlines.readWithin(handler.Timeouts.Idle)
+	_, err := requestReader.Peek(1)
+	return err == nil
+}
+
+func isDraining(connection io.Writer) bool {
+	conn, ok := connection.(idleConnection)
+	return ok && conn.Draining()
 }
 
 func (handler *blockingConnectionHandler) Routes() []Route {
-	return handler.Router.Routes()
+	return handler.currentRouter().Routes()
+}
+
+// Whether the connection may be used for another request, after responding to this one.
+// See RFC 7230, Section 6.3 (https://tools.ietf.org/html/rfc7230#section-6.3).
+func isPersistent(requested RequestMessage) bool {
+	options := connectionOptions(requested)
+	switch {
+	case options["close"]:
+		return false
+	case requested.Version() == VERSION_1_0:
+		return options["keep-alive"]
+	default:
+		return requested.Version() == VERSION_1_1
+	}
+}
+
+func connectionOptions(requested RequestMessage) map[string]bool {
+	options := make(map[string]bool)
+	for _, option := range headerTokens(requested, "Connection") {
+		options[option] = true
+	}
+
+	return options
+}
+
+// Splits the values of a header with a comma-separated list of case-insensitive tokens, like Connection
+func headerTokens(requested RequestMessage, field string) []string {
+	tokens := make([]string, 0)
+	for _, token := range strings.Split(requested.CombinedHeaderValue(field), ",") {
+		if trimmed := strings.TrimSpace(token); trimmed != "" {
+			tokens = append(tokens, strings.ToLower(trimmed))
+		}
+	}
+
+	return tokens
 }
 
 type Router interface {
-	RouteRequest(reader *bufio.Reader) (ok Request, err Response)
+	RouteRequest(requested RequestMessage) (ok Request, err Response)
 	Routes() []Route
 }
 
 type Request interface {
-	Handle(client io.Writer) error
+	Handle(client msg.ResponseWriter) error
 }
 
 type Response interface {
-	WriteTo(client io.Writer) error
-	WriteHeader(client io.Writer) error
+	WriteTo(client msg.ResponseWriter) error
+	WriteHeader(client msg.ResponseWriter) error
 }
@@ -3,9 +3,11 @@ package http_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg/servererror"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,17 +19,72 @@ var _ = Describe("blockingConnectionHandler", func() {
 			request *httptest.RequestMock
 			router  *RouterMock
 
-			requestReader  = anyReader()
-			responseWriter = anyWriter()
+			responseWriter *bufio.Writer
 		)
 
-		It("parses the request with the Router", func() {
+		BeforeEach(func() {
+			responseWriter = anyWriter()
+		})
+
+		It("routes the parsed request with the Router", func() {
 			request = &httptest.RequestMock{}
 			router = &RouterMock{ReturnsRequest: request}
 
 			handler = http.NewConnectionHandler(router)
-			handler.Handle(requestReader, responseWriter)
-			router.VerifyReceived(requestReader)
+			handler.Handle(context.Background(), makeReader("GET /foo HTTP/1.1\r\n\r\n"), responseWriter)
+			router.VerifyReceived(http.GET, "/foo")
+		})
+
+		Describe("the context of the requested message", func() {
+			var contextRouter *ContextRouter
+
+			BeforeEach(func() {
+				contextRouter = &ContextRouter{Contexts: make(chan context.Context, 1)}
+			})
+
+			It("is done once the response has been written", func() {
+				handler = http.NewConnectionHandler(contextRouter)
+				handler.Handle(context.Background(), makeReader("GET / HTTP/1.1\r\n\r\n"), responseWriter)
+				Expect((<-contextRouter.Contexts).Err()).To(MatchError(context.Canceled))
+			})
+
+			It("has a deadline from the write timeout", func() {
+				handler = http.NewConnectionHandler(contextRouter)
+				handler.Handle(context.Background(), makeReader("GET / HTTP/1.1\r\n\r\n"), responseWriter)
+				_, hasDeadline := (<-contextRouter.Contexts).Deadline()
+				Expect(hasDeadline).To(BeTrue())
+			})
+
+			It("is done when the connection's context is done", func() {
+				connectionCtx, cancel := context.WithCancel(context.Background())
+				cancel()
+				contextRouter.WaitForDone = true
+
+				handler = http.NewConnectionHandler(contextRouter)
+				handler.Handle(connectionCtx, makeReader("GET / HTTP/1.1\r\n\r\n"), responseWriter)
+				Expect(contextRouter.Contexts).To(Receive())
+			})
+		})
+
+		Context("when the request can not be parsed", func() {
+			var response *bytes.Buffer
+
+			BeforeEach(func() {
+				request = &httptest.RequestMock{}
+				router = &RouterMock{ReturnsRequest: request}
+				response = &bytes.Buffer{}
+
+				handler = http.NewConnectionHandler(router)
+				handler.Handle(context.Background(), makeReader("GET /foo HTTP/1.1\r\n\n"), response)
+			})
+
+			It("writes 400 Bad Request to the response writer", func() {
+				Expect(response.String()).To(HavePrefix("HTTP/1.1 400 Bad Request\r\n"))
+			})
+
+			It("does not route the request", func() {
+				router.VerifyNumRequestsRouted(0)
+			})
 		})
 
 		Context("when there is a routing error", func() {
@@ -36,8 +93,16 @@ var _ = Describe("blockingConnectionHandler", func() {
 				router = &RouterMock{ReturnsError: errorResponse}
 
 				handler = http.NewConnectionHandler(router)
-				handler.Handle(requestReader, responseWriter)
-				errorResponse.VerifyWrittenTo(responseWriter)
+				handler.Handle(context.Background(), makeReader("GET / HTTP/1.1\r\n\r\n"), responseWriter)
+				errorResponse.VerifyWritten()
+			})
+
+			It("continues with the next request, since the erroneous one was read in full", func() {
+				router = &RouterMock{ReturnsError: &servererror.NotImplemented{Method: "BOGUS"}}
+
+				handler = http.NewConnectionHandler(router)
+				handler.Handle(context.Background(), makeReader("BOGUS / HTTP/1.1\r\n\r\nBOGUS / HTTP/1.1\r\n\r\n"), responseWriter)
+				router.VerifyNumRequestsRouted(2)
 			})
 		})
 
@@ -46,8 +111,8 @@ var _ = Describe("blockingConnectionHandler", func() {
 			router = &RouterMock{ReturnsRequest: request}
 
 			handler = http.NewConnectionHandler(router)
-			handler.Handle(requestReader, responseWriter)
-			request.VerifyHandle(responseWriter)
+			handler.Handle(context.Background(), makeReader("GET / HTTP/1.1\r\n\r\n"), responseWriter)
+			request.VerifyHandled()
 		})
 
 		Context("when there is an error handling the request", func() {
@@ -56,17 +121,85 @@ var _ = Describe("blockingConnectionHandler", func() {
 				router = &RouterMock{ReturnsRequest: request}
 
 				handler = http.NewConnectionHandler(router)
-				handler.Handle(requestReader, responseWriter)
+				handler.Handle(context.Background(), makeReader("GET / HTTP/1.1\r\n\r\n"), responseWriter)
 				Expect(responseWriter.Buffered()).To(BeNumerically(">", 0))
 			})
 		})
+
+		Describe("persistent connections", func() {
+			BeforeEach(func() {
+				request = &httptest.RequestMock{}
+				router = &RouterMock{ReturnsRequest: request}
+				handler = http.NewConnectionHandler(router)
+			})
+
+			It("handles each request on an HTTP/1.1 connection, until the client closes it", func() {
+				handler.Handle(context.Background(), makeReader("GET /one HTTP/1.1\r\n\r\nGET /two HTTP/1.1\r\n\r\n"), responseWriter)
+				router.VerifyNumRequestsRouted(2)
+			})
+
+			It("stops after a request with Connection: close", func() {
+				handler.Handle(context.Background(), makeReader("GET /one HTTP/1.1\r\nConnection: close\r\n\r\nGET /two HTTP/1.1\r\n\r\n"), responseWriter)
+				router.VerifyNumRequestsRouted(1)
+			})
+
+			It("stops after an HTTP/1.0 request", func() {
+				handler.Handle(context.Background(), makeReader("GET /one HTTP/1.0\r\n\r\nGET /two HTTP/1.0\r\n\r\n"), responseWriter)
+				router.VerifyNumRequestsRouted(1)
+			})
+
+			It("continues after an HTTP/1.0 request with Connection: keep-alive", func() {
+				handler.Handle(context.Background(), makeReader("GET /one HTTP/1.0\r\nConnection: Keep-Alive\r\n\r\nGET /two HTTP/1.0\r\n\r\n"), responseWriter)
+				router.VerifyNumRequestsRouted(2)
+			})
+
+			It("responds with Connection: close, when closing the connection afterwards", func() {
+				response := &bytes.Buffer{}
+				handler = http.NewConnectionHandler(&EchoRouter{})
+				handler.Handle(context.Background(), makeReader("GET /one HTTP/1.1\r\nConnection: close\r\n\r\n"), response)
+				httptest.ParseResponse(response).HeaderShould("Connection", Equal("close"))
+			})
+
+			It("responds with Connection: keep-alive, when keeping an HTTP/1.0 connection alive", func() {
+				response := &bytes.Buffer{}
+				handler = http.NewConnectionHandler(&EchoRouter{})
+				handler.Handle(context.Background(), makeReader("GET /one HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"), response)
+				httptest.ParseResponse(response).HeaderShould("Connection", Equal("keep-alive"))
+			})
+		})
+
+		Describe("pipelined requests", func() {
+			var response *bytes.Buffer
+
+			BeforeEach(func() {
+				response = &bytes.Buffer{}
+				handler = http.NewConnectionHandler(&EchoRouter{})
+			})
+
+			It("writes responses in the same order as the requests", func() {
+				handler.Handle(context.Background(), makeReader(
+					"POST /one HTTP/1.1\r\nContent-Length: 5\r\n\r\nfirst"+
+						"GET /two HTTP/1.1\r\n\r\n"+
+						"POST /three HTTP/1.1\r\nContent-Length: 5\r\n\r\nthird"),
+					response)
+				Expect(response.String()).To(MatchRegexp(`(?s)^HTTP/1.1 200 OK\r\n.*/one first` +
+					`HTTP/1.1 200 OK\r\n.*/two ` +
+					`HTTP/1.1 200 OK\r\n.*/three third$`))
+			})
+
+			It("stops at the first request that can not be parsed", func() {
+				handler.Handle(context.Background(), makeReader(
+					"GET /one HTTP/1.1\r\n\r\n"+
+						"GET  /two HTTP/1.1\r\n\r\n"+
+						"GET /three HTTP/1.1\r\n\r\n"),
+					response)
+				Expect(response.String()).To(MatchRegexp(`(?s)^HTTP/1.1 200 OK\r\n.*/one HTTP/1.1 400 Bad Request\r\n`))
+				Expect(response.String()).NotTo(ContainSubstring("/three"))
+			})
+		})
 	})
 })
 
-func anyReader() *bufio.Reader {
-	return bufio.NewReader(bytes.NewBufferString(""))
-}
-
 func anyWriter() *bufio.Writer {
 	return bufio.NewWriter(bytes.NewBufferString(""))
 }
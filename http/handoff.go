@@ -0,0 +1,210 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Environment variables for handing off listening sockets to a replacement process.
+// The listeners start at file descriptor 3, followed by a pipe that the replacement writes to when it is ready.
+const (
+	handoffFdsEnv   = "GOHTTP_LISTEN_FDS"
+	handoffReadyEnv = "GOHTTP_READY_FD"
+)
+
+// Listening sockets that a previous process handed off to this one, such as during a restart with StartReplacement.
+// Servers that inherit from it use the socket with the same address, instead of opening a new one.
+type Handoff struct {
+	mutex     sync.Mutex
+	listeners map[string]net.Listener
+	ready     *os.File
+}
+
+// Receives the listening sockets that the process that started this one handed off to it, if any.
+// The environment variables are unset afterwards, so they do not leak into child processes.
+func ReceiveHandoff() (*Handoff, error) {
+	defer unsetHandoffEnvironment()
+	handoff := &Handoff{listeners: make(map[string]net.Listener)}
+	if os.Getenv(handoffFdsEnv) == "" {
+		return handoff, nil
+	}
+
+	numFds, fdsErr := strconv.Atoi(os.Getenv(handoffFdsEnv))
+	if fdsErr != nil || numFds < 0 {
+		return nil, fmt.Errorf("ReceiveHandoff: invalid %s: %s", handoffFdsEnv, os.Getenv(handoffFdsEnv))
+	}
+
+	for i := 0; i < numFds; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+		listener, listenErr := fileListener(uintptr(fd), fmt.Sprintf("%s_%d", handoffFdsEnv, i))
+		if listenErr != nil {
+			handoff.closeUntaken()
+			return nil, listenErr
+		}
+
+		handoff.listeners[listenerKey(listener.Addr())] = listener
+	}
+
+	if readyFd, readyErr := strconv.Atoi(os.Getenv(handoffReadyEnv)); readyErr == nil {
+		syscall.CloseOnExec(readyFd)
+		handoff.ready = os.NewFile(uintptr(readyFd), handoffReadyEnv)
+	}
+
+	return handoff, nil
+}
+
+// Removes and returns every listener that no server has taken yet
+func (handoff *Handoff) TakeAll() []net.Listener {
+	if handoff == nil {
+		return nil
+	}
+
+	handoff.mutex.Lock()
+	defer handoff.mutex.Unlock()
+	listeners := make([]net.Listener, 0, len(handoff.listeners))
+	for key, listener := range handoff.listeners {
+		listeners = append(listeners, listener)
+		delete(handoff.listeners, key)
+	}
+
+	return listeners
+}
+
+// Tells the process that handed off its listeners that this one is ready to take over, and closes any listeners that
+// no server took
+func (handoff *Handoff) Ready() error {
+	if handoff == nil {
+		return nil
+	}
+
+	handoff.closeUntaken()
+	handoff.mutex.Lock()
+	defer handoff.mutex.Unlock()
+	if handoff.ready == nil {
+		return nil
+	}
+
+	ready := handoff.ready
+	handoff.ready = nil
+	defer ready.Close()
+	_, err := ready.Write([]byte{1})
+	return err
+}
+
+// Removes and returns the listener with the given address, if any.  An inherited Unix socket file is left in place
+// when its listener closes, since the process that created it may still be using it, such as when this process fails to
+// become ready.  UnixSocket removes the file as a stale socket, the next time it listens on that path.
+func (handoff *Handoff) take(address net.Addr) net.Listener {
+	if handoff == nil {
+		return nil
+	}
+
+	handoff.mutex.Lock()
+	defer handoff.mutex.Unlock()
+	key := listenerKey(address)
+	listener := handoff.listeners[key]
+	delete(handoff.listeners, key)
+	if unixListener, isUnix := listener.(*net.UnixListener); isUnix {
+		unixListener.SetUnlinkOnClose(false)
+	}
+
+	return listener
+}
+
+func (handoff *Handoff) closeUntaken() {
+	for _, listener := range handoff.TakeAll() {
+		_ = listener.Close()
+	}
+}
+
+// Identifies a listener by its address.  A TCP listener on every address is identified by its port alone, since a
+// socket bound to 0.0.0.0 can report its address as [::] once it has been handed off.
+func listenerKey(address net.Addr) string {
+	if tcpAddress, isTCP := address.(*net.TCPAddr); isTCP && (tcpAddress.IP == nil || tcpAddress.IP.IsUnspecified()) {
+		return "tcp:*:" + strconv.Itoa(tcpAddress.Port)
+	}
+
+	return address.Network() + ":" + address.String()
+}
+
+func unsetHandoffEnvironment() {
+	_ = os.Unsetenv(handoffFdsEnv)
+	_ = os.Unsetenv(handoffReadyEnv)
+}
+
+// Starts the program at path as a replacement for this process, handing off the listeners to it, and waits up to
+// readyTimeout for the replacement to call Handoff.Ready.  The replacement is killed if it does not become ready in
+// time.  args includes the program name, like os.Args, and env is added to this process's environment.
+func StartReplacement(path string, args []string, env []string, listeners []*os.File, readyTimeout time.Duration) error {
+	readyReader, readyWriter, pipeErr := os.Pipe()
+	if pipeErr != nil {
+		return pipeErr
+	}
+	defer readyReader.Close()
+
+	files := append(append([]*os.File{os.Stdin, os.Stdout, os.Stderr}, listeners...), readyWriter)
+	fds, fdsErr := rawFds(files)
+	if fdsErr != nil {
+		_ = readyWriter.Close()
+		return fdsErr
+	}
+
+	env = append(append(os.Environ(), env...),
+		fmt.Sprintf("%s=%d", handoffFdsEnv, len(listeners)),
+		fmt.Sprintf("%s=%d", handoffReadyEnv, listenFdsStart+len(listeners)))
+	pid, startErr := syscall.ForkExec(path, args, &syscall.ProcAttr{Env: env, Files: fds})
+	_ = readyWriter.Close()
+	if startErr != nil {
+		return startErr
+	}
+
+	process, _ := os.FindProcess(pid)
+	ready := make(chan error, 1)
+	go func() {
+		_, readErr := readyReader.Read(make([]byte, 1))
+		ready <- readErr
+	}()
+
+	select {
+	case readErr := <-ready:
+		if readErr == io.EOF {
+			_, _ = process.Wait()
+			return fmt.Errorf("StartReplacement: exited before it was ready")
+		} else if readErr != nil {
+			_ = process.Kill()
+			return readErr
+		}
+
+		go func() { _, _ = process.Wait() }()
+		return nil
+	case <-time.After(readyTimeout):
+		_ = process.Kill()
+		_, _ = process.Wait()
+		return fmt.Errorf("StartReplacement: not ready after %s", readyTimeout)
+	}
+}
+
+// The file descriptor of each file.  File.Fd would put a listening socket in blocking mode, which the copy shares with
+// the server's own listener, so it could no longer close while it is waiting to accept a connection.
+func rawFds(files []*os.File) ([]uintptr, error) {
+	fds := make([]uintptr, len(files))
+	for i, file := range files {
+		rawConn, err := file.SyscallConn()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rawConn.Control(func(fd uintptr) { fds[i] = fd }); err != nil {
+			return nil, err
+		}
+	}
+
+	return fds, nil
+}
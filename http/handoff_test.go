@@ -0,0 +1,242 @@
+package http_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kkrull/gohttp/http"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StartReplacement", func() {
+	var (
+		host   string
+		server *http.TCPServer
+		files  []*os.File
+		conn   net.Conn
+	)
+
+	BeforeEach(func() {
+		host = "localhost"
+	})
+
+	JustBeforeEach(func() {
+		server = http.TCPServerBuilder(host).Build()
+		Expect(server.Start()).To(Succeed())
+
+		var err error
+		files, err = server.ListenerFiles()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		for _, file := range files {
+			Expect(file.Close()).To(Succeed())
+		}
+
+		if conn != nil {
+			Expect(conn.Close()).To(Succeed())
+			conn = nil
+		}
+
+		Expect(server.Shutdown()).To(Succeed())
+	})
+
+	port := func() string {
+		_, port, err := net.SplitHostPort(server.Address().String())
+		Expect(err).NotTo(HaveOccurred())
+		return port
+	}
+
+	startReplacement := func(mode string, readyTimeout time.Duration) error {
+		return http.StartReplacement(
+			os.Args[0],
+			[]string{os.Args[0], "-test.run=^TestHandoffHelperProcess$"},
+			[]string{"GOHTTP_HANDOFF_HELPER=" + mode, "GOHTTP_HANDOFF_ADDRESS=" + net.JoinHostPort(host, port())},
+			files,
+			readyTimeout)
+	}
+
+	Context("when the replacement takes over the listener and becomes ready", func() {
+		It("returns no error, and the replacement answers requests once this server stops", func(done Done) {
+			address := server.Address().String()
+			Expect(startReplacement("ready", 5*time.Second)).To(Succeed())
+			Expect(server.Shutdown()).To(Succeed())
+
+			var err error
+			conn, err = net.Dial("tcp", address)
+			Expect(err).NotTo(HaveOccurred())
+			writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+			expectHttpResponse(conn)
+			close(done)
+		}, 10)
+	})
+
+	Context("when the server listens on every address", func() {
+		BeforeEach(func() {
+			host = "0.0.0.0"
+		})
+
+		It("the replacement takes over the listener, instead of trying to open another one", func(done Done) {
+			address := net.JoinHostPort("127.0.0.1", port())
+			Expect(startReplacement("ready", 5*time.Second)).To(Succeed())
+			Expect(server.Shutdown()).To(Succeed())
+
+			var err error
+			conn, err = net.Dial("tcp", address)
+			Expect(err).NotTo(HaveOccurred())
+			writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+			expectHttpResponse(conn)
+			close(done)
+		}, 10)
+	})
+
+	Context("when the replacement exits before it is ready", func() {
+		It("returns an error", func(done Done) {
+			err := startReplacement("exit", 5*time.Second)
+			Expect(err).To(MatchError("StartReplacement: exited before it was ready"))
+			close(done)
+		}, 10)
+	})
+
+	Context("when the replacement is not ready in time", func() {
+		It("returns an error", func(done Done) {
+			err := startReplacement("hang", 100*time.Millisecond)
+			Expect(err).To(MatchError("StartReplacement: not ready after 100ms"))
+			close(done)
+		}, 10)
+	})
+})
+
+var _ = Describe("ReceiveHandoff", func() {
+	Context("when the process was not started by StartReplacement", func() {
+		It("returns a Handoff without any listeners", func() {
+			handoff, err := http.ReceiveHandoff()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handoff.TakeAll()).To(BeEmpty())
+			Expect(handoff.Ready()).To(Succeed())
+		})
+	})
+
+	Context("given a nil Handoff", func() {
+		It("a server that inherits from it opens its own listener", func() {
+			var handoff *http.Handoff
+			server := http.TCPServerBuilder("localhost").InheritingFrom(handoff).Build()
+			Expect(server.Start()).To(Succeed())
+			Expect(server.Shutdown()).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("TCPServer#ListenerFiles", func() {
+	It("returns an error when the server is not running", func() {
+		server := http.TCPServerBuilder("localhost").Build()
+		_, err := server.ListenerFiles()
+		Expect(err).To(MatchError("TCPServer: not running"))
+	})
+
+	It("leaves a Unix socket file in place after the server stops, for the process that takes it over", func() {
+		directory, err := ioutil.TempDir("", "TCPServer-handoff")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(directory)
+
+		socketPath := path.Join(directory, "gohttp.sock")
+		server := http.TCPServerBuilder("localhost").ListeningOnUnixSocket(socketPath, 0600).Build()
+		Expect(server.Start()).To(Succeed())
+		files, err := server.ListenerFiles()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Close()).To(Succeed())
+
+		Expect(server.Shutdown()).To(Succeed())
+		Expect(socketPath).To(BeAnExistingFile())
+	})
+	It("leaves a Unix socket file in place when a replacement that inherits it fails to start, and drains", func(done Done) {
+		directory, err := ioutil.TempDir("", "TCPServer-handoff")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(directory)
+
+		socketPath := path.Join(directory, "gohttp.sock")
+		server := http.TCPServerBuilder("localhost").ListeningOnUnixSocket(socketPath, 0600).Build()
+		Expect(server.Start()).To(Succeed())
+		defer server.Shutdown()
+		files, err := server.ListenerFiles()
+		Expect(err).NotTo(HaveOccurred())
+		defer files[0].Close()
+
+		err = http.StartReplacement(
+			os.Args[0],
+			[]string{os.Args[0], "-test.run=^TestHandoffHelperProcess$"},
+			[]string{"GOHTTP_HANDOFF_HELPER=drain", "GOHTTP_HANDOFF_SOCKET=" + socketPath},
+			files,
+			5*time.Second)
+		Expect(err).To(MatchError("StartReplacement: exited before it was ready"))
+		Expect(socketPath).To(BeAnExistingFile())
+
+		conn, err := net.Dial("unix", socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+		expectHttpResponse(conn)
+		close(done)
+	}, 10)
+})
+
+// Runs in the replacement process of a StartReplacement spec, which takes over the listener of the server at
+// GOHTTP_HANDOFF_ADDRESS for a couple seconds when GOHTTP_HANDOFF_HELPER is "ready"
+func TestHandoffHelperProcess(t *testing.T) {
+	switch os.Getenv("GOHTTP_HANDOFF_HELPER") {
+	case "":
+		return
+	case "exit":
+		os.Exit(0)
+	case "hang":
+		time.Sleep(10 * time.Second)
+		os.Exit(0)
+	case "drain":
+		drainInheritedUnixSocket(os.Getenv("GOHTTP_HANDOFF_SOCKET"))
+		os.Exit(0)
+	}
+
+	handoff, err := http.ReceiveHandoff()
+	if err != nil {
+		os.Exit(2)
+	}
+
+	host, port, _ := net.SplitHostPort(os.Getenv("GOHTTP_HANDOFF_ADDRESS"))
+	portNumber, _ := net.LookupPort("tcp", port)
+	server := http.TCPServerBuilder(host).
+		ListeningOnPort(uint16(portNumber)).
+		InheritingFrom(handoff).
+		Build()
+	if server.Start() != nil || handoff.Ready() != nil {
+		os.Exit(3)
+	}
+
+	time.Sleep(2 * time.Second)
+	os.Exit(0)
+}
+
+// Takes over the Unix socket at socketPath, then drains it without ever becoming ready, like a replacement that fails
+// to start one of its other servers
+func drainInheritedUnixSocket(socketPath string) {
+	handoff, err := http.ReceiveHandoff()
+	if err != nil {
+		os.Exit(2)
+	}
+
+	server := http.TCPServerBuilder("localhost").
+		ListeningOnUnixSocket(socketPath, 0600).
+		InheritingFrom(handoff).
+		Build()
+	if server.Start() != nil {
+		os.Exit(3)
+	}
+
+	_, _ = server.Drain(time.Second)
+}
@@ -3,11 +3,15 @@ package http_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"sync"
 	"testing"
 
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/success"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,19 +21,209 @@ func TestHttp(t *testing.T) {
 	RunSpecs(t, "http")
 }
 
+/* BlockingRouter */
+
+// Routes every request to one that ignores its context and blocks until Release is closed, like a handler that never
+// returns
+type BlockingRouter struct {
+	Release chan struct{}
+}
+
+func (router *BlockingRouter) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	return &blockingRequest{Release: router.Release}, nil
+}
+
+func (router *BlockingRouter) Routes() []http.Route {
+	return nil
+}
+
+type blockingRequest struct {
+	Release chan struct{}
+}
+
+func (request *blockingRequest) Handle(client msg.ResponseWriter) error {
+	<-request.Release
+	msg.WriteStatus(client, success.OKStatus)
+	msg.WriteContentLengthHeader(client, 0)
+	return nil
+}
+
+/* ContextRouter */
+
+// Routes every request to one that sends the requested message's context on Contexts, after waiting for it to be done
+// when WaitForDone is set
+type ContextRouter struct {
+	Contexts    chan context.Context
+	WaitForDone bool
+}
+
+func (router *ContextRouter) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	return &contextRequest{Router: router, Message: requested}, nil
+}
+
+func (router *ContextRouter) Routes() []http.Route {
+	return nil
+}
+
+type contextRequest struct {
+	Router  *ContextRouter
+	Message http.RequestMessage
+}
+
+func (request *contextRequest) Handle(client msg.ResponseWriter) error {
+	ctx := request.Message.Context()
+	if request.Router.WaitForDone {
+		<-ctx.Done()
+	}
+
+	request.Router.Contexts <- ctx
+	msg.WriteStatus(client, success.OKStatus)
+	msg.WriteContentLengthHeader(client, 0)
+	return nil
+}
+
+/* LargeBodyRouter */
+
+// Routes every request to one that writes a body of Size bytes with Content-Length, a piece at a time, and stops when
+// the requested message's context is done
+type LargeBodyRouter struct {
+	Size int
+}
+
+func (router *LargeBodyRouter) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	return &largeBodyRequest{Size: router.Size, Message: requested}, nil
+}
+
+func (router *LargeBodyRouter) Routes() []http.Route {
+	return nil
+}
+
+type largeBodyRequest struct {
+	Size    int
+	Message http.RequestMessage
+}
+
+func (request *largeBodyRequest) Handle(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, success.OKStatus)
+	msg.WriteContentLengthHeader(client, request.Size)
+	msg.WriteEndOfMessageHeader(client)
+
+	piece := bytes.Repeat([]byte("x"), 64*1024)
+	for remaining := request.Size; remaining > 0; remaining -= len(piece) {
+		if err := request.Message.Context().Err(); err != nil {
+			return err
+		} else if remaining < len(piece) {
+			piece = piece[:remaining]
+		}
+
+		if _, err := client.Write(piece); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/* EchoRouter */
+
+// Routes every request to one that writes the target and body of the requested message
+type EchoRouter struct{}
+
+func (router *EchoRouter) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	return &echoRequest{Message: requested}, nil
+}
+
+func (router *EchoRouter) Routes() []http.Route {
+	return nil
+}
+
+type echoRequest struct {
+	Message http.RequestMessage
+}
+
+func (request *echoRequest) Handle(client msg.ResponseWriter) error {
+	body := fmt.Sprintf("%s %s", request.Message.Target(), request.Message.Body())
+	msg.WriteStatus(client, success.OKStatus)
+	msg.WriteContentLengthHeader(client, len(body))
+	msg.WriteEndOfMessageHeader(client)
+	msg.WriteBody(client, body)
+	return nil
+}
+
+/* FailingRouter */
+
+// Routes every request to one that writes the status and body it is told to, then panics with Panic if it is set or
+// returns Err otherwise
+type FailingRouter struct {
+	WritesStatus bool
+	WritesBody   string
+	Panic        interface{}
+	Err          error
+}
+
+func (router *FailingRouter) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	return &failingRequest{Router: router}, nil
+}
+
+func (router *FailingRouter) Routes() []http.Route {
+	return nil
+}
+
+type failingRequest struct {
+	Router *FailingRouter
+}
+
+func (request *failingRequest) Handle(client msg.ResponseWriter) error {
+	if request.Router.WritesStatus {
+		msg.WriteStatus(client, success.OKStatus)
+	}
+	if request.Router.WritesBody != "" {
+		msg.WriteBody(client, request.Router.WritesBody)
+	}
+	if request.Router.Panic != nil {
+		panic(request.Router.Panic)
+	}
+
+	return request.Router.Err
+}
+
+/* GetOnlyResourceMock */
+
+// A resource that only supports GET, and writes Body without declaring its length
+type GetOnlyResourceMock struct {
+	Body string
+}
+
+func (mock *GetOnlyResourceMock) Name() string {
+	return "GetOnlyResourceMock"
+}
+
+func (mock *GetOnlyResourceMock) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	msg.WriteStatus(client, success.OKStatus)
+	msg.WriteContentTypeHeader(client, "text/plain")
+	msg.WriteEndOfMessageHeader(client)
+	msg.WriteBody(client, mock.Body)
+	return nil
+}
+
 /* HandlerMock */
 
 type HandlerMock struct {
+	mutex                sync.Mutex
 	handleRequestReader  *bufio.Reader
 	handleResponseWriter io.Writer
 }
 
-func (mock *HandlerMock) Handle(requestReader *bufio.Reader, responseWriter io.Writer) {
+func (mock *HandlerMock) Handle(ctx context.Context, requestReader *bufio.Reader, responseWriter io.Writer) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.handleRequestReader = requestReader
 	mock.handleResponseWriter = responseWriter
 }
 
 func (mock *HandlerMock) ShouldHandleConnection() {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	ExpectWithOffset(1, mock.handleRequestReader).NotTo(BeNil())
 	ExpectWithOffset(1, mock.handleResponseWriter).NotTo(BeNil())
 }
@@ -64,8 +258,9 @@ func (mock *ResourceMock) Name() string {
 	return "ResourceMock"
 }
 
-func (mock *ResourceMock) Patch(client io.Writer, message http.RequestMessage) {
+func (mock *ResourceMock) Patch(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.patchReceivedMessage = message
+	return nil
 }
 
 func (mock *ResourceMock) PatchShouldHaveBeenCalled(path string) {
@@ -75,22 +270,22 @@ func (mock *ResourceMock) PatchShouldHaveBeenCalled(path string) {
 /* ResponseMock */
 
 type ResponseMock struct {
-	writeHeaderReceived io.Writer
-	writtenTo           io.Writer
+	writeHeaderReceived msg.ResponseWriter
+	writtenTo           msg.ResponseWriter
 }
 
-func (mock *ResponseMock) WriteTo(client io.Writer) error {
+func (mock *ResponseMock) WriteTo(client msg.ResponseWriter) error {
 	mock.writtenTo = client
 	return nil
 }
 
-func (mock *ResponseMock) WriteHeader(client io.Writer) error {
+func (mock *ResponseMock) WriteHeader(client msg.ResponseWriter) error {
 	mock.writeHeaderReceived = client
 	return nil
 }
 
-func (mock *ResponseMock) VerifyWrittenTo(writer *bufio.Writer) {
-	ExpectWithOffset(1, mock.writtenTo).To(BeIdenticalTo(writer))
+func (mock *ResponseMock) VerifyWritten() {
+	ExpectWithOffset(1, mock.writtenTo).NotTo(BeNil())
 }
 
 /* RouteMock */
@@ -119,31 +314,52 @@ func (mock *RouteMock) ShouldHaveReceivedParameters(parameters map[string]string
 type RouterMock struct {
 	ReturnsRequest http.Request
 	ReturnsError   http.Response
-	receivedReader *bufio.Reader
-	parsed         []byte
+	received       []http.RequestMessage
 }
 
 func (mock *RouterMock) Routes() []http.Route {
 	return nil
 }
 
-func (mock *RouterMock) RouteRequest(reader *bufio.Reader) (http.Request, http.Response) {
-	mock.receivedReader = reader
-	allButLF, _ := reader.ReadBytes(byte('\r'))
-	shouldBeLF, _ := reader.ReadByte()
-	mock.parsed = appendByte(allButLF, shouldBeLF)
+func (mock *RouterMock) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	mock.received = append(mock.received, requested)
 	return mock.ReturnsRequest, mock.ReturnsError
 }
 
-func appendByte(allButLast []byte, last byte) []byte {
-	whole := make([]byte, len(allButLast)+1)
-	copy(whole, allButLast)
-	whole[len(whole)-1] = last
-	return whole
+func (mock RouterMock) VerifyReceived(method string, target string) {
+	ExpectWithOffset(1, mock.received).NotTo(BeEmpty())
+	ExpectWithOffset(1, mock.received[0].Method()).To(Equal(method))
+	ExpectWithOffset(1, mock.received[0].Target()).To(Equal(target))
+}
+
+func (mock RouterMock) VerifyNumRequestsRouted(numRequests int) {
+	ExpectWithOffset(1, mock.received).To(HaveLen(numRequests))
 }
 
-func (mock RouterMock) VerifyReceived(reader *bufio.Reader) {
-	ExpectWithOffset(1, mock.receivedReader).To(BeIdenticalTo(reader))
+/* StreamingRouter */
+
+// Routes every request to one that writes Body without declaring its length
+type StreamingRouter struct {
+	Body string
+}
+
+func (router *StreamingRouter) RouteRequest(requested http.RequestMessage) (http.Request, http.Response) {
+	return &streamingRequest{Body: router.Body}, nil
+}
+
+func (router *StreamingRouter) Routes() []http.Route {
+	return nil
+}
+
+type streamingRequest struct {
+	Body string
+}
+
+func (request *streamingRequest) Handle(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, success.OKStatus)
+	msg.WriteEndOfMessageHeader(client)
+	msg.WriteBody(client, request.Body)
+	return nil
 }
 
 /* Helpers */
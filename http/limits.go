@@ -0,0 +1,17 @@
+package http
+
+// Upper bounds on the size of a request, so that one client can not exhaust the server's memory.
+// A limit of 0 means there is no limit.
+type RequestLimits struct {
+	MaxRequestLineBytes int   // Longer request-lines are answered with 414 URI Too Long
+	MaxHeaderBytes      int   // Header sections (and any trailers) with more bytes are answered with 431
+	MaxHeaderFields     int   // Header sections (and any trailers) with more fields are answered with 431
+	MaxBodyBytes        int64 // Larger bodies are answered with 413 Payload Too Large
+}
+
+var DefaultRequestLimits = RequestLimits{
+	MaxRequestLineBytes: 8 * 1024,
+	MaxHeaderBytes:      64 * 1024,
+	MaxHeaderFields:     100,
+	MaxBodyBytes:        10 * 1024 * 1024,
+}
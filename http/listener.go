@@ -0,0 +1,78 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// Opens the listener that a TCPServer accepts connections on, when it is not listening on a TCP host and port
+type ListenerFactory interface {
+	Listen() (net.Listener, error)
+}
+
+func NewExistingListener(listener net.Listener) *ExistingListener {
+	return &ExistingListener{Listener: listener}
+}
+
+// Accepts connections on a listener that is already open, such as one from ActivationListeners.
+// The server closes it when it stops, so it can not be started again.
+type ExistingListener struct {
+	Listener net.Listener
+}
+
+func (existing *ExistingListener) Listen() (net.Listener, error) {
+	return existing.Listener, nil
+}
+
+func NewUnixSocket(path string, mode os.FileMode) *UnixSocket {
+	return &UnixSocket{Path: path, Mode: mode}
+}
+
+// Listens on a Unix domain socket at Path, such as for a reverse proxy on the same machine.
+// A stale socket file left behind by a server that is no longer running is removed first, but any other file at Path
+// is left alone.  The socket file gets the permissions in Mode, when there are any, and is removed when the listener
+// closes.
+type UnixSocket struct {
+	Path string
+	Mode os.FileMode
+}
+
+func (socket *UnixSocket) Listen() (net.Listener, error) {
+	if err := socket.removeStaleSocket(); err != nil {
+		return nil, err
+	}
+
+	listener, listenErr := net.Listen("unix", socket.Path)
+	if listenErr != nil {
+		return nil, listenErr
+	}
+
+	if socket.Mode != 0 {
+		if chmodErr := os.Chmod(socket.Path, socket.Mode); chmodErr != nil {
+			_ = listener.Close()
+			return nil, chmodErr
+		}
+	}
+
+	return listener, nil
+}
+
+func (socket *UnixSocket) removeStaleSocket() error {
+	info, statErr := os.Lstat(socket.Path)
+	switch {
+	case os.IsNotExist(statErr):
+		return nil
+	case statErr != nil:
+		return statErr
+	case info.Mode()&os.ModeSocket == 0:
+		return fmt.Errorf("UnixSocket: %s already exists and is not a socket", socket.Path)
+	}
+
+	if conn, dialErr := net.Dial("unix", socket.Path); dialErr == nil {
+		_ = conn.Close()
+		return fmt.Errorf("UnixSocket: %s is already in use", socket.Path)
+	}
+
+	return os.Remove(socket.Path)
+}
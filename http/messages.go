@@ -1,11 +1,12 @@
 package http
 
 import (
+	"context"
 	"fmt"
-	"sort"
+	"net/textproto"
+	"strings"
 
-	"github.com/kkrull/gohttp/msg/clienterror"
-	"github.com/kkrull/gohttp/msg/servererror"
+	"github.com/kkrull/gohttp/msg"
 )
 
 // Request method verbs
@@ -22,6 +23,7 @@ const (
 )
 
 const (
+	VERSION_1_0 = "HTTP/1.0"
 	VERSION_1_1 = "HTTP/1.1"
 )
 
@@ -109,15 +111,29 @@ func NewRequestMessage(method, path string) RequestMessage {
 }
 
 type requestMessage struct {
+	ctx             context.Context
 	method          string
 	path            string
 	target          string
 	version         string
 	queryParameters []QueryParameter
-	headers         []header
+	pathParameters  map[string]string
+	headers         msg.Header
 	body            []byte
 }
 
+func (message *requestMessage) Context() context.Context {
+	if message.ctx == nil {
+		return context.Background()
+	}
+
+	return message.ctx
+}
+
+func (message *requestMessage) SetContext(ctx context.Context) {
+	message.ctx = ctx
+}
+
 func (message *requestMessage) Method() string {
 	return message.method
 }
@@ -142,34 +158,41 @@ func (message *requestMessage) QueryParameters() []QueryParameter {
 	return message.queryParameters
 }
 
+func (message *requestMessage) PathParameter(name string) string {
+	return message.pathParameters[name]
+}
+
+func (message *requestMessage) SetPathParameters(parameters map[string]string) {
+	message.pathParameters = parameters
+}
+
 func (message *requestMessage) Target() string {
 	return message.target
 }
 
 func (message *requestMessage) HeaderLines() []string {
-	lines := make([]string, len(message.headers))
-	for i, header := range message.headers {
-		lines[i] = header.String()
+	fields := message.headers.Fields()
+	lines := make([]string, len(fields))
+	for i, field := range fields {
+		lines[i] = fmt.Sprintf("%s: %s", field.Name, field.Value)
 	}
 
 	return lines
 }
 
 func (message *requestMessage) HeaderValues(field string) []string {
-	values := make([]string, 0)
-	for _, header := range message.headers {
-		if header.Field != field {
-			continue
-		}
-
-		values = append(values, header.Value)
-	}
+	return message.headers.Values(field)
+}
 
-	return values
+// The values of every field with the given name, combined into one comma-separated list.
+// See RFC 7230, Section 3.2.2 (https://tools.ietf.org/html/rfc7230#section-3.2.2).
+func (message *requestMessage) CombinedHeaderValue(field string) string {
+	return strings.Join(message.headers.Values(field), ", ")
 }
 
+// Adds a header field, in canonical form (like Content-Length) since field names are case-insensitive
 func (message *requestMessage) AddHeader(field, value string) {
-	message.headers = append(message.headers, header{Field: field, Value: value})
+	message.headers.Add(textproto.CanonicalMIMEHeaderKey(field), value)
 }
 
 func (message *requestMessage) Body() []byte {
@@ -180,69 +203,10 @@ func (message *requestMessage) SetBody(body []byte) {
 	message.body = body
 }
 
-func (message *requestMessage) NotImplemented() Response {
-	return &servererror.NotImplemented{Method: message.method}
-}
-
+// Routes the message to the method of the resource that handles it, or to a response saying which methods it allows
 func (message *requestMessage) MakeResourceRequest(resource Resource) Request {
-	method := knownMethods[message.method]
-	if method == nil {
-		return message.unknownHttpMethod(resource)
-	}
-
-	request, isImplementedByResource := method.MakeRequest(message, resource)
-	if isImplementedByResource {
-		return request
-	} else if message.method == OPTIONS {
-		return &staticOptionsRequest{SupportedMethods: message.supportedMethods(resource)}
-	}
-
-	return message.unsupportedMethod(resource)
-}
-
-func (message *requestMessage) unknownHttpMethod(resource Resource) Request {
-	return clienterror.MethodNotAllowed(message.supportedMethods(resource)...)
-}
-
-func (message *requestMessage) unsupportedMethod(resource Resource) Request {
-	return clienterror.MethodNotAllowed(message.supportedMethods(resource)...)
-}
-
-func (message *requestMessage) supportedMethods(resource Resource) []string {
-	supported := []string{OPTIONS}
-	for name, method := range knownMethods {
-		imaginaryRequest := &requestMessage{method: name, target: message.target, version: message.version}
-		_, isSupported := method.MakeRequest(imaginaryRequest, resource)
-		if isSupported {
-			supported = append(supported, name)
-		}
-	}
-
-	sort.Strings(supported)
-	return supported
-}
-
-var knownMethods = map[string]Method{
-	DELETE:  &deleteMethod{},
-	GET:     &getMethod{},
-	HEAD:    &headMethod{},
-	OPTIONS: &optionsMethod{},
-	PATCH:   &patchMethod{},
-	POST:    &postMethod{},
-	PUT:     &putMethod{},
-}
-
-type Method interface {
-	MakeRequest(requested *requestMessage, resource Resource) (request Request, isSupported bool)
-}
-
-type header struct {
-	Field string
-	Value string
-}
-
-func (header *header) String() string {
-	return fmt.Sprintf("%s: %s", header.Field, header.Value)
+	route := &methodRoute{handlers: resourceMethods(resource)}
+	return route.Route(message)
 }
 
 // Handles requests of supported HTTP methods for a resource
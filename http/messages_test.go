@@ -1,10 +1,10 @@
 package http_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("requestMessage", func() {
@@ -23,9 +23,49 @@ var _ = Describe("requestMessage", func() {
 			})
 
 			It("returns a request that calls PatchResource#Patch", func() {
-				request.Handle(&bytes.Buffer{})
+				request.Handle(&httptest.ResponseBuffer{})
 				resource.PatchShouldHaveBeenCalled("/existing")
 			})
 		})
+
+		Context("given a message with the HEAD method, for a resource that only supports GET", func() {
+			var response *httptest.ResponseBuffer
+
+			BeforeEach(func() {
+				response = &httptest.ResponseBuffer{}
+				message := http.NewHeadMessage("/greeting")
+				request := message.MakeResourceRequest(&GetOnlyResourceMock{Body: "hello world"})
+				request.Handle(response)
+			})
+
+			It("responds with the status from GET", func() {
+				httptest.ParseResponse(response).StatusShouldBe(200, "OK")
+			})
+
+			It("responds with the header fields from GET", func() {
+				httptest.ParseResponse(response).HeaderShould("Content-Type", Equal("text/plain"))
+			})
+
+			It("sets Content-Length to the length of the body GET would have written", func() {
+				httptest.ParseResponse(response).HeaderShould("Content-Length", Equal("11"))
+			})
+
+			It("does not write the body", func() {
+				httptest.ParseResponse(response).BodyShould(BeEmpty())
+			})
+		})
+
+		Context("given a message with the OPTIONS method, for a resource that only supports GET", func() {
+			var response = &httptest.ResponseBuffer{}
+
+			BeforeEach(func() {
+				response.Reset()
+				message := http.NewOptionsMessage("/greeting")
+				request := message.MakeResourceRequest(&GetOnlyResourceMock{})
+				request.Handle(response)
+			})
+
+			It("allows HEAD too", httptest.AllowedMethodsShouldBe(response, http.GET, http.HEAD, http.OPTIONS))
+		})
 	})
 })
@@ -1,132 +1,158 @@
 package http
 
 import (
-	"io"
+	"sort"
+	"strconv"
 
 	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/clienterror"
 	"github.com/kkrull/gohttp/msg/success"
 )
 
-/* DELETE */
-
-type deleteMethod struct{}
+// Handles requests with one method, like the Get method of a GetResource
+type MethodHandler func(client msg.ResponseWriter, message RequestMessage) error
 
-func (method *deleteMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(DeleteResource)
-	if ok {
-		return &deleteRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
+// The handler for each method that a Resource implements
+func resourceMethods(resource Resource) map[string]MethodHandler {
+	handlers := make(map[string]MethodHandler)
+	if supportedResource, ok := resource.(DeleteResource); ok {
+		handlers[DELETE] = supportedResource.Delete
+	}
+	if supportedResource, ok := resource.(GetResource); ok {
+		handlers[GET] = supportedResource.Get
+	}
+	if supportedResource, ok := resource.(HeadResource); ok {
+		handlers[HEAD] = supportedResource.Head
+	}
+	if supportedResource, ok := resource.(OptionsResource); ok {
+		handlers[OPTIONS] = supportedResource.Options
+	}
+	if supportedResource, ok := resource.(PatchResource); ok {
+		handlers[PATCH] = supportedResource.Patch
+	}
+	if supportedResource, ok := resource.(PostResource); ok {
+		handlers[POST] = supportedResource.Post
+	}
+	if supportedResource, ok := resource.(PutResource); ok {
+		handlers[PUT] = supportedResource.Put
 	}
 
-	return nil, false
+	return handlers
 }
 
-type deleteRequest struct {
-	Message  RequestMessage
-	Resource DeleteResource
+// Dispatches requests to the handler for their method.
+// HEAD is answered with the GET handler when there is no HEAD handler, and OPTIONS with the methods that have handlers
+// when there is no OPTIONS handler.  Any other method is not allowed.
+type methodRoute struct {
+	handlers map[string]MethodHandler
 }
 
-func (request *deleteRequest) Handle(client io.Writer) error {
-	request.Resource.Delete(client, request.Message)
-	return nil
-}
+func (route *methodRoute) Route(requested RequestMessage) Request {
+	method := requested.Method()
+	if handler, ok := route.handlers[method]; ok {
+		return &handlerRequest{Handler: handler, Message: requested}
+	}
 
-type DeleteResource interface {
-	Delete(client io.Writer, message RequestMessage)
+	switch {
+	case method == HEAD && route.handlers[GET] != nil:
+		return &headFromGetRequest{Message: requested, Get: route.handlers[GET]}
+	case method == OPTIONS:
+		return &staticOptionsRequest{SupportedMethods: route.allowedMethods()}
+	default:
+		return clienterror.MethodNotAllowed(route.allowedMethods()...)
+	}
 }
 
-/* GET */
-
-type getMethod struct{}
+// The methods with handlers, along with the ones that are answered without a handler of their own
+func (route *methodRoute) allowedMethods() []string {
+	allowed := []string{OPTIONS}
+	for method := range route.handlers {
+		if method != OPTIONS {
+			allowed = append(allowed, method)
+		}
+	}
 
-func (method *getMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(GetResource)
-	if ok {
-		return &getRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
+	if route.handlers[GET] != nil && route.handlers[HEAD] == nil {
+		allowed = append(allowed, HEAD)
 	}
 
-	return nil, false
+	sort.Strings(allowed)
+	return allowed
 }
 
-type getRequest struct {
-	Message  RequestMessage
-	Resource GetResource
+type handlerRequest struct {
+	Handler MethodHandler
+	Message RequestMessage
 }
 
-func (request *getRequest) Handle(client io.Writer) error {
-	request.Resource.Get(client, request.Message)
-	return nil
+func (request *handlerRequest) Handle(client msg.ResponseWriter) error {
+	return request.Handler(client, request.Message)
 }
 
-type GetResource interface {
-	Get(client io.Writer, message RequestMessage)
+/* DELETE */
+
+type DeleteResource interface {
+	Delete(client msg.ResponseWriter, message RequestMessage) error
 }
 
-/* HEAD */
+/* GET */
 
-type headMethod struct{}
+type GetResource interface {
+	Get(client msg.ResponseWriter, message RequestMessage) error
+}
 
-func (*headMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(HeadResource)
-	if ok {
-		return &headRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
-	}
+/* HEAD */
 
-	return nil, false
+type HeadResource interface {
+	Head(client msg.ResponseWriter, message RequestMessage) error
 }
 
-type headRequest struct {
-	Message  RequestMessage
-	Resource HeadResource
+// Responds to HEAD for a resource that only implements GET, by getting the resource without writing its body
+type headFromGetRequest struct {
+	Message RequestMessage
+	Get     MethodHandler
 }
 
-func (request *headRequest) Handle(client io.Writer) error {
-	request.Resource.Head(client, request.Message)
-	return nil
-}
+func (request *headFromGetRequest) Handle(client msg.ResponseWriter) error {
+	withoutBody := &bodyDiscardingWriter{ResponseWriter: client}
+	if err := request.Get(withoutBody, request.Message); err != nil {
+		return err
+	}
 
-type HeadResource interface {
-	Head(client io.Writer, message RequestMessage)
+	return withoutBody.EndMessage()
 }
 
-/* OPTIONS */
-
-type optionsMethod struct{}
+// Discards the body, while holding on to the message header so it can declare the length of the body that would have
+// been written.  See RFC 7231, Section 4.3.2 (https://tools.ietf.org/html/rfc7231#section-4.3.2).
+type bodyDiscardingWriter struct {
+	msg.ResponseWriter
+	numBytesDiscarded int
+}
 
-func (*optionsMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(OptionsResource)
-	if ok {
-		return &dynamicOptionsRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
+func (writer *bodyDiscardingWriter) Write(body []byte) (int, error) {
+	if writer.Status() == (msg.Status{}) {
+		return 0, msg.ErrMissingStatus
 	}
 
-	return nil, false
+	writer.numBytesDiscarded += len(body)
+	return len(body), nil
 }
 
-// Asks an OptionsResource what it thinks its supported HTTP methods are,
-// when there is no single answer that is known ahead of time
-type dynamicOptionsRequest struct {
-	Message  RequestMessage
-	Resource OptionsResource
-}
+func (writer *bodyDiscardingWriter) EndMessage() error {
+	header := writer.Header()
+	if writer.Status() != (msg.Status{}) && !header.Has("Content-Length") && !header.Has("Transfer-Encoding") {
+		header.Set("Content-Length", strconv.Itoa(writer.numBytesDiscarded))
+	}
 
-func (request *dynamicOptionsRequest) Handle(client io.Writer) error {
-	request.Resource.Options(client, request.Message)
-	return nil
+	return writer.ResponseWriter.EndMessage()
 }
 
+/* OPTIONS */
+
+// A resource that decides what its supported HTTP methods are when it is asked,
+// when there is no single answer that is known ahead of time
 type OptionsResource interface {
-	Options(client io.Writer, message RequestMessage)
+	Options(client msg.ResponseWriter, message RequestMessage) error
 }
 
 // Responds with a static set of supported HTTP methods that are known a priori
@@ -134,97 +160,25 @@ type staticOptionsRequest struct {
 	SupportedMethods []string
 }
 
-func (request *staticOptionsRequest) Handle(client io.Writer) error {
+func (request *staticOptionsRequest) Handle(client msg.ResponseWriter) error {
 	msg.RespondWithAllowHeader(client, success.OKStatus, request.SupportedMethods)
 	return nil
 }
 
 /* PATCH */
 
-type patchMethod struct{}
-
-func (*patchMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(PatchResource)
-	if ok {
-		return &patchRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
-	}
-
-	return nil, false
-}
-
-type patchRequest struct {
-	Message  RequestMessage
-	Resource PatchResource
-}
-
-func (request *patchRequest) Handle(client io.Writer) error {
-	request.Resource.Patch(client, request.Message)
-	return nil
-}
-
 type PatchResource interface {
-	Patch(client io.Writer, message RequestMessage)
+	Patch(client msg.ResponseWriter, message RequestMessage) error
 }
 
 /* POST */
 
-type postMethod struct{}
-
-func (*postMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(PostResource)
-	if ok {
-		return &postRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
-	}
-
-	return nil, false
-}
-
-type postRequest struct {
-	Message  RequestMessage
-	Resource PostResource
-}
-
-func (request *postRequest) Handle(client io.Writer) error {
-	request.Resource.Post(client, request.Message)
-	return nil
-}
-
 type PostResource interface {
-	Post(client io.Writer, message RequestMessage)
+	Post(client msg.ResponseWriter, message RequestMessage) error
 }
 
 /* PUT */
 
-type putMethod struct{}
-
-func (*putMethod) MakeRequest(message *requestMessage, resource Resource) (request Request, isSupported bool) {
-	supportedResource, ok := resource.(PutResource)
-	if ok {
-		return &putRequest{
-			Message:  message,
-			Resource: supportedResource,
-		}, true
-	}
-
-	return nil, false
-}
-
-type putRequest struct {
-	Message  RequestMessage
-	Resource PutResource
-}
-
-func (request *putRequest) Handle(client io.Writer) error {
-	request.Resource.Put(client, request.Message)
-	return nil
-}
-
 type PutResource interface {
-	Put(client io.Writer, message RequestMessage)
+	Put(client msg.ResponseWriter, message RequestMessage) error
 }
@@ -0,0 +1,61 @@
+package http
+
+import "github.com/kkrull/gohttp/msg"
+
+// Wraps behavior around a Route, such as authentication, compression, or logging.
+// It can look at the request message before calling next, answer the request itself without calling next, or wrap the
+// Request that next returns to see the response, such as its status once the Request has been handled.
+// A Route returns nil when it does not handle the request, which middleware should pass along unless it answers the
+// request itself.
+type Middleware func(next Route) Route
+
+// Combines middleware into one, in which the first middleware is the outermost.
+// The first one sees each request message first, and sees the response to it last.
+func Chain(middleware ...Middleware) Middleware {
+	return func(next Route) Route {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+
+		return next
+	}
+}
+
+// Wraps middleware around a Route, in which the first middleware is the outermost
+func Wrap(route Route, middleware ...Middleware) Route {
+	return Chain(middleware...)(route)
+}
+
+// Middleware that adds a field to the header of every response, such as one for a security policy
+func ResponseHeader(name string, value string) Middleware {
+	return func(next Route) Route {
+		return RouteFunc(func(requested RequestMessage) Request {
+			request := next.Route(requested)
+			if request == nil {
+				return nil
+			}
+
+			return RequestFunc(func(client msg.ResponseWriter) error {
+				if err := client.AddHeader(name, value); err != nil {
+					return err
+				}
+
+				return request.Handle(client)
+			})
+		})
+	}
+}
+
+// A Route that is a function
+type RouteFunc func(requested RequestMessage) Request
+
+func (route RouteFunc) Route(requested RequestMessage) Request {
+	return route(requested)
+}
+
+// A Request that is a function
+type RequestFunc func(client msg.ResponseWriter) error
+
+func (request RequestFunc) Handle(client msg.ResponseWriter) error {
+	return request(client)
+}
@@ -0,0 +1,165 @@
+package http_test
+
+import (
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/clienterror"
+	"github.com/kkrull/gohttp/msg/servererror"
+	"github.com/kkrull/gohttp/msg/success"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		calls    []string
+		response = &httptest.ResponseBuffer{}
+	)
+
+	// Middleware that records when it sees the request message, and when it sees the response
+	recording := func(name string) http.Middleware {
+		return func(next http.Route) http.Route {
+			return http.RouteFunc(func(requested http.RequestMessage) http.Request {
+				calls = append(calls, name+" routes")
+				request := next.Route(requested)
+				if request == nil {
+					return nil
+				}
+
+				return http.RequestFunc(func(client msg.ResponseWriter) error {
+					err := request.Handle(client)
+					calls = append(calls, name+" sees "+client.Status().Reason)
+					return err
+				})
+			})
+		}
+	}
+
+	okRoute := http.RouteFunc(func(requested http.RequestMessage) http.Request {
+		calls = append(calls, "route")
+		return http.RequestFunc(func(client msg.ResponseWriter) error {
+			msg.WriteStatus(client, success.OKStatus)
+			msg.WriteContentLengthHeader(client, 0)
+			msg.WriteEndOfMessageHeader(client)
+			return nil
+		})
+	})
+
+	BeforeEach(func() {
+		calls = nil
+		response.Reset()
+	})
+
+	Describe("::Wrap", func() {
+		It("runs the first middleware first, and lets it see the response status last", func() {
+			route := http.Wrap(okRoute, recording("outer"), recording("inner"))
+			request := route.Route(http.NewGetMessage("/"))
+			Expect(request.Handle(response)).To(Succeed())
+			Expect(calls).To(Equal([]string{
+				"outer routes",
+				"inner routes",
+				"route",
+				"inner sees OK",
+				"outer sees OK",
+			}))
+		})
+
+		It("lets middleware answer a request without calling the Route", func() {
+			denyAll := func(next http.Route) http.Route {
+				return http.RouteFunc(func(requested http.RequestMessage) http.Request {
+					return clienterror.MethodNotAllowed()
+				})
+			}
+
+			route := http.Wrap(okRoute, denyAll)
+			request := route.Route(http.NewGetMessage("/"))
+			Expect(request.Handle(response)).To(Succeed())
+			Expect(calls).To(BeEmpty())
+			httptest.ParseResponse(response).StatusShouldBe(405, "Method Not Allowed")
+		})
+	})
+
+	Describe("::Chain", func() {
+		It("combines middleware in the same order as Wrap", func() {
+			route := http.Chain(recording("first"), recording("second"))(okRoute)
+			route.Route(http.NewGetMessage("/"))
+			Expect(calls).To(Equal([]string{"first routes", "second routes", "route"}))
+		})
+	})
+
+	Describe("::ResponseHeader", func() {
+		It("adds the field to the header of the response", func() {
+			route := http.Wrap(okRoute, http.ResponseHeader("X-Frame-Options", "DENY"))
+			request := route.Route(http.NewGetMessage("/"))
+			Expect(request.Handle(response)).To(Succeed())
+			httptest.ParseResponse(response).HeaderShould("X-Frame-Options", Equal("DENY"))
+		})
+
+		It("returns nil when the Route does not handle the request", func() {
+			route := http.Wrap(&RouteMock{RouteReturns: nil}, http.ResponseHeader("X-Frame-Options", "DENY"))
+			Expect(route.Route(http.NewGetMessage("/"))).To(BeNil())
+		})
+	})
+
+	Describe("RequestLineRouter#Use", func() {
+		var router *http.RequestLineRouter
+
+		BeforeEach(func() {
+			router = http.NewRouter()
+		})
+
+		It("wraps the middleware around every route, outside of middleware for individual routes", func() {
+			router.Use(recording("global"))
+			router.Use(recording("second global"))
+			router.AddRoute(http.Wrap(okRoute, recording("route")))
+
+			request, err := router.RouteRequest(http.NewGetMessage("/"))
+			Expect(err).To(BeNil())
+			Expect(request.Handle(response)).To(Succeed())
+			Expect(calls).To(Equal([]string{
+				"global routes",
+				"second global routes",
+				"route routes",
+				"route",
+				"route sees OK",
+				"second global sees OK",
+				"global sees OK",
+			}))
+		})
+
+		It("responds Not Implemented through the middleware when no route handles the request", func() {
+			router.Use(recording("global"))
+			router.AddRoute(&RouteMock{RouteReturns: nil})
+
+			request, err := router.RouteRequest(http.NewGetMessage("/"))
+			Expect(err).To(BeNil())
+			Expect(request.Handle(response)).To(Succeed())
+			httptest.ParseResponse(response).StatusShouldBe(501, "Not Implemented")
+			Expect(calls).To(Equal([]string{"global routes", "global sees Not Implemented"}))
+		})
+
+		It("responds Not Implemented when the middleware returns no request", func() {
+			router.Use(func(next http.Route) http.Route {
+				return http.RouteFunc(func(requested http.RequestMessage) http.Request { return nil })
+			})
+			router.AddRoute(okRoute)
+
+			_, err := router.RouteRequest(http.NewGetMessage("/"))
+			Expect(err).To(BeEquivalentTo(&servererror.NotImplemented{Method: http.GET}))
+		})
+
+		It("wraps the middleware around the routes once, instead of for every request", func() {
+			numWraps := 0
+			router.Use(func(next http.Route) http.Route {
+				numWraps++
+				return next
+			})
+			router.AddRoute(okRoute)
+
+			router.RouteRequest(http.NewGetMessage("/"))
+			router.RouteRequest(http.NewGetMessage("/"))
+			Expect(numWraps).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+func NewMultiServer(servers ...*TCPServer) *MultiServer {
+	return &MultiServer{Servers: servers}
+}
+
+// Runs several TCPServers as one, such as one for HTTP and another for HTTPS that share the same Router.
+// They start together, and drain or shut down together.
+type MultiServer struct {
+	Servers []*TCPServer
+}
+
+// The address of the first server, or nil if it is not running
+func (multi *MultiServer) Address() net.Addr {
+	if len(multi.Servers) == 0 {
+		return nil
+	}
+
+	return multi.Servers[0].Address()
+}
+
+// The address of each running server, in order
+func (multi *MultiServer) Addresses() []net.Addr {
+	addresses := make([]net.Addr, 0, len(multi.Servers))
+	for _, server := range multi.Servers {
+		if address := server.Address(); address != nil {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+// Starts every server, or shuts down the ones that started if any of them can not start
+func (multi *MultiServer) Start() error {
+	for i, server := range multi.Servers {
+		if err := server.Start(); err != nil {
+			for _, started := range multi.Servers[:i] {
+				_ = started.Shutdown()
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Drains every server at the same time, returning the total number of connections that were forcibly closed
+func (multi *MultiServer) Drain(timeout time.Duration) (numForciblyClosed int, err error) {
+	var (
+		mutex sync.Mutex
+		wait  sync.WaitGroup
+	)
+
+	for _, server := range multi.Servers {
+		wait.Add(1)
+		go func(server *TCPServer) {
+			defer wait.Done()
+			numClosed, drainErr := server.Drain(timeout)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			numForciblyClosed += numClosed
+			if err == nil {
+				err = drainErr
+			}
+		}(server)
+	}
+
+	wait.Wait()
+	return numForciblyClosed, err
+}
+
+// Copies of the listening socket of each server, in order
+func (multi *MultiServer) ListenerFiles() ([]*os.File, error) {
+	files := make([]*os.File, 0, len(multi.Servers))
+	for _, server := range multi.Servers {
+		serverFiles, err := server.ListenerFiles()
+		if err != nil {
+			for _, file := range files {
+				_ = file.Close()
+			}
+
+			return nil, err
+		}
+
+		files = append(files, serverFiles...)
+	}
+
+	return files, nil
+}
+
+// Shuts down every server, returning the first error
+func (multi *MultiServer) Shutdown() error {
+	var firstErr error
+	for _, server := range multi.Servers {
+		if err := server.Shutdown(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,69 @@
+package http_test
+
+import (
+	"net"
+	"time"
+
+	"github.com/kkrull/gohttp/http"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MultiServer", func() {
+	var (
+		server *http.MultiServer
+		first  *http.TCPServer
+		second *http.TCPServer
+	)
+
+	BeforeEach(func() {
+		router := &EchoRouter{}
+		first = http.TCPServerBuilder("localhost").WithRouter(router).Build()
+		second = http.TCPServerBuilder("localhost").WithRouter(router).Build()
+		server = http.NewMultiServer(first, second)
+	})
+
+	AfterEach(func() {
+		Expect(server.Shutdown()).To(Succeed())
+	})
+
+	Describe("#Start", func() {
+		It("starts every server", func(done Done) {
+			Expect(server.Start()).To(Succeed())
+			Expect(server.Addresses()).To(HaveLen(2))
+			Expect(server.Address()).To(Equal(first.Address()))
+			close(done)
+		})
+
+		It("shuts down the servers that started, when another one can not start", func(done Done) {
+			second.Host = "666.666.666.666"
+			Expect(server.Start()).NotTo(Succeed())
+			Expect(first.Address()).To(BeNil())
+			close(done)
+		})
+	})
+
+	Describe("#Drain", func() {
+		It("stops every server", func(done Done) {
+			Expect(server.Start()).To(Succeed())
+			addresses := server.Addresses()
+			Expect(server.Drain(time.Second)).To(Equal(0))
+
+			for _, address := range addresses {
+				_, err := net.Dial("tcp", address.String())
+				Expect(err).To(HaveOccurred())
+			}
+
+			close(done)
+		})
+	})
+
+	Describe("#Shutdown", func() {
+		It("stops every server", func(done Done) {
+			Expect(server.Start()).To(Succeed())
+			Expect(server.Shutdown()).To(Succeed())
+			Expect(server.Addresses()).To(BeEmpty())
+			close(done)
+		})
+	})
+})
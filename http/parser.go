@@ -2,27 +2,36 @@ package http
 
 import (
 	"bufio"
+	"bytes"
+	"io"
+	"math"
 	"strconv"
 	"strings"
 
 	"github.com/kkrull/gohttp/msg/clienterror"
 )
 
-// Parses an HTTP request message one line at a time.
-type LineRequestParser struct{}
+// Parses an HTTP request message one line at a time, refusing any request that exceeds Limits.
+type LineRequestParser struct {
+	Limits RequestLimits
+}
 
-func (parser *LineRequestParser) Parse(reader *bufio.Reader) (ok *requestMessage, err Response) {
-	methodObject := &parseMethodObject{reader: reader}
+func (parser *LineRequestParser) Parse(reader *bufio.Reader, beforeBody ...func()) (ok *requestMessage, err Response) {
+	methodObject := &parseMethodObject{reader: reader, limits: parser.Limits, beforeBody: beforeBody}
 	return methodObject.ReadingRequestLine()
 }
 
 //A state machine that parses an HTTP request during the process of reading the request from input
 type parseMethodObject struct {
-	reader *bufio.Reader
+	reader           *bufio.Reader
+	limits           RequestLimits
+	beforeBody       []func()
+	headerBytesRead  int
+	headerFieldsRead int
 }
 
 func (parser *parseMethodObject) ReadingRequestLine() (ok *requestMessage, badRequest Response) {
-	requestLine, err := parser.readCRLFLine()
+	requestLine, err := parser.readCRLFLine(orNoLimit(parser.limits.MaxRequestLineBytes), &clienterror.URITooLong{})
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +41,10 @@ func (parser *parseMethodObject) ReadingRequestLine() (ok *requestMessage, badRe
 
 func (parser *parseMethodObject) parsingRequestLine(requestLine string) (ok *requestMessage, badRequest Response) {
 	const numFieldsInRequestLine = 3
+	if hasControlCharacter(requestLine, "") {
+		return nil, &clienterror.BadRequest{DisplayText: "control character in request-line"}
+	}
+
 	fields := strings.Split(requestLine, " ")
 	if len(fields) != numFieldsInRequestLine {
 		return nil, &clienterror.BadRequest{DisplayText: "incorrectly formatted or missing request-line"}
@@ -72,44 +85,237 @@ func (parser *parseMethodObject) parsingQueryString(requested *requestMessage, r
 }
 
 func (parser *parseMethodObject) readingHeaders(requested *requestMessage) (ok *requestMessage, badRequest Response) {
-	isBlankLineBetweenHeadersAndBody := func(line string) bool { return line == "" }
+	if err := parser.readingFields(requested); err != nil {
+		return nil, err
+	}
 
+	for _, notify := range parser.beforeBody {
+		notify()
+	}
+
+	return parser.readingBody(requested)
+}
+
+// Reads header fields up to the blank line at the end of the section, unfolding any values continued with obs-fold.
+// See RFC 7230, Section 3.2.4 (https://tools.ietf.org/html/rfc7230#section-3.2.4).
+func (parser *parseMethodObject) readingFields(requested *requestMessage) (badRequest Response) {
+	isObsoleteLineFold := func(line string) bool { return line[0] == ' ' || line[0] == '\t' }
+
+	unfolded := ""
 	for {
-		line, err := parser.readCRLFLine()
+		line, err := parser.readHeaderLine()
 		if err != nil {
-			return nil, err
-		} else if isBlankLineBetweenHeadersAndBody(line) {
-			return parser.readingBody(requested)
-		} else {
-			headerParts := strings.Split(line, ":")
-			requested.AddHeader(headerParts[0], strings.Trim(headerParts[1], " "))
+			return err
+		} else if line == "" {
+			break
+		} else if isObsoleteLineFold(line) {
+			if unfolded == "" {
+				return &clienterror.BadRequest{DisplayText: "obsolete line folding before the first header field"}
+			}
+
+			unfolded += " " + strings.Trim(line, optionalWhitespace)
+			continue
+		}
+
+		if unfolded != "" {
+			if err := parser.addingHeaderField(requested, unfolded); err != nil {
+				return err
+			}
 		}
+
+		unfolded = line
 	}
+
+	if unfolded == "" {
+		return nil
+	}
+
+	return parser.addingHeaderField(requested, unfolded)
+}
+
+// Reads the next line of the header section, as long as the section stays within MaxHeaderBytes
+func (parser *parseMethodObject) readHeaderLine() (line string, badRequest Response) {
+	maxBytes := orNoLimit(parser.limits.MaxHeaderBytes) - parser.headerBytesRead
+	line, err := parser.readCRLFLine(maxBytes, &clienterror.RequestHeaderFieldsTooLarge{})
+	parser.headerBytesRead += len(line)
+	return line, err
+}
+
+func (parser *parseMethodObject) addingHeaderField(requested *requestMessage, line string) (badRequest Response) {
+	parser.headerFieldsRead++
+	if parser.headerFieldsRead > orNoLimit(parser.limits.MaxHeaderFields) {
+		return &clienterror.RequestHeaderFieldsTooLarge{}
+	}
+
+	return addHeaderField(requested, line)
+}
+
+// Adds a field of the form name ":" OWS value OWS, splitting on the first colon since values may contain more of them.
+// See RFC 7230, Section 3.2 (https://tools.ietf.org/html/rfc7230#section-3.2).
+func addHeaderField(requested *requestMessage, line string) (badRequest Response) {
+	headerParts := strings.SplitN(line, ":", 2)
+	if len(headerParts) != 2 {
+		return &clienterror.BadRequest{DisplayText: "header field missing ':'"}
+	}
+
+	name := headerParts[0]
+	if name == "" {
+		return &clienterror.BadRequest{DisplayText: "header field missing name"}
+	} else if strings.ContainsAny(name, optionalWhitespace) {
+		return &clienterror.BadRequest{DisplayText: "whitespace in header field name"}
+	} else if hasControlCharacter(name, "") {
+		return &clienterror.BadRequest{DisplayText: "control character in header field name"}
+	} else if hasControlCharacter(headerParts[1], "\t") {
+		return &clienterror.BadRequest{DisplayText: "control character in header field value"}
+	}
+
+	requested.AddHeader(name, strings.Trim(headerParts[1], optionalWhitespace))
+	return nil
+}
+
+// OWS in RFC 7230, Section 3.2.3
+const optionalWhitespace = " \t"
+
+// Whether the text has a control character other than the ones allowed, such as a bare LF or NUL.  Field values may
+// only have HTAB, besides visible characters, spaces, and obs-text (RFC 7230, Section 3.2).
+func hasControlCharacter(text string, allowed string) bool {
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; (c < ' ' || c == 0x7f) && strings.IndexByte(allowed, c) < 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 const (
 	base10      = 10
+	base16      = 16
 	bitsInInt64 = 64
 )
 
 func (parser *parseMethodObject) readingBody(requested *requestMessage) (ok *requestMessage, badRequest Response) {
+	transferCodings := headerTokens(requested, "Transfer-Encoding")
 	contentLengths := requested.HeaderValues("Content-Length")
+	switch {
+	case len(transferCodings) > 0 && len(contentLengths) > 0:
+		return nil, &clienterror.BadRequest{DisplayText: "both Transfer-Encoding and Content-Length"}
+	case len(transferCodings) > 0:
+		return parser.checkingTransferCodings(requested, transferCodings)
+	}
+
 	switch len(contentLengths) {
 	case 0:
 		return requested, nil
 	case 1:
-		contentLength, _ := strconv.ParseInt(contentLengths[0], base10, bitsInInt64)
-		body := make([]byte, contentLength)
-		_, _ = parser.reader.Read(body)
-		requested.SetBody(body)
-		return requested, nil
+		contentLength, parseErr := strconv.ParseInt(contentLengths[0], base10, bitsInInt64)
+		if parseErr != nil || contentLengths[0] == "" || !isDigits(contentLengths[0]) {
+			return nil, &clienterror.BadRequest{DisplayText: "invalid Content-Length"}
+		} else if parser.isBodyTooLarge(contentLength) {
+			return nil, &clienterror.PayloadTooLarge{}
+		}
+
+		return parser.readingFixedLengthBody(requested, contentLength)
 	default:
 		return nil, &clienterror.BadRequest{DisplayText: "2 or more Content-Length headers"}
 	}
 }
 
-func (parser *parseMethodObject) readCRLFLine() (line string, badRequest Response) {
-	maybeEndsInCR, _ := parser.reader.ReadString('\r')
+//Reads exactly as many bytes as the body has, so that any pipelined requests after it remain on the reader.
+//The body grows as it arrives, instead of being allocated up front from whatever length the client claims.
+func (parser *parseMethodObject) readingFixedLengthBody(requested *requestMessage, contentLength int64) (ok *requestMessage, badRequest Response) {
+	body := &bytes.Buffer{}
+	if _, err := io.CopyN(body, parser.reader, contentLength); err != nil {
+		return nil, &clienterror.BadRequest{DisplayText: "end of input before end of message body"}
+	}
+
+	requested.SetBody(body.Bytes())
+	return requested, nil
+}
+
+//Only chunked is supported, which has to be the final coding on a request (RFC 7230, Section 3.3.3)
+func (parser *parseMethodObject) checkingTransferCodings(requested *requestMessage, transferCodings []string) (ok *requestMessage, badRequest Response) {
+	if len(transferCodings) != 1 || transferCodings[0] != "chunked" {
+		return nil, &clienterror.BadRequest{DisplayText: "unsupported Transfer-Encoding"}
+	}
+
+	return parser.readingChunkedBody(requested)
+}
+
+//Decodes a body in the chunked transfer coding (RFC 7230, Section 4.1)
+func (parser *parseMethodObject) readingChunkedBody(requested *requestMessage) (ok *requestMessage, badRequest Response) {
+	body := &bytes.Buffer{}
+	for {
+		chunkSize, err := parser.readingChunkSize()
+		if err != nil {
+			return nil, err
+		} else if chunkSize == 0 {
+			requested.SetBody(body.Bytes())
+			return parser.readingTrailers(requested)
+		} else if parser.isBodyTooLarge(int64(body.Len()) + chunkSize) {
+			return nil, &clienterror.PayloadTooLarge{}
+		} else if err := parser.readingChunkData(body, chunkSize); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *parseMethodObject) isBodyTooLarge(numBytes int64) bool {
+	return parser.limits.MaxBodyBytes > 0 && numBytes > parser.limits.MaxBodyBytes
+}
+
+func (parser *parseMethodObject) readingChunkSize() (size int64, badRequest Response) {
+	const maxChunkSizeLineBytes = 1024
+	line, err := parser.readCRLFLine(maxChunkSizeLineBytes, &clienterror.BadRequest{DisplayText: "chunk size line too long"})
+	if err != nil {
+		return 0, err
+	}
+
+	withoutExtensions := strings.TrimRight(strings.SplitN(line, ";", 2)[0], " \t")
+	size, parseErr := strconv.ParseInt(withoutExtensions, base16, bitsInInt64)
+	if parseErr != nil || strings.IndexFunc(withoutExtensions, isNotHexDigit) >= 0 {
+		return 0, &clienterror.BadRequest{DisplayText: "invalid chunk size"}
+	}
+
+	return size, nil
+}
+
+// chunk-size is 1*HEXDIG, without the sign that strconv.ParseInt would accept
+func isNotHexDigit(r rune) bool {
+	return r > 0x7f || !isHexDigit(byte(r))
+}
+
+func (parser *parseMethodObject) readingChunkData(body *bytes.Buffer, chunkSize int64) (badRequest Response) {
+	if _, err := io.CopyN(body, parser.reader, chunkSize); err != nil {
+		return &clienterror.BadRequest{DisplayText: "end of input before end of chunk"}
+	}
+
+	_, err := parser.readCRLFLine(0, &clienterror.BadRequest{DisplayText: "chunk data longer than chunk size"})
+	return err
+}
+
+//Trailer fields after the last chunk are added to the other header fields
+func (parser *parseMethodObject) readingTrailers(requested *requestMessage) (ok *requestMessage, badRequest Response) {
+	if err := parser.readingFields(requested); err != nil {
+		return nil, err
+	}
+
+	return requested, nil
+}
+
+// Reads a line ending in CRLF, stopping with tooLong once it has read more than maxBytes (not counting the CRLF)
+func (parser *parseMethodObject) readCRLFLine(maxBytes int, tooLong Response) (line string, badRequest Response) {
+	var maybeEndsInCR string
+	for {
+		fragment, err := parser.reader.ReadSlice('\r')
+		maybeEndsInCR += string(fragment)
+		if len(strings.TrimSuffix(maybeEndsInCR, "\r")) > maxBytes {
+			return "", tooLong
+		} else if err != bufio.ErrBufferFull {
+			break
+		}
+	}
+
 	if len(maybeEndsInCR) == 0 {
 		return "", &clienterror.BadRequest{DisplayText: "end of input before terminating CRLF"}
 	} else if !strings.HasSuffix(maybeEndsInCR, "\r") {
@@ -146,3 +352,12 @@ func extractFragment(target string) (prefix string, fragment string) {
 		return fields[0], fields[1]
 	}
 }
+
+// Treats a limit of 0 as no limit at all
+func orNoLimit(limit int) int {
+	if limit <= 0 {
+		return math.MaxInt32
+	}
+
+	return limit
+}
@@ -3,6 +3,7 @@ package http_test
 import (
 	"bufio"
 	"bytes"
+	"testing/iotest"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -63,6 +64,72 @@ var _ = Describe("LineRequestParser", func() {
 				request, err = parser.Parse(makeReader(" GET / HTTP/1.1\r\n\r\n"))
 				Expect(err).To(beABadRequestResponse("incorrectly formatted or missing request-line"))
 			})
+
+			It("when Content-Length is not a number", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: four\r\n\r\nABCD"))
+				Expect(err).To(beABadRequestResponse("invalid Content-Length"))
+			})
+
+			It("when Content-Length is negative", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: -4\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("invalid Content-Length"))
+			})
+
+			It("when Content-Length has a sign or anything else besides digits", func() {
+				for _, contentLength := range []string{"+4", "4.0", "0x4", ""} {
+					request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: %s\r\n\r\nABCD", contentLength))
+					Expect(err).To(beABadRequestResponse("invalid Content-Length"))
+				}
+			})
+
+			It("when Content-Length is huge but the body is not, without allocating it up front", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: 9000000000000000000\r\n\r\nABCD"))
+				Expect(err).To(beABadRequestResponse("end of input before end of message body"))
+			})
+
+			It("when the body is shorter than Content-Length", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nABCD"))
+				Expect(err).To(beABadRequestResponse("end of input before end of message body"))
+			})
+
+			It("when there is whitespace between a header field name and the colon", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nHost : localhost\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("whitespace in header field name"))
+			})
+
+			It("when a header field has no name", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\n: localhost\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("header field missing name"))
+			})
+
+			It("when the first header line is folded onto a field that does not exist", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\n folded\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("obsolete line folding before the first header field"))
+			})
+
+			It("when the request-line has a bare LF, NUL, or other control character", func() {
+				for _, character := range []string{"\n", "\x00", "\t", "\x1b", "\x7f"} {
+					request, err = parser.Parse(makeReader("GET /x%sSet-Cookie:a HTTP/1.1\r\n\r\n", character))
+					Expect(err).To(beABadRequestResponse("control character in request-line"))
+				}
+			})
+
+			It("when a header field value has a bare LF, NUL, or other control character", func() {
+				for _, character := range []string{"\n", "\x00", "\x1b", "\x7f"} {
+					request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nHost: evil%sSet-Cookie:\r\n\r\n", character))
+					Expect(err).To(beABadRequestResponse("control character in header field value"))
+				}
+			})
+
+			It("when a header field name has a control character", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nX\x00Name: value\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("control character in header field name"))
+			})
+
+			It("when a trailer field value has a control character", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nX-Trailer: a\nb\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("control character in header field value"))
+			})
 		})
 
 		Context("given a well-formed request", func() {
@@ -119,6 +186,136 @@ var _ = Describe("LineRequestParser", func() {
 			})
 		})
 
+		Context("given a request with Transfer-Encoding: chunked", func() {
+			var reader *bufio.Reader
+
+			BeforeEach(func() {
+				reader = makeReader("POST /foo HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n" +
+					"4\r\nWiki\r\n" +
+					"5;name=value\r\npedia\r\n" +
+					"E\r\n in\r\n\r\nchunks.\r\n" +
+					"0\r\n" +
+					"Expires: never\r\n" +
+					"\r\n")
+				request, err = parser.Parse(reader)
+			})
+
+			It("returns no error", func() {
+				Expect(err).To(BeNil())
+			})
+
+			It("decodes the chunks into the body", func() {
+				Expect(request.Body()).To(Equal([]byte("Wikipedia in\r\n\r\nchunks.")))
+			})
+
+			It("adds trailer fields to the headers", func() {
+				Expect(request.HeaderValues("Expires")).To(Equal([]string{"never"}))
+			})
+
+			It("reads the whole request, up to the end of the trailers", func() {
+				Expect(reader.Buffered()).To(Equal(0))
+			})
+		})
+
+		Context("given chunked requests with no trailers, one after another", func() {
+			It("parses each request in turn", func() {
+				reader := makeReader("POST /one HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nABC\r\n0\r\n\r\n" +
+					"GET /two HTTP/1.1\r\n\r\n")
+
+				request, err = parser.Parse(reader)
+				Expect(err).To(BeNil())
+				Expect(request.Body()).To(Equal([]byte("ABC")))
+
+				request, err = parser.Parse(reader)
+				Expect(err).To(BeNil())
+				Expect(request.Target()).To(Equal("/two"))
+			})
+		})
+
+		Describe("it returns 400 Bad Request for chunked bodies", func() {
+			It("when the request also has Content-Length", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: 3\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nABC\r\n0\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("both Transfer-Encoding and Content-Length"))
+			})
+
+			It("when chunked is not the only transfer coding", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: gzip, chunked\r\n\r\n0\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("unsupported Transfer-Encoding"))
+			})
+
+			It("when the chunk size is not hexadecimal", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nG\r\nABC\r\n0\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("invalid chunk size"))
+			})
+
+			It("when the chunk size has a sign", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n+3\r\nABC\r\n0\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("invalid chunk size"))
+			})
+
+			It("when the chunk data is longer than the chunk size", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nABC\r\n0\r\n\r\n"))
+				Expect(err).To(beABadRequestResponse("chunk data longer than chunk size"))
+			})
+
+			It("when the input ends in the middle of a chunk", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n8\r\nABC"))
+				Expect(err).To(beABadRequestResponse("end of input before end of chunk"))
+			})
+
+			It("when the input ends before the last chunk", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nABC\r\n"))
+				Expect(err).To(beABadRequestResponse("end of input before terminating CRLF"))
+			})
+		})
+
+		Context("given several pipelined requests on the same reader", func() {
+			var reader *bufio.Reader
+
+			BeforeEach(func() {
+				reader = makeReader("POST /one HTTP/1.1\r\nContent-Length: 3\r\n\r\nABC" +
+					"GET /two HTTP/1.1\r\n\r\n" +
+					"PUT /three HTTP/1.1\r\nContent-Length: 4\r\n\r\nWXYZ")
+			})
+
+			It("parses each request in turn, using Content-Length to find where each body ends", func() {
+				request, err = parser.Parse(reader)
+				Expect(err).To(BeNil())
+				Expect(request.Target()).To(Equal("/one"))
+				Expect(request.Body()).To(Equal([]byte("ABC")))
+
+				request, err = parser.Parse(reader)
+				Expect(err).To(BeNil())
+				Expect(request.Target()).To(Equal("/two"))
+				Expect(request.Body()).To(BeEmpty())
+
+				request, err = parser.Parse(reader)
+				Expect(err).To(BeNil())
+				Expect(request.Target()).To(Equal("/three"))
+				Expect(request.Body()).To(Equal([]byte("WXYZ")))
+			})
+
+			It("leaves nothing on the reader after the last request", func() {
+				for i := 0; i < 3; i++ {
+					_, err = parser.Parse(reader)
+					Expect(err).To(BeNil())
+				}
+
+				Expect(reader.Buffered()).To(Equal(0))
+			})
+		})
+
+		Context("given a body that arrives in several reads", func() {
+			It("reads until the whole body has arrived", func() {
+				reader := bufio.NewReaderSize(iotest.OneByteReader(
+					bytes.NewBufferString("POST / HTTP/1.1\r\nContent-Length: 20\r\n\r\n01234567890123456789")),
+					16)
+				request, err = parser.Parse(reader)
+				Expect(err).To(BeNil())
+				Expect(request.Body()).To(Equal([]byte("01234567890123456789")))
+			})
+		})
+
 		Context("given a target with no query or fragment", func() {
 			BeforeEach(func() {
 				request, _ = parser.Parse(requestWithTarget("/widget"))
@@ -220,6 +417,130 @@ var _ = Describe("LineRequestParser", func() {
 				Expect(request.HeaderValues("One")).To(Equal([]string{"1"}))
 				Expect(request.HeaderValues("Double")).To(Equal([]string{"text/html", "text/plain"}))
 			})
+
+			It("accepts tabs and obs-text inside field values", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nX-Text: a\tb \xe9\r\n\r\n"))
+				Expect(err).To(BeNil())
+				Expect(request.HeaderValues("X-Text")).To(Equal([]string{"a\tb \xe9"}))
+			})
+		})
+
+		Context("given functions to call before the body", func() {
+			var numCalls int
+
+			BeforeEach(func() {
+				numCalls = 0
+			})
+
+			It("calls them once the header has been read", func() {
+				reader := makeReader("POST / HTTP/1.1\r\nContent-Length: 4\r\n\r\nABCD")
+				request, err = parser.Parse(reader, func() {
+					numCalls++
+					Expect(reader.Buffered()).To(Equal(len("ABCD")))
+				})
+				Expect(numCalls).To(Equal(1))
+			})
+
+			It("does not call them when the header can not be parsed", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nHost : localhost\r\n\r\n"), func() { numCalls++ })
+				Expect(numCalls).To(Equal(0))
+			})
+		})
+
+		Context("given RequestLimits", func() {
+			BeforeEach(func() {
+				parser = &http.LineRequestParser{Limits: http.RequestLimits{
+					MaxRequestLineBytes: len("GET /12345 HTTP/1.1"),
+					MaxHeaderBytes:      len("Content-Length: 4") + len("Host: a"),
+					MaxHeaderFields:     2,
+					MaxBodyBytes:        4,
+				}}
+			})
+
+			It("accepts a request that is right at each limit", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: 4\r\nHost: a\r\n\r\nABCD"))
+				Expect(err).NotTo(HaveOccurred())
+
+				request, err = parser.Parse(makeReader("GET /12345 HTTP/1.1\r\n\r\n"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns 414 URI Too Long for a longer request-line", func() {
+				request, err = parser.Parse(makeReader("GET /123456 HTTP/1.1\r\n\r\n"))
+				Expect(err).To(BeEquivalentTo(&clienterror.URITooLong{}))
+			})
+
+			It("returns 431 Request Header Fields Too Large for more header bytes", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nContent-Length: 0\r\nHost: ab\r\n\r\n"))
+				Expect(err).To(BeEquivalentTo(&clienterror.RequestHeaderFieldsTooLarge{}))
+			})
+
+			It("returns 431 Request Header Fields Too Large for more header fields", func() {
+				request, err = parser.Parse(makeReader("GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n"))
+				Expect(err).To(BeEquivalentTo(&clienterror.RequestHeaderFieldsTooLarge{}))
+			})
+
+			It("returns 413 Payload Too Large for a longer Content-Length, without reading the body", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nABCDE"))
+				Expect(err).To(BeEquivalentTo(&clienterror.PayloadTooLarge{}))
+			})
+
+			It("returns 413 Payload Too Large for chunks that add up to a larger body", func() {
+				request, err = parser.Parse(makeReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n" +
+					"3\r\nABC\r\n2\r\nDE\r\n0\r\n\r\n"))
+				Expect(err).To(BeEquivalentTo(&clienterror.PayloadTooLarge{}))
+			})
+		})
+
+		Context("given a request with header fields in other cases", func() {
+			BeforeEach(func() {
+				message := makeReader("POST / HTTP/1.1\r\ncontent-length: 4\r\nX-FORWARDED-FOR: proxy\r\n\r\nABCD")
+				request, _ = parser.Parse(message)
+			})
+
+			It("looks up fields without regard to case", func() {
+				Expect(request.HeaderValues("Content-Length")).To(Equal([]string{"4"}))
+				Expect(request.HeaderValues("x-forwarded-for")).To(Equal([]string{"proxy"}))
+			})
+
+			It("canonicalises field names", func() {
+				Expect(request.HeaderLines()).To(Equal([]string{
+					"Content-Length: 4",
+					"X-Forwarded-For: proxy",
+				}))
+			})
+
+			It("frames the body with fields in any case", func() {
+				Expect(request.Body()).To(Equal([]byte("ABCD")))
+			})
+		})
+
+		Context("given header field values", func() {
+			It("keeps any colons after the first one", func() {
+				request, _ = parser.Parse(makeReader("GET / HTTP/1.1\r\nHost: localhost:8080\r\n\r\n"))
+				Expect(request.HeaderValues("Host")).To(Equal([]string{"localhost:8080"}))
+			})
+
+			It("trims optional whitespace around the value", func() {
+				request, _ = parser.Parse(makeReader("GET / HTTP/1.1\r\nAccept:\t text/plain \t\r\n\r\n"))
+				Expect(request.HeaderValues("Accept")).To(Equal([]string{"text/plain"}))
+			})
+
+			It("allows an empty value", func() {
+				request, _ = parser.Parse(makeReader("GET / HTTP/1.1\r\nAccept:\r\n\r\n"))
+				Expect(request.HeaderValues("Accept")).To(Equal([]string{""}))
+			})
+
+			It("replaces obsolete line folding with a space", func() {
+				request, _ = parser.Parse(makeReader("GET / HTTP/1.1\r\nX-Folded: one\r\n two\r\n\tthree\r\nAccept: */*\r\n\r\n"))
+				Expect(request.HeaderValues("X-Folded")).To(Equal([]string{"one two three"}))
+				Expect(request.HeaderValues("Accept")).To(Equal([]string{"*/*"}))
+			})
+
+			It("combines the values of repeated list fields into one comma-separated value", func() {
+				request, _ = parser.Parse(makeReader("GET / HTTP/1.1\r\nAccept: text/html\r\naccept: text/plain, */*\r\n\r\n"))
+				Expect(request.CombinedHeaderValue("Accept")).To(Equal("text/html, text/plain, */*"))
+			})
 		})
 	})
 })
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A path pattern like /users/{id}/files/{name...}, which matches paths segment by segment.
+// A static segment matches itself, {name} matches any one segment that is not empty, and {name...} matches the rest
+// of the path (even if that is empty) and may only be the last segment.
+type PathPattern struct {
+	Pattern  string
+	segments []patternSegment
+}
+
+type patternSegment struct {
+	kind segmentKind
+	text string // The static text, or the name of the parameter
+}
+
+// Kinds of segments, in order of precedence when more than one pattern matches the same path
+type segmentKind int
+
+const (
+	staticSegment segmentKind = iota
+	parameterSegment
+	wildcardSegment
+)
+
+// Parses a path pattern, returning an error that says what is wrong with the pattern when it is not valid
+func ParsePathPattern(pattern string) (*PathPattern, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("invalid path pattern %q: must start with /", pattern)
+	}
+
+	parsed := &PathPattern{Pattern: pattern}
+	names := make(map[string]bool)
+	texts := strings.Split(pattern[1:], "/")
+	for i, text := range texts {
+		segment, err := parseSegment(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %s", pattern, err)
+		} else if segment.kind == wildcardSegment && i != len(texts)-1 {
+			return nil, fmt.Errorf("invalid path pattern %q: {%s...} must be the last segment", pattern, segment.text)
+		} else if segment.kind != staticSegment && names[segment.text] {
+			return nil, fmt.Errorf("invalid path pattern %q: duplicate parameter %s", pattern, segment.text)
+		}
+
+		if segment.kind != staticSegment {
+			names[segment.text] = true
+		}
+
+		parsed.segments = append(parsed.segments, segment)
+	}
+
+	return parsed, nil
+}
+
+func parseSegment(text string) (patternSegment, error) {
+	isParameter := strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}")
+	if strings.ContainsAny(text, "{}") && !isParameter {
+		return patternSegment{}, fmt.Errorf("a parameter must be the whole segment: %s", text)
+	} else if !isParameter {
+		return patternSegment{kind: staticSegment, text: text}, nil
+	}
+
+	name := text[1 : len(text)-1]
+	kind := parameterSegment
+	if strings.HasSuffix(name, "...") {
+		name = strings.TrimSuffix(name, "...")
+		kind = wildcardSegment
+	}
+
+	if name == "" || strings.IndexFunc(name, isInvalidParameterNameRune) >= 0 {
+		return patternSegment{}, fmt.Errorf("invalid parameter name: %s", text)
+	}
+
+	return patternSegment{kind: kind, text: name}, nil
+}
+
+func isInvalidParameterNameRune(r rune) bool {
+	isLetter := ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+	isDigit := '0' <= r && r <= '9'
+	return !(isLetter || isDigit || r == '_')
+}
+
+// The value of each parameter in the pattern, when it matches the given path
+func (pattern *PathPattern) Match(path string) (parameters map[string]string, matches bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	parameters = make(map[string]string)
+	texts := strings.Split(path[1:], "/")
+	for i, segment := range pattern.segments {
+		switch {
+		case i >= len(texts):
+			return nil, false
+		case segment.kind == wildcardSegment:
+			parameters[segment.text] = strings.Join(texts[i:], "/")
+			return parameters, true
+		case segment.kind == parameterSegment && texts[i] == "":
+			return nil, false
+		case segment.kind == parameterSegment:
+			parameters[segment.text] = texts[i]
+		case texts[i] != segment.text:
+			return nil, false
+		}
+	}
+
+	if len(texts) != len(pattern.segments) {
+		return nil, false
+	}
+
+	return parameters, true
+}
+
+// Whether this pattern takes precedence over another one that matches the same path, because it is more specific.
+// Comparing segments in order, the first one that differs decides: static > parameter > wildcard.
+func (pattern *PathPattern) precedes(other *PathPattern) bool {
+	for i := 0; i < len(pattern.segments) && i < len(other.segments); i++ {
+		if pattern.segments[i].kind != other.segments[i].kind {
+			return pattern.segments[i].kind < other.segments[i].kind
+		}
+	}
+
+	return len(pattern.segments) > len(other.segments)
+}
+
+// Whether both patterns match exactly the same paths, so that neither one takes precedence over the other
+func (pattern *PathPattern) conflictsWith(other *PathPattern) bool {
+	if len(pattern.segments) != len(other.segments) {
+		return false
+	}
+
+	for i, segment := range pattern.segments {
+		otherSegment := other.segments[i]
+		if segment.kind != otherSegment.kind {
+			return false
+		} else if segment.kind == staticSegment && segment.text != otherSegment.text {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (pattern *PathPattern) String() string {
+	return pattern.Pattern
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kkrull/gohttp/msg/redirect"
+)
+
+func NewHTTPSRedirectRouter(host string, httpsPort uint16) *HTTPSRedirectRouter {
+	return &HTTPSRedirectRouter{Host: host, HTTPSPort: httpsPort}
+}
+
+// Routes every request to a redirect to the same target on the HTTPS origin, for a plain HTTP listener that only sends
+// clients to HTTPS.  GET and HEAD are redirected with 301 Moved Permanently, and other methods are redirected with
+// 308 Permanent Redirect so that clients repeat them with the same method and body.
+// The host name comes from the request's Host header, or from Host when the request does not have a valid one.
+// Nothing from the request is copied into the Location header without checking its syntax or percent-encoding it.
+type HTTPSRedirectRouter struct {
+	Host      string
+	HTTPSPort uint16
+}
+
+func (router *HTTPSRedirectRouter) RouteRequest(requested RequestMessage) (ok Request, err Response) {
+	location := fmt.Sprintf("https://%s%s", router.authority(requested), escapeTarget(requested.Target()))
+	switch requested.Method() {
+	case GET, HEAD:
+		return redirect.MovedPermanently(location), nil
+	default:
+		return redirect.PermanentRedirect(location), nil
+	}
+}
+
+func (router *HTTPSRedirectRouter) Routes() []Route {
+	return nil
+}
+
+func (router *HTTPSRedirectRouter) authority(requested RequestMessage) string {
+	host := router.Host
+	if hosts := requested.HeaderValues("Host"); len(hosts) > 0 {
+		if requestedHost, valid := parseHostHeader(hosts[0]); valid {
+			host = requestedHost
+		}
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	if router.HTTPSPort == 443 {
+		return host
+	}
+
+	return fmt.Sprintf("%s:%d", host, router.HTTPSPort)
+}
+
+// The host from a Host header, without any port.  IPv6 addresses lose their brackets.
+func withoutPort(hostAndPort string) string {
+	if host, _, err := net.SplitHostPort(hostAndPort); err == nil {
+		return host
+	}
+
+	return strings.Trim(hostAndPort, "[]")
+}
+
+// The host from a Host header, without any port or the brackets around an IPv6 address.  Only accepts a reg-name,
+// IPv4 address, or bracketed IPv6 address with an optional port, as in RFC 7230, Section 5.4
+// (https://tools.ietf.org/html/rfc7230#section-5.4).
+func parseHostHeader(value string) (host string, valid bool) {
+	host, port := value, ""
+	if colon := strings.LastIndexByte(value, ':'); colon >= 0 && !strings.HasSuffix(value, "]") {
+		host, port = value[:colon], value[colon+1:]
+	}
+
+	if !isDigits(port) {
+		return "", false
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		address := host[1 : len(host)-1]
+		return address, strings.Contains(address, ":") && net.ParseIP(address) != nil
+	}
+
+	return host, isRegName(host)
+}
+
+// Whether the host is a non-empty reg-name, which includes IPv4 addresses.  See RFC 3986, Section 3.2.2
+// (https://tools.ietf.org/html/rfc3986#section-3.2.2).
+func isRegName(host string) bool {
+	if host == "" {
+		return false
+	}
+
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-._~!$&'()*+,;=", c) >= 0:
+		case c == '%' && i+2 < len(host) && isHexDigit(host[i+1]) && isHexDigit(host[i+2]):
+			i += 2
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// Percent-encodes any control character, space, or non-ASCII byte in a request target, so it is safe in a header
+func escapeTarget(target string) string {
+	escaped := &strings.Builder{}
+	for i := 0; i < len(target); i++ {
+		if c := target[i]; c <= ' ' || c >= 0x7f {
+			fmt.Fprintf(escaped, "%%%02X", c)
+		} else {
+			escaped.WriteByte(c)
+		}
+	}
+
+	return escaped.String()
+}
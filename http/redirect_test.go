@@ -0,0 +1,86 @@
+package http_test
+
+import (
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTPSRedirectRouter", func() {
+	Describe("#RouteRequest", func() {
+		var (
+			router    *http.HTTPSRedirectRouter
+			requested *httptest.RequestMessage
+			response  *httptest.ResponseBuffer
+		)
+
+		BeforeEach(func() {
+			router = http.NewHTTPSRedirectRouter("localhost", 8443)
+			response = &httptest.ResponseBuffer{}
+		})
+
+		redirect := func() *httptest.ResponseMessage {
+			request, err := router.RouteRequest(requested)
+			Expect(err).To(BeNil())
+			Expect(request.Handle(response)).To(Succeed())
+			return httptest.ParseResponse(response)
+		}
+
+		It("redirects GET to the same target on the HTTPS port, with 301 Moved Permanently", func() {
+			requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/files?sort=name"}
+			requested.AddHeader("Host", "example.com:8080")
+
+			parsed := redirect()
+			parsed.StatusShouldBe(301, "Moved Permanently")
+			parsed.HeaderShould("Location", Equal("https://example.com:8443/files?sort=name"))
+		})
+
+		It("redirects other methods with 308 Permanent Redirect, so the client does not change the method", func() {
+			requested = &httptest.RequestMessage{MethodReturns: http.POST, TargetReturns: "/form"}
+			requested.AddHeader("Host", "example.com")
+
+			parsed := redirect()
+			parsed.StatusShouldBe(308, "Permanent Redirect")
+			parsed.HeaderShould("Location", Equal("https://example.com:8443/form"))
+		})
+
+		It("leaves out the port when HTTPS is on the default port", func() {
+			router.HTTPSPort = 443
+			requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/"}
+			requested.AddHeader("Host", "example.com")
+			redirect().HeaderShould("Location", Equal("https://example.com/"))
+		})
+
+		It("keeps the brackets around an IPv6 address", func() {
+			requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/"}
+			requested.AddHeader("Host", "[::1]:8080")
+			redirect().HeaderShould("Location", Equal("https://[::1]:8443/"))
+		})
+
+		It("uses the configured host when the request has no Host header", func() {
+			requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/"}
+			redirect().HeaderShould("Location", Equal("https://localhost:8443/"))
+		})
+
+		It("uses the configured host when the Host header is not a host name or address", func() {
+			requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/x"}
+			requested.AddHeader("Host", "evil\nSet-Cookie:")
+			redirect().HeaderShould("Location", Equal("https://localhost:8443/x"))
+			Expect(response.String()).NotTo(ContainSubstring("\nSet-Cookie"))
+
+			for _, host := range []string{"", "example.com:80x", "[example.com]", "exa mple.com", "::1", "a/b"} {
+				requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/x"}
+				requested.AddHeader("Host", host)
+				response.Reset()
+				redirect().HeaderShould("Location", Equal("https://localhost:8443/x"))
+			}
+		})
+
+		It("percent-encodes control characters, spaces, and non-ASCII bytes in the target", func() {
+			requested = &httptest.RequestMessage{MethodReturns: http.GET, TargetReturns: "/x\nSet-Cookie:a b\xff"}
+			requested.AddHeader("Host", "example.com")
+			redirect().HeaderShould("Location", Equal("https://example.com:8443/x%0ASet-Cookie:a%20b%FF"))
+		})
+	})
+})
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/kkrull/gohttp/msg"
+)
+
+// Creates a ResponseWriter for a request with no special needs, such as an HTTP/1.1 GET request
+func NewResponseWriter(client io.Writer) msg.ResponseWriter {
+	return &responseWriter{
+		client:   client,
+		canChunk: true,
+	}
+}
+
+// Creates a ResponseWriter that frames the response in the way the requesting client understands, and that omits the
+// body of a response to HEAD
+func NewResponseWriterFor(client io.Writer, requested RequestMessage) msg.ResponseWriter {
+	return newResponseWriterFor(client, requested)
+}
+
+func newResponseWriterFor(client io.Writer, requested RequestMessage) *responseWriter {
+	return &responseWriter{
+		client:   client,
+		canChunk: requested.Version() != VERSION_1_0,
+		omitBody: requested.Method() == HEAD,
+	}
+}
+
+// Holds the status and header fields of a response until the header is committed, which happens when the body is first
+// written or the message ends.  The body is framed by the response's own Content-Length or Transfer-Encoding, if it has
+// one.  Otherwise the body is chunked, or delimited by closing the connection for clients that do not support chunking.
+// See RFC 7230, Section 3.3.3 (https://tools.ietf.org/html/rfc7230#section-3.3.3).
+type responseWriter struct {
+	client   io.Writer
+	canChunk bool
+	omitBody bool
+
+	status      msg.Status
+	header      msg.Header
+	trailer     msg.Header
+	headerEnded bool
+
+	body         io.Writer
+	chunks       *msg.ChunkedWriter
+	numBodyBytes int64
+	messageEnded bool
+}
+
+func (writer *responseWriter) Status() msg.Status {
+	return writer.status
+}
+
+func (writer *responseWriter) WriteStatus(status msg.Status) error {
+	if writer.hasStatus() {
+		return msg.ErrStatusAlreadyWritten
+	}
+
+	writer.status = status
+	return nil
+}
+
+func (writer *responseWriter) Header() *msg.Header {
+	return &writer.header
+}
+
+func (writer *responseWriter) AddHeader(name string, value string) error {
+	if writer.headerEnded {
+		return msg.ErrHeaderEnded
+	}
+
+	writer.header.Add(name, value)
+	return nil
+}
+
+func (writer *responseWriter) EndHeader() error {
+	if !writer.hasStatus() {
+		return msg.ErrMissingStatus
+	} else if writer.headerEnded {
+		return msg.ErrHeaderEnded
+	}
+
+	writer.headerEnded = true
+	return nil
+}
+
+func (writer *responseWriter) Write(body []byte) (int, error) {
+	if writer.messageEnded {
+		return 0, msg.ErrMessageEnded
+	} else if !writer.isCommitted() {
+		if err := writer.commit(writer.frameBodyOfUnknownLength); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := writer.body.Write(body)
+	writer.numBodyBytes += int64(n)
+	return n, err
+}
+
+func (writer *responseWriter) AddTrailer(name string, value string) error {
+	if writer.messageEnded {
+		return msg.ErrMessageEnded
+	}
+
+	writer.trailer.Add(name, value)
+	return nil
+}
+
+func (writer *responseWriter) BodyBytesWritten() int64 {
+	return writer.numBodyBytes
+}
+
+func (writer *responseWriter) EndMessage() error {
+	if writer.messageEnded {
+		return nil
+	} else if !writer.isCommitted() {
+		if err := writer.commit(writer.frameEmptyBody); err != nil {
+			return err
+		}
+	}
+
+	writer.messageEnded = true
+	if writer.chunks == nil {
+		return nil
+	}
+
+	for _, field := range writer.trailer.Fields() {
+		writer.chunks.AddTrailer(field.Name, field.Value)
+	}
+
+	return writer.chunks.Close()
+}
+
+func (writer *responseWriter) commit(frameBody func()) error {
+	if !writer.hasStatus() {
+		return msg.ErrMissingStatus
+	}
+
+	writer.headerEnded = true
+	frameBody()
+	if _, err := fmt.Fprintf(writer.client, "HTTP/1.1 %d %s\r\n", writer.status.Code, writer.status.Reason); err != nil {
+		return err
+	}
+
+	for _, field := range writer.header.Fields() {
+		if _, err := fmt.Fprintf(writer.client, "%s: %s\r\n", field.Name, field.Value); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(writer.client, "\r\n")
+	return err
+}
+
+// Frames a response to HEAD in the same way as the response to GET, even though its body is omitted
+func (writer *responseWriter) frameBodyOfUnknownLength() {
+	switch {
+	case !writer.permitsBody():
+	case writer.header.Has("Transfer-Encoding") || writer.header.Has("Content-Length"):
+	case writer.canChunk:
+		writer.header.Set("Transfer-Encoding", "chunked")
+	default:
+		writer.header.Set("Connection", "close")
+	}
+
+	writer.frameBody()
+}
+
+func (writer *responseWriter) frameEmptyBody() {
+	switch {
+	case writer.omitBody || !writer.permitsBody():
+	case writer.header.Has("Transfer-Encoding") || writer.header.Has("Content-Length"):
+	default:
+		writer.header.Set("Content-Length", "0")
+	}
+
+	writer.frameBody()
+}
+
+func (writer *responseWriter) frameBody() {
+	switch {
+	case writer.omitBody || !writer.permitsBody():
+		writer.body = ioutil.Discard
+	case isChunked(writer.header.Values("Transfer-Encoding")):
+		writer.chunks = msg.NewChunkedWriter(writer.client)
+		writer.body = writer.chunks
+	default:
+		writer.body = writer.client
+	}
+}
+
+// Discards the status, header fields, and trailer fields so that a different response can be written, returning false
+// instead if the header has already been sent
+func (writer *responseWriter) discardHeader() bool {
+	if writer.isCommitted() {
+		return false
+	}
+
+	writer.status = msg.Status{}
+	writer.header = msg.Header{}
+	writer.trailer = msg.Header{}
+	writer.headerEnded = false
+	return true
+}
+
+func (writer *responseWriter) hasStatus() bool {
+	return writer.status.Code != 0
+}
+
+func (writer *responseWriter) isCommitted() bool {
+	return writer.body != nil
+}
+
+// 1xx, 204 No Content, and 304 Not Modified responses never have a body
+func (writer *responseWriter) permitsBody() bool {
+	code := writer.status.Code
+	return code >= 200 && code != 204 && code != 304
+}
+
+// Whether the last transfer coding is chunked.  See RFC 7230, Section 3.3.1.
+func isChunked(transferEncodings []string) bool {
+	if len(transferEncodings) == 0 {
+		return false
+	}
+
+	codings := strings.Split(transferEncodings[len(transferEncodings)-1], ",")
+	return strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked")
+}
+
+// Whether the response tells the client that the connection will close after it
+func closesConnection(response msg.ResponseWriter) bool {
+	for _, value := range response.Header().Values("Connection") {
+		for _, option := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(option), "close") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
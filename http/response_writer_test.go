@@ -0,0 +1,133 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/success"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("responseWriter", func() {
+	var (
+		writer msg.ResponseWriter
+		client *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		client = &bytes.Buffer{}
+		writer = http.NewResponseWriter(client)
+	})
+
+	Describe("#Header", func() {
+		It("can be changed until the header is committed", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			msg.WriteHeader(writer, "X-Original", "value")
+			writer.Header().Set("X-Original", "changed")
+			writer.EndMessage()
+
+			httptest.ParseResponse(client).HeaderShould("X-Original", Equal("changed"))
+		})
+	})
+
+	Describe("ordering", func() {
+		It("returns an error for a second status", func() {
+			Expect(writer.WriteStatus(success.OKStatus)).To(Succeed())
+			Expect(writer.WriteStatus(success.OKStatus)).To(MatchError(msg.ErrStatusAlreadyWritten))
+		})
+
+		It("returns an error when ending the header before writing a status", func() {
+			Expect(writer.EndHeader()).To(MatchError(msg.ErrMissingStatus))
+		})
+
+		It("returns an error when writing the body before writing a status", func() {
+			_, err := writer.Write([]byte("body"))
+			Expect(err).To(MatchError(msg.ErrMissingStatus))
+			Expect(client.String()).To(BeEmpty())
+		})
+
+		It("returns an error when adding a header field after the header ends", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			writer.EndHeader()
+			Expect(writer.AddHeader("X-Late", "value")).To(MatchError(msg.ErrHeaderEnded))
+		})
+
+		It("returns an error when writing the body after the message ends", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			writer.EndMessage()
+			_, err := writer.Write([]byte("body"))
+			Expect(err).To(MatchError(msg.ErrMessageEnded))
+		})
+	})
+
+	Describe("#Write", func() {
+		It("writes the status and header before the first part of the body", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			msg.WriteContentLengthHeader(writer, 4)
+			msg.WriteBody(writer, "body")
+			Expect(client.String()).To(Equal("HTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\nbody"))
+		})
+
+		It("chunks a body of unknown length", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			msg.WriteBody(writer, "body")
+			writer.AddTrailer("X-Checksum", "42")
+			writer.EndMessage()
+			Expect(client.String()).To(Equal(
+				"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n4\r\nbody\r\n0\r\nX-Checksum: 42\r\n\r\n"))
+		})
+
+		It("counts the bytes written to the body", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			msg.WriteBody(writer, "body")
+			Expect(writer.BodyBytesWritten()).To(BeEquivalentTo(4))
+		})
+	})
+
+	Describe("#EndMessage", func() {
+		It("sets Content-Length to 0 for a response with a header and no body", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			writer.EndMessage()
+			Expect(client.String()).To(Equal("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+		})
+
+		It("does not set Content-Length for 204 No Content", func() {
+			msg.WriteStatus(writer, msg.Status{Code: 204, Reason: "No Content"})
+			writer.EndMessage()
+			Expect(client.String()).To(Equal("HTTP/1.1 204 No Content\r\n\r\n"))
+		})
+
+		It("does nothing more when called again", func() {
+			msg.WriteStatus(writer, success.OKStatus)
+			writer.EndMessage()
+			writer.EndMessage()
+			Expect(client.String()).To(Equal("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+		})
+	})
+
+	Context("when handling a request from an HTTP/1.0 client", func() {
+		It("closes the connection after a body of unknown length, instead of chunking it", func() {
+			handler := http.NewConnectionHandler(&StreamingRouter{Body: "streamed"})
+			handler.Handle(context.Background(), makeReader("GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"), client)
+			Expect(client.String()).To(Equal("HTTP/1.1 200 OK\r\nConnection: close\r\n\r\nstreamed"))
+		})
+	})
+
+	Context("when handling a HEAD request", func() {
+		It("frames the response in the same way as GET, without the body", func() {
+			handler := http.NewConnectionHandler(&StreamingRouter{Body: "streamed"})
+			handler.Handle(context.Background(), makeReader("HEAD / HTTP/1.1\r\nHost: localhost\r\n\r\n"), client)
+			Expect(client.String()).To(Equal("HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"))
+		})
+
+		It("closes the connection for an HTTP/1.0 client, as it would after the body for GET", func() {
+			handler := http.NewConnectionHandler(&StreamingRouter{Body: "streamed"})
+			handler.Handle(context.Background(), makeReader("HEAD / HTTP/1.0\r\n\r\n"), client)
+			Expect(client.String()).To(Equal("HTTP/1.1 200 OK\r\nConnection: close\r\n\r\n"))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"fmt"
+	"sort"
+)
+
+func NewRouteTable() *RouteTable {
+	return &RouteTable{}
+}
+
+// A Route that picks another Route by matching the requested path against path patterns like /users/{id}.
+// When more than one pattern matches, the most specific one is tried first, and the next one is tried whenever a
+// Route returns nil.  The parameters in the matching pattern are set on the request message, before it is routed.
+//
+// A pattern can also have a handler for each method.  A request with any other method is answered with 405 Method Not
+// Allowed, and OPTIONS is answered with the methods that have handlers, unless it has a handler of its own.
+type RouteTable struct {
+	entries []routeTableEntry
+}
+
+type routeTableEntry struct {
+	pattern *PathPattern
+	route   Route
+}
+
+// Routes requests for every path that matches the pattern to the Route.  Returns an error when the pattern is not
+// valid, or when it conflicts with a pattern that is already in the table because both match exactly the same paths.
+func (table *RouteTable) Add(pattern string, route Route) error {
+	parsed, err := ParsePathPattern(pattern)
+	if err != nil {
+		return err
+	} else if conflicting := table.conflictingEntry(parsed); conflicting != nil {
+		return fmt.Errorf("RouteTable: %s conflicts with %s", pattern, conflicting.pattern)
+	}
+
+	table.addEntry(routeTableEntry{pattern: parsed, route: route})
+	return nil
+}
+
+// Routes requests with the method, for every path that matches the pattern, to the handler.  Handlers for other
+// methods may be added with the same pattern, but not with another one that matches the same paths.
+func (table *RouteTable) HandleMethod(method string, pattern string, handler MethodHandler) error {
+	return table.addMethods(pattern, map[string]MethodHandler{method: handler})
+}
+
+// Routes requests for every path that matches the pattern to the Resource, for each method it implements
+func (table *RouteTable) AddResource(pattern string, resource Resource) error {
+	return table.addMethods(pattern, resourceMethods(resource))
+}
+
+// Adds handlers to the methods for a pattern, after checking that none of them would replace another handler
+func (table *RouteTable) addMethods(pattern string, handlers map[string]MethodHandler) error {
+	parsed, err := ParsePathPattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	conflicting := table.conflictingEntry(parsed)
+	if conflicting == nil {
+		table.addEntry(routeTableEntry{pattern: parsed, route: &methodRoute{handlers: handlers}})
+		return nil
+	}
+
+	route, hasMethods := conflicting.route.(*methodRoute)
+	if !hasMethods || conflicting.pattern.Pattern != pattern {
+		return fmt.Errorf("RouteTable: %s conflicts with %s", pattern, conflicting.pattern)
+	}
+
+	methods := make([]string, 0, len(handlers))
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+
+	sort.Strings(methods)
+	for _, method := range methods {
+		if route.handlers[method] != nil {
+			return fmt.Errorf("RouteTable: %s %s already has a handler", method, pattern)
+		}
+	}
+
+	for method, handler := range handlers {
+		route.handlers[method] = handler
+	}
+
+	return nil
+}
+
+func (table *RouteTable) conflictingEntry(pattern *PathPattern) *routeTableEntry {
+	for i, entry := range table.entries {
+		if entry.pattern.conflictsWith(pattern) {
+			return &table.entries[i]
+		}
+	}
+
+	return nil
+}
+
+func (table *RouteTable) addEntry(entry routeTableEntry) {
+	table.entries = append(table.entries, entry)
+	sort.SliceStable(table.entries, func(i, j int) bool {
+		return table.entries[i].pattern.precedes(table.entries[j].pattern)
+	})
+}
+
+// The patterns in the table, in the order in which they are tried
+func (table *RouteTable) Patterns() []string {
+	patterns := make([]string, len(table.entries))
+	for i, entry := range table.entries {
+		patterns[i] = entry.pattern.Pattern
+	}
+
+	return patterns
+}
+
+func (table *RouteTable) Route(requested RequestMessage) Request {
+	for _, entry := range table.entries {
+		parameters, matches := entry.pattern.Match(requested.Path())
+		if !matches {
+			continue
+		}
+
+		setPathParameters(requested, parameters)
+		if request := entry.route.Route(requested); request != nil {
+			return request
+		}
+	}
+
+	setPathParameters(requested, nil)
+	return nil
+}
+
+func setPathParameters(requested RequestMessage, parameters map[string]string) {
+	if message, ok := requested.(pathParameterSetter); ok {
+		message.SetPathParameters(parameters)
+	}
+}
+
+type pathParameterSetter interface {
+	SetPathParameters(parameters map[string]string)
+}
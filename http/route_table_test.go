@@ -0,0 +1,287 @@
+package http_test
+
+import (
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/success"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParsePathPattern", func() {
+	It("parses static segments, parameters, and a trailing wildcard", func() {
+		pattern, err := http.ParsePathPattern("/users/{id}/files/{name...}")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pattern.Pattern).To(Equal("/users/{id}/files/{name...}"))
+	})
+
+	Context("given a pattern that is not valid", func() {
+		expectError := func(pattern string, message string) {
+			_, err := http.ParsePathPattern(pattern)
+			ExpectWithOffset(1, err).To(MatchError(message))
+		}
+
+		It("returns an error for a pattern without a leading slash", func() {
+			expectError("users", `invalid path pattern "users": must start with /`)
+		})
+
+		It("returns an error for a parameter that is only part of a segment", func() {
+			expectError("/users/id-{id}",
+				`invalid path pattern "/users/id-{id}": a parameter must be the whole segment: id-{id}`)
+		})
+
+		It("returns an error for a parameter without a name, or with a name that is not a word", func() {
+			expectError("/users/{}", `invalid path pattern "/users/{}": invalid parameter name: {}`)
+			expectError("/users/{user id}", `invalid path pattern "/users/{user id}": invalid parameter name: {user id}`)
+		})
+
+		It("returns an error for a wildcard before the last segment", func() {
+			expectError("/files/{path...}/edit",
+				`invalid path pattern "/files/{path...}/edit": {path...} must be the last segment`)
+		})
+
+		It("returns an error for a parameter that is in the pattern twice", func() {
+			expectError("/users/{id}/friends/{id}",
+				`invalid path pattern "/users/{id}/friends/{id}": duplicate parameter id`)
+		})
+	})
+})
+
+var _ = Describe("PathPattern", func() {
+	Describe("#Match", func() {
+		match := func(pattern string, path string) (map[string]string, bool) {
+			parsed, err := http.ParsePathPattern(pattern)
+			ExpectWithOffset(1, err).NotTo(HaveOccurred())
+			return parsed.Match(path)
+		}
+
+		expectMatch := func(pattern string, path string, parameters map[string]string) {
+			matched, matches := match(pattern, path)
+			ExpectWithOffset(1, matches).To(BeTrue())
+			ExpectWithOffset(1, matched).To(Equal(parameters))
+		}
+
+		It("matches a static path to itself", func() {
+			expectMatch("/about", "/about", map[string]string{})
+			expectMatch("/", "/", map[string]string{})
+		})
+
+		It("matches a parameter to one segment", func() {
+			expectMatch("/users/{id}", "/users/42", map[string]string{"id": "42"})
+		})
+
+		It("matches a wildcard to the rest of the path, even when that is empty", func() {
+			expectMatch("/users/{id}/files/{name...}", "/users/42/files/a/b.txt",
+				map[string]string{"id": "42", "name": "a/b.txt"})
+			expectMatch("/files/{name...}", "/files/", map[string]string{"name": ""})
+		})
+
+		It("does not match a path with different segments", func() {
+			_, matches := match("/about", "/contact")
+			Expect(matches).To(BeFalse())
+			_, matches = match("/about", "/about/")
+			Expect(matches).To(BeFalse())
+		})
+
+		It("does not match an empty segment to a parameter", func() {
+			_, matches := match("/users/{id}", "/users/")
+			Expect(matches).To(BeFalse())
+		})
+
+		It("does not match a path with more or fewer segments than the pattern", func() {
+			_, matches := match("/users/{id}", "/users/42/files")
+			Expect(matches).To(BeFalse())
+			_, matches = match("/files/{name...}", "/files")
+			Expect(matches).To(BeFalse())
+		})
+
+		It("does not match an asterisk-form target", func() {
+			_, matches := match("/{name...}", "*")
+			Expect(matches).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("RouteTable", func() {
+	var table *http.RouteTable
+
+	BeforeEach(func() {
+		table = http.NewRouteTable()
+	})
+
+	Describe("#Add", func() {
+		It("returns an error for a pattern that is not valid", func() {
+			err := table.Add("users", &RouteMock{})
+			Expect(err).To(MatchError(`invalid path pattern "users": must start with /`))
+		})
+
+		It("returns an error for a pattern that matches the same paths as one that is already in the table", func() {
+			Expect(table.Add("/users/{id}", &RouteMock{})).To(Succeed())
+			err := table.Add("/users/{name}", &RouteMock{})
+			Expect(err).To(MatchError("RouteTable: /users/{name} conflicts with /users/{id}"))
+		})
+
+		It("accepts patterns that overlap, when one is more specific than the other", func() {
+			Expect(table.Add("/users/{id}", &RouteMock{})).To(Succeed())
+			Expect(table.Add("/users/me", &RouteMock{})).To(Succeed())
+			Expect(table.Add("/users/{path...}", &RouteMock{})).To(Succeed())
+			Expect(table.Add("/users/{id}/{path...}", &RouteMock{})).To(Succeed())
+		})
+	})
+
+	Describe("#Patterns", func() {
+		It("lists static patterns before parameters, and parameters before wildcards", func() {
+			Expect(table.Add("/{path...}", &RouteMock{})).To(Succeed())
+			Expect(table.Add("/users/{id}", &RouteMock{})).To(Succeed())
+			Expect(table.Add("/users/me", &RouteMock{})).To(Succeed())
+			Expect(table.Add("/users/{id}/{path...}", &RouteMock{})).To(Succeed())
+			Expect(table.Patterns()).To(Equal([]string{
+				"/users/me",
+				"/users/{id}/{path...}",
+				"/users/{id}",
+				"/{path...}",
+			}))
+		})
+	})
+
+	Describe("#Route", func() {
+		var (
+			staticRoute, parameterRoute, wildcardRoute *RouteMock
+		)
+
+		BeforeEach(func() {
+			wildcardRoute = &RouteMock{RouteReturns: &httptest.RequestMock{}}
+			parameterRoute = &RouteMock{RouteReturns: &httptest.RequestMock{}}
+			staticRoute = &RouteMock{RouteReturns: &httptest.RequestMock{}}
+			Expect(table.Add("/users/{path...}", wildcardRoute)).To(Succeed())
+			Expect(table.Add("/users/{id}", parameterRoute)).To(Succeed())
+			Expect(table.Add("/users/me", staticRoute)).To(Succeed())
+		})
+
+		It("routes to a static pattern before a parameter or wildcard that also matches", func() {
+			Expect(table.Route(http.NewGetMessage("/users/me"))).To(BeIdenticalTo(staticRoute.RouteReturns))
+		})
+
+		It("routes to a parameter before a wildcard that also matches", func() {
+			Expect(table.Route(http.NewGetMessage("/users/42"))).To(BeIdenticalTo(parameterRoute.RouteReturns))
+		})
+
+		It("routes to a wildcard when nothing more specific matches", func() {
+			Expect(table.Route(http.NewGetMessage("/users/42/files"))).To(BeIdenticalTo(wildcardRoute.RouteReturns))
+		})
+
+		It("sets the parameters in the pattern on the request message", func() {
+			requested := http.NewGetMessage("/users/42")
+			table.Route(requested)
+			Expect(requested.PathParameter("id")).To(Equal("42"))
+		})
+
+		It("tries the next pattern that matches, when a Route returns nil", func() {
+			staticRoute.RouteReturns = nil
+			requested := http.NewGetMessage("/users/me")
+			Expect(table.Route(requested)).To(BeIdenticalTo(parameterRoute.RouteReturns))
+			Expect(requested.PathParameter("id")).To(Equal("me"))
+		})
+
+		It("returns nil when no pattern matches", func() {
+			Expect(table.Route(http.NewGetMessage("/groups/1"))).To(BeNil())
+		})
+	})
+
+	Describe("#AddResource", func() {
+		It("routes requests to the methods that the Resource implements", func() {
+			resource := &ResourceMock{}
+			Expect(table.AddResource("/teapots/{name}", resource)).To(Succeed())
+			request := table.Route(http.NewPatchMessage("/teapots/earl-grey"))
+			Expect(request).NotTo(BeNil())
+			Expect(request.Handle(&httptest.ResponseBuffer{})).To(Succeed())
+			resource.PatchShouldHaveBeenCalled("/teapots/earl-grey")
+			Expect(resource.patchReceivedMessage.PathParameter("name")).To(Equal("earl-grey"))
+		})
+	})
+
+	Describe("#HandleMethod", func() {
+		var (
+			response = &httptest.ResponseBuffer{}
+			received http.RequestMessage
+		)
+
+		BeforeEach(func() {
+			response.Reset()
+			received = nil
+			getUser := func(client msg.ResponseWriter, message http.RequestMessage) error {
+				received = message
+				msg.WriteStatus(client, success.OKStatus)
+				msg.WriteContentLengthHeader(client, 4)
+				msg.WriteEndOfMessageHeader(client)
+				msg.WriteBody(client, "user")
+				return nil
+			}
+			deleteUser := func(client msg.ResponseWriter, message http.RequestMessage) error {
+				msg.WriteStatus(client, success.NoContentStatus)
+				msg.WriteEndOfMessageHeader(client)
+				return nil
+			}
+
+			Expect(table.HandleMethod(http.GET, "/users/{id}", getUser)).To(Succeed())
+			Expect(table.HandleMethod(http.DELETE, "/users/{id}", deleteUser)).To(Succeed())
+		})
+
+		handle := func(requested http.RequestMessage) {
+			request := table.Route(requested)
+			ExpectWithOffset(1, request).NotTo(BeNil())
+			ExpectWithOffset(1, request.Handle(response)).To(Succeed())
+		}
+
+		It("routes a request with a method that has a handler to that handler, with the path parameters", func() {
+			handle(http.NewGetMessage("/users/42"))
+			Expect(received).NotTo(BeNil())
+			Expect(received.PathParameter("id")).To(Equal("42"))
+			httptest.ParseResponse(response).BodyShould(Equal("user"))
+		})
+
+		It("answers HEAD with the GET handler, without the body", func() {
+			handle(http.NewHeadMessage("/users/42"))
+			responseMessage := httptest.ParseResponse(response)
+			responseMessage.StatusShouldBe(200, "OK")
+			responseMessage.BodyShould(BeEmpty())
+		})
+
+		Context("when the method has no handler", func() {
+			BeforeEach(func() {
+				handle(http.NewPutMessage("/users/42"))
+			})
+
+			It("responds 405 Method Not Allowed", httptest.ShouldHaveNoBody(response, 405, "Method Not Allowed"))
+			It("sets Allow to the methods that have handlers for the pattern",
+				httptest.AllowedMethodsShouldBe(response, http.DELETE, http.GET, http.HEAD, http.OPTIONS))
+		})
+
+		Context("when the method is OPTIONS, without a handler of its own", func() {
+			BeforeEach(func() {
+				handle(http.NewOptionsMessage("/users/42"))
+			})
+
+			It("responds 200 OK with no body", httptest.ShouldHaveNoBody(response, 200, "OK"))
+			It("sets Allow to the methods that have handlers for the pattern",
+				httptest.AllowedMethodsShouldBe(response, http.DELETE, http.GET, http.HEAD, http.OPTIONS))
+		})
+
+		It("returns an error for a method that already has a handler for the pattern", func() {
+			err := table.HandleMethod(http.GET, "/users/{id}", nil)
+			Expect(err).To(MatchError("RouteTable: GET /users/{id} already has a handler"))
+		})
+
+		It("returns an error for a pattern that matches the same paths with different parameter names", func() {
+			err := table.HandleMethod(http.PUT, "/users/{name}", nil)
+			Expect(err).To(MatchError("RouteTable: /users/{name} conflicts with /users/{id}"))
+		})
+
+		It("returns an error for a pattern that matches the same paths as a Route", func() {
+			Expect(table.Add("/groups/{id}", &RouteMock{})).To(Succeed())
+			err := table.HandleMethod(http.GET, "/groups/{id}", nil)
+			Expect(err).To(MatchError("RouteTable: /groups/{id} conflicts with /groups/{id}"))
+		})
+	})
+})
@@ -2,20 +2,27 @@ package http
 
 import (
 	"bufio"
+	"context"
+
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/servererror"
 )
 
 func NewRouter() *RequestLineRouter {
-	return &RequestLineRouter{
-		Parser: &LineRequestParser{},
-		logger: noLogger{},
-	}
+	router := &RequestLineRouter{logger: noLogger{}}
+	router.route = RouteFunc(router.routeToFirstMatch)
+	return router
 }
 
-// Routes requests based solely upon the first line in the request
+// Routes requests based solely upon the first line in the request.
+// Middleware from Use is wrapped around every route, in the order it was added, outside of any middleware that was
+// wrapped around an individual Route.  Requests that no route handles are answered with 501 Not Implemented, which
+// also goes through the middleware.
 type RequestLineRouter struct {
-	Parser RequestParser
-	logger RequestLogger
-	routes []Route
+	logger     RequestLogger
+	routes     []Route
+	middleware []Middleware
+	route      Route // The routes, wrapped in the middleware
 }
 
 func (router *RequestLineRouter) AddRoute(route Route) {
@@ -32,29 +39,39 @@ func (router *RequestLineRouter) LogRequests(logger RequestLogger) {
 	router.logger = logger
 }
 
-func (router RequestLineRouter) RouteRequest(reader *bufio.Reader) (ok Request, err Response) {
-	requested, err := router.Parser.Parse(reader)
-	if err != nil {
-		return nil, err
-	}
+// Adds middleware around every route, inside of any middleware that was added before it
+func (router *RequestLineRouter) Use(middleware ...Middleware) {
+	router.middleware = append(router.middleware, middleware...)
+	router.route = Wrap(RouteFunc(router.routeToFirstMatch), router.middleware...)
+}
 
+// Routes the request through the middleware to the first matching route.  Responds Not Implemented when the
+// middleware returns no Request at all.
+func (router *RequestLineRouter) RouteRequest(requested RequestMessage) (ok Request, notImplemented Response) {
 	router.logger.Parsed(requested)
-	return router.routeRequest(requested)
+	if request := router.route.Route(requested); request != nil {
+		return request, nil
+	}
+
+	return nil, &servererror.NotImplemented{Method: requested.Method()}
 }
 
-func (router RequestLineRouter) routeRequest(requested *requestMessage) (ok Request, notImplemented Response) {
+func (router *RequestLineRouter) routeToFirstMatch(requested RequestMessage) Request {
 	for _, route := range router.routes {
 		request := route.Route(requested)
 		if request != nil {
-			return request, nil
+			return request
 		}
 	}
 
-	return nil, requested.NotImplemented()
+	return RequestFunc(func(client msg.ResponseWriter) error {
+		return (&servererror.NotImplemented{Method: requested.Method()}).WriteTo(client)
+	})
 }
 
 type RequestParser interface {
-	Parse(reader *bufio.Reader) (ok *requestMessage, err Response)
+	// Parses the next request, calling any beforeBody functions once the header has been read
+	Parse(reader *bufio.Reader, beforeBody ...func()) (ok *requestMessage, err Response)
 }
 
 type RequestLogger interface {
@@ -62,14 +79,21 @@ type RequestLogger interface {
 }
 
 type RequestMessage interface {
+	// Done when the client disconnects, the response takes too long, or the server shuts down
+	Context() context.Context
+
 	Method() string
 	Target() string
 	Version() string
 	Path() string
 	QueryParameters() []QueryParameter
 
+	// The value of a parameter in the path pattern that matched this request, like id in /users/{id}
+	PathParameter(name string) string
+
 	HeaderLines() []string
 	HeaderValues(field string) (values []string)
+	CombinedHeaderValue(field string) string
 	Body() []byte
 
 	MakeResourceRequest(resource Resource) Request
@@ -3,8 +3,6 @@ package http_test
 import (
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
-	"github.com/kkrull/gohttp/msg/servererror"
-
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -12,7 +10,7 @@ import (
 var _ = Describe("::NewRouter", func() {
 	It("configures no logging for requests, by default", func() {
 		router := http.NewRouter()
-		router.RouteRequest(makeReader("GET / HTTP/1.1\r\n\r\n"))
+		router.RouteRequest(http.NewGetMessage("/"))
 	})
 })
 
@@ -29,15 +27,19 @@ var _ = Describe("RequestLineRouter", func() {
 			router = http.NewRouter()
 			logger = &RequestLoggerMock{}
 			router.LogRequests(logger)
-			request, err = router.RouteRequest(makeReader("GET / HTTP/1.1\r\n\r\n"))
+			request, err = router.RouteRequest(http.NewGetMessage("/"))
 			logger.ParsedShouldHaveReceived(http.GET, "/")
 		})
 
 		Context("given a well-formed request not matched by any Route", func() {
-			It("returns a NotImplemented response", func() {
+			It("returns a request that responds Not Implemented", func() {
 				router = http.NewRouter()
-				request, err = router.RouteRequest(makeReader("get / HTTP/1.1\r\n\r\n"))
-				Expect(err).To(BeEquivalentTo(&servererror.NotImplemented{Method: "get"}))
+				request, err = router.RouteRequest(http.NewRequestMessage("get", "/"))
+				Expect(err).To(BeNil())
+
+				response := &httptest.ResponseBuffer{}
+				Expect(request.Handle(response)).To(Succeed())
+				httptest.ParseResponse(response).StatusShouldBe(501, "Not Implemented")
 			})
 		})
 
@@ -51,7 +53,7 @@ var _ = Describe("RequestLineRouter", func() {
 				router = http.NewRouter()
 				router.AddRoute(unrelatedRoute)
 				router.AddRoute(matchingRoute)
-				request, err = router.RouteRequest(makeReader("HEAD /foo HTTP/1.1\r\nAccept: */*\r\n\r\n"))
+				request, err = router.RouteRequest(http.NewHeadMessage("/foo"))
 			})
 
 			It("tries routing the method and path from the request until it finds a match", func() {
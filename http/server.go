@@ -2,9 +2,16 @@ package http
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // Builder for TCPServer that defaults to any available port on localhost
@@ -13,26 +20,66 @@ func TCPServerBuilder(host string) *tcpServerBuilder {
 		host:           host,
 		port:           0,
 		maxConnections: 1,
-		handler:        NewConnectionHandler(NewRouter()),
+		router:         NewRouter(),
+		limits:         DefaultRequestLimits,
+		timeouts:       DefaultTimeouts,
 	}
 }
 
 type tcpServerBuilder struct {
 	host           string
 	port           uint16
+	listener       ListenerFactory
+	handoff        *Handoff
 	maxConnections uint
 	handler        ConnectionHandler
+	router         Router
+	limits         RequestLimits
+	timeouts       Timeouts
+
+	excessConnections ExcessConnectionPolicy
+	retryAfterSeconds uint
+	onAcceptError     func(err error)
+	onHandlerError    func(err error)
+
+	tlsConfig   *tls.Config
+	tlsCertFile string
+	tlsKeyFile  string
 }
 
 func (builder *tcpServerBuilder) Build() *TCPServer {
 	return &TCPServer{
 		Host:           builder.host,
 		Port:           builder.port,
+		Listener:       builder.listener,
+		Handoff:        builder.handoff,
 		MaxConnections: builder.maxConnections,
-		Handler:        builder.handler,
+		Handler:        builder.connectionHandler(),
+
+		ExcessConnections: builder.excessConnections,
+		RetryAfterSeconds: builder.retryAfterSeconds,
+		OnAcceptError:     builder.onAcceptError,
+		OnHandlerError:    builder.onHandlerError,
+
+		TLSConfig:   builder.tlsConfig,
+		TLSCertFile: builder.tlsCertFile,
+		TLSKeyFile:  builder.tlsKeyFile,
 	}
 }
 
+func (builder *tcpServerBuilder) connectionHandler() ConnectionHandler {
+	if builder.handler != nil {
+		return builder.handler
+	}
+
+	handler := newBlockingConnectionHandler(builder.router, builder.limits, builder.timeouts)
+	if builder.onHandlerError != nil {
+		handler.OnError = builder.onHandlerError
+	}
+
+	return handler
+}
+
 func (builder *tcpServerBuilder) ListeningOnHost(host string) *tcpServerBuilder {
 	builder.host = host
 	return builder
@@ -43,6 +90,87 @@ func (builder *tcpServerBuilder) ListeningOnPort(port uint16) *tcpServerBuilder
 	return builder
 }
 
+// Uses a listening socket from the handoff, when it has one for the server's address
+func (builder *tcpServerBuilder) InheritingFrom(handoff *Handoff) *tcpServerBuilder {
+	builder.handoff = handoff
+	return builder
+}
+
+// Listens on a Unix domain socket at path instead of a TCP port, with the given file permissions
+func (builder *tcpServerBuilder) ListeningOnUnixSocket(path string, mode os.FileMode) *tcpServerBuilder {
+	return builder.ListeningWith(NewUnixSocket(path, mode))
+}
+
+// Accepts connections on whatever listener the factory opens, instead of the TCP host and port
+func (builder *tcpServerBuilder) ListeningWith(factory ListenerFactory) *tcpServerBuilder {
+	builder.listener = factory
+	return builder
+}
+
+// Calls handleError with any error accepting a connection, other than the one from the listener closing
+func (builder *tcpServerBuilder) OnAcceptError(handleError func(err error)) *tcpServerBuilder {
+	builder.onAcceptError = handleError
+	return builder
+}
+
+// Answers connections beyond MaxConnections with 503 Service Unavailable, instead of leaving them to wait
+func (builder *tcpServerBuilder) RejectingExcessConnections(retryAfterSeconds uint) *tcpServerBuilder {
+	builder.excessConnections = RejectExcessConnections
+	builder.retryAfterSeconds = retryAfterSeconds
+	return builder
+}
+
+// Calls handleError with any error or recovered panic from handling a request, instead of logging it to standard error
+func (builder *tcpServerBuilder) OnHandlerError(handleError func(err error)) *tcpServerBuilder {
+	builder.onHandlerError = handleError
+	return builder
+}
+
+// Serves HTTPS with the certificate and key in the given PEM files, which are loaded when the server starts
+func (builder *tcpServerBuilder) ServingTLS(certFile string, keyFile string) *tcpServerBuilder {
+	builder.tls()
+	builder.tlsCertFile = certFile
+	builder.tlsKeyFile = keyFile
+	return builder
+}
+
+// Serves HTTPS with a certificate that is already loaded, such as one from SelfSignedCertificate
+func (builder *tcpServerBuilder) ServingTLSWithCertificate(certificate tls.Certificate) *tcpServerBuilder {
+	config := builder.tls()
+	config.Certificates = append(config.Certificates, certificate)
+	return builder
+}
+
+// Requires clients to present a certificate that is signed by one of the given certificate authorities
+func (builder *tcpServerBuilder) VerifyingClientCertificates(clientCAs *x509.CertPool) *tcpServerBuilder {
+	config := builder.tls()
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.ClientCAs = clientCAs
+	return builder
+}
+
+// Restricts TLS 1.2 and earlier to the given cipher suites (TLS 1.3 suites are not configurable)
+func (builder *tcpServerBuilder) WithTLSCipherSuites(cipherSuites ...uint16) *tcpServerBuilder {
+	builder.tls().CipherSuites = cipherSuites
+	return builder
+}
+
+// Refuses clients that do not support at least the given version of TLS, such as tls.VersionTLS13
+func (builder *tcpServerBuilder) WithMinTLSVersion(version uint16) *tcpServerBuilder {
+	builder.tls().MinVersion = version
+	return builder
+}
+
+func (builder *tcpServerBuilder) tls() *tls.Config {
+	if builder.tlsConfig == nil {
+		builder.tlsConfig = &tls.Config{MinVersion: DefaultMinTLSVersion}
+	}
+
+	return builder.tlsConfig
+}
+
+// Handles connections with a custom ConnectionHandler, instead of the default one that is configured with the
+// builder's Router, RequestLimits, and Timeouts
 func (builder *tcpServerBuilder) WithConnectionHandler(handler ConnectionHandler) *tcpServerBuilder {
 	builder.handler = handler
 	return builder
@@ -53,15 +181,54 @@ func (builder *tcpServerBuilder) WithMaxConnections(maxConnections uint) *tcpSer
 	return builder
 }
 
+func (builder *tcpServerBuilder) WithRequestLimits(limits RequestLimits) *tcpServerBuilder {
+	builder.limits = limits
+	return builder
+}
+
+func (builder *tcpServerBuilder) WithTimeouts(timeouts Timeouts) *tcpServerBuilder {
+	builder.timeouts = timeouts
+	return builder
+}
+
+func (builder *tcpServerBuilder) WithRouter(router Router) *tcpServerBuilder {
+	builder.router = router
+	return builder
+}
+
 type TCPServer struct {
-	Host           string
-	Port           uint16
+	Host string
+	Port uint16
+
+	// Opens the listener instead of listening on Host and Port, when there is one
+	Listener ListenerFactory
+
+	// Listening sockets from a previous process, which the server uses instead of opening its own for the same address
+	Handoff *Handoff
+
 	MaxConnections uint
 	Handler        ConnectionHandler
-	listener       *net.TCPListener
+
+	ExcessConnections ExcessConnectionPolicy
+	RetryAfterSeconds uint
+	OnAcceptError     func(err error)
+	OnHandlerError    func(err error)
+
+	// Serves HTTPS instead of HTTP, when there is a TLS configuration or certificate file
+	TLSConfig   *tls.Config
+	TLSCertFile string
+	TLSKeyFile  string
+
+	mutex    sync.Mutex
+	socket   net.Listener
+	listener net.Listener
+	acceptor *acceptor
 }
 
+// The address of the listener, such as the host and port or the path to a Unix socket, or nil if it is not running
 func (server *TCPServer) Address() net.Addr {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
 	if server.listener == nil {
 		return nil
 	}
@@ -73,12 +240,41 @@ func (server *TCPServer) Routes() []Route {
 	return server.Handler.Routes()
 }
 
+// Routes new requests with the given Router, while requests that are already in progress finish with the old one.
+// This only works with the default ConnectionHandler, and not with one from WithConnectionHandler.
+func (server *TCPServer) SetRouter(router Router) error {
+	handler, ok := server.Handler.(routerSetter)
+	if !ok {
+		return fmt.Errorf("TCPServer: the ConnectionHandler does not use a Router")
+	}
+
+	handler.SetRouter(router)
+	return nil
+}
+
+type routerSetter interface {
+	SetRouter(router Router)
+}
+
 func (server *TCPServer) Start() error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
 	if err := server.startListening(); err != nil {
 		return err
 	}
 
-	go server.acceptConnections()
+	acceptor := newAcceptor(server.listener, server.Handler, server.MaxConnections)
+	acceptor.excessPolicy = server.ExcessConnections
+	acceptor.retryAfterSeconds = server.RetryAfterSeconds
+	if server.OnAcceptError != nil {
+		acceptor.onAcceptError = server.OnAcceptError
+	}
+	if server.OnHandlerError != nil {
+		acceptor.onHandlerPanic = server.OnHandlerError
+	}
+
+	server.acceptor = acceptor
+	go acceptor.acceptConnections()
 	return nil
 }
 
@@ -87,66 +283,118 @@ func (server *TCPServer) startListening() error {
 		return fmt.Errorf("TCPServer: already running")
 	}
 
-	address, addressErr := net.ResolveTCPAddr("tcp", server.hostAndPort())
-	if addressErr != nil {
-		return addressErr
+	var tlsConfig *tls.Config
+	if server.TLSConfig != nil || server.TLSCertFile != "" {
+		var tlsErr error
+		if tlsConfig, tlsErr = serverTLSConfig(server.TLSConfig, server.TLSCertFile, server.TLSKeyFile); tlsErr != nil {
+			return tlsErr
+		}
 	}
 
-	listener, listenError := net.ListenTCP("tcp", address)
+	listener, listenError := server.listen()
 	if listenError != nil {
 		return listenError
 	}
 
-	server.listener = listener
+	server.socket = listener
+	if tlsConfig != nil {
+		server.listener = tls.NewListener(listener, tlsConfig)
+	} else {
+		server.listener = listener
+	}
+
 	return nil
 }
 
-func (server TCPServer) hostAndPort() string {
-	return fmt.Sprintf("%s:%d", server.Host, server.Port)
+func (server *TCPServer) listen() (net.Listener, error) {
+	if socket, isUnixSocket := server.Listener.(*UnixSocket); isUnixSocket {
+		if inherited := server.Handoff.take(&net.UnixAddr{Name: socket.Path, Net: "unix"}); inherited != nil {
+			return inherited, nil
+		}
+	}
+
+	if server.Listener != nil {
+		return server.Listener.Listen()
+	}
+
+	address, addressErr := net.ResolveTCPAddr("tcp", server.hostAndPort())
+	if addressErr != nil {
+		return nil, addressErr
+	}
+
+	if inherited := server.Handoff.take(address); inherited != nil {
+		return inherited, nil
+	}
+
+	return net.ListenTCP("tcp", address)
 }
 
-func (server TCPServer) acceptConnections() {
-	handlerTokens := allocateConnectionHandlers(server)
+// A copy of the listening socket, to hand off to another process that takes over for this server.
+// A Unix socket file is left in place when this server stops, so that the other process can keep using it.
+func (server *TCPServer) ListenerFiles() ([]*os.File, error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.socket == nil {
+		return nil, fmt.Errorf("TCPServer: not running")
+	}
 
-	for {
-		select {
-		case bandwidthToHandleConnection := <-handlerTokens:
-			conn, listenerClosed := server.listener.AcceptTCP()
-			if listenerClosed != nil {
-				continue
-			}
+	if unixListener, isUnix := server.socket.(*net.UnixListener); isUnix {
+		unixListener.SetUnlinkOnClose(false)
+	}
 
-			go func(t uint) {
-				server.Handler.Handle(bufio.NewReader(conn), conn)
-				_ = conn.Close()
-				handlerTokens <- t
-			}(bandwidthToHandleConnection)
-		default:
-		}
+	filer, ok := server.socket.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("TCPServer: can not copy a listener of type %T", server.socket)
 	}
+
+	file, err := filer.File()
+	if err != nil {
+		return nil, err
+	}
+
+	return []*os.File{file}, nil
 }
 
-func allocateConnectionHandlers(server TCPServer) chan uint {
-	workerTokens := make(chan uint, server.MaxConnections)
-	for i := uint(1); i <= server.MaxConnections; i++ {
-		workerTokens <- i
+func (server *TCPServer) hostAndPort() string {
+	return net.JoinHostPort(server.Host, strconv.Itoa(int(server.Port)))
+}
+
+// Stops accepting connections, then lets each connection finish the request it is working on for up to timeout before
+// closing it forcibly.  Idle connections are closed right away.  Returns how many connections were forcibly closed.
+func (server *TCPServer) Drain(timeout time.Duration) (numForciblyClosed int, err error) {
+	acceptor, err := server.stopListening()
+	if acceptor == nil {
+		return 0, err
 	}
 
-	return workerTokens
+	return acceptor.drain(timeout), err
 }
 
+// Stops accepting connections and closes every connection right away, even those in the middle of a request.
+// It also ends any Drain that is in progress.
 func (server *TCPServer) Shutdown() error {
-	if server.listener == nil {
-		return nil
+	acceptor, err := server.stopListening()
+	if acceptor != nil {
+		acceptor.closeConnections()
 	}
 
-	defer func() {
+	return err
+}
+
+func (server *TCPServer) stopListening() (acceptor *acceptor, err error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.listener != nil {
+		err = server.listener.Close()
 		server.listener = nil
-	}()
-	return server.listener.Close()
+		server.socket = nil
+	}
+
+	return server.acceptor, err
 }
 
 type ConnectionHandler interface {
-	Handle(request *bufio.Reader, response io.Writer)
+	// Handles requests on a connection until it closes, or until ctx is done
+	Handle(ctx context.Context, request *bufio.Reader, response io.Writer)
 	Routes() []Route
 }
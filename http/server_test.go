@@ -2,8 +2,16 @@ package http_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/kkrull/gohttp/http"
 	. "github.com/onsi/ginkgo"
@@ -114,6 +122,18 @@ var _ = Describe("TCPServer", func() {
 			})
 		})
 
+		Context("given an IPv6 address", func() {
+			It("accepts connections on that address", func(done Done) {
+				server = http.TCPServerBuilder("::1").Build()
+				Expect(server.Start()).To(Succeed())
+
+				conn, err = net.Dial("tcp", server.Address().String())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(server.Address().(*net.TCPAddr).IP.String()).To(Equal("::1"))
+				close(done)
+			})
+		})
+
 		Context("given no port number", func() {
 			BeforeEach(func(done Done) {
 				server = http.TCPServerBuilder("localhost").Build()
@@ -139,7 +159,7 @@ var _ = Describe("TCPServer", func() {
 				for i := 0; i < 2; i++ {
 					conn, err = net.Dial("tcp", server.Address().String())
 					Expect(err).NotTo(HaveOccurred())
-					writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+					writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
 					expectHttpResponse(conn)
 				}
 
@@ -148,6 +168,99 @@ var _ = Describe("TCPServer", func() {
 		})
 	})
 
+	Describe("listening on a Unix socket", func() {
+		var (
+			directory  string
+			socketPath string
+		)
+
+		BeforeEach(func() {
+			directory, err = ioutil.TempDir("", "TCPServer-socket")
+			Expect(err).NotTo(HaveOccurred())
+			socketPath = path.Join(directory, "gohttp.sock")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(directory)).To(Succeed())
+		})
+
+		Context("when the server is running", func() {
+			BeforeEach(func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					ListeningOnUnixSocket(socketPath, 0600).
+					Build()
+				Expect(server.Start()).To(Succeed())
+				close(done)
+			})
+
+			It("reports the path to the socket as its address", func() {
+				Expect(server.Address().Network()).To(Equal("unix"))
+				Expect(server.Address().String()).To(Equal(socketPath))
+			})
+
+			It("responds to HTTP requests on the socket", func(done Done) {
+				conn, err = net.Dial("unix", socketPath)
+				Expect(err).NotTo(HaveOccurred())
+				writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+				expectHttpResponse(conn)
+				close(done)
+			})
+
+			It("gives the socket file the requested permissions", func() {
+				info, statErr := os.Stat(socketPath)
+				Expect(statErr).NotTo(HaveOccurred())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			})
+
+			It("removes the socket file when it shuts down", func() {
+				Expect(server.Shutdown()).To(Succeed())
+				_, statErr := os.Stat(socketPath)
+				Expect(os.IsNotExist(statErr)).To(BeTrue())
+			})
+
+			It("another server can not start on the same socket", func() {
+				other := http.TCPServerBuilder("localhost").
+					ListeningOnUnixSocket(socketPath, 0600).
+					Build()
+				Expect(other.Start()).To(MatchError(HaveSuffix("is already in use")))
+			})
+		})
+
+		Context("when a stale socket file is left over from a server that is no longer running", func() {
+			BeforeEach(func() {
+				stale, listenErr := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+				Expect(listenErr).NotTo(HaveOccurred())
+				stale.SetUnlinkOnClose(false)
+				Expect(stale.Close()).To(Succeed())
+			})
+
+			It("replaces the stale socket", func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					ListeningOnUnixSocket(socketPath, 0600).
+					Build()
+				Expect(server.Start()).To(Succeed())
+
+				conn, err = net.Dial("unix", socketPath)
+				Expect(err).NotTo(HaveOccurred())
+				close(done)
+			})
+		})
+
+		Context("when there is some other kind of file at the path", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(socketPath, []byte("precious"), 0600)).To(Succeed())
+			})
+
+			It("returns an error and leaves the file alone", func() {
+				server = http.TCPServerBuilder("localhost").
+					ListeningOnUnixSocket(socketPath, 0600).
+					Build()
+				Expect(server.Start()).To(MatchError(HaveSuffix("already exists and is not a socket")))
+				Expect(ioutil.ReadFile(socketPath)).To(Equal([]byte("precious")))
+			})
+		})
+	})
+
 	Describe("#Shutdown", func() {
 		Context("when the server has not been started", func() {
 			It("returns no error", func() {
@@ -184,6 +297,126 @@ var _ = Describe("TCPServer", func() {
 		})
 	})
 
+	Describe("#Drain", func() {
+		Context("when the server has not been started", func() {
+			It("returns no error and closes no connections", func() {
+				server = http.TCPServerBuilder("localhost").Build()
+				Expect(server.Drain(time.Second)).To(Equal(0))
+			})
+		})
+
+		Context("when the server is running", func() {
+			BeforeEach(func(done Done) {
+				server = http.TCPServerBuilder("localhost").Build()
+				Expect(server.Start()).To(Succeed())
+				close(done)
+			})
+
+			It("stops accepting connections", func(done Done) {
+				oldAddress := server.Address().String()
+				Expect(server.Drain(time.Second)).To(Equal(0))
+				conn, err = net.Dial("tcp", oldAddress)
+				Expect(err).To(HaveOccurred())
+				close(done)
+			})
+
+			It("closes idle persistent connections right away", func(done Done) {
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				Expect(conn.Read(make([]byte, 1024))).To(BeNumerically(">", 0))
+				waitForHandler()
+
+				Expect(server.Drain(time.Minute)).To(Equal(0))
+				Expect(readString(conn)).To(BeEmpty())
+				close(done)
+			}, 2)
+
+			It("lets a request in progress finish, then closes its connection", func(done Done) {
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n")
+				waitForHandler()
+
+				numForciblyClosed := make(chan int, 1)
+				go func() {
+					n, _ := server.Drain(time.Minute)
+					numForciblyClosed <- n
+				}()
+
+				waitForHandler()
+				writeString(conn, "\r\n")
+				response, _ := readString(conn)
+				Expect(response).To(MatchRegexp("^HTTP/1[.]1 \\d{3} "))
+				Expect(response).To(ContainSubstring("Connection: close\r\n"))
+				Eventually(numForciblyClosed).Should(Receive(Equal(0)))
+				close(done)
+			}, 2)
+
+			It("forcibly closes connections that are still busy after the timeout, and counts them", func(done Done) {
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n")
+				waitForHandler()
+
+				Expect(server.Drain(50 * time.Millisecond)).To(Equal(1))
+				Expect(readString(conn)).To(BeEmpty())
+				close(done)
+			}, 2)
+
+			It("ends early when the server is shut down", func(done Done) {
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n")
+				waitForHandler()
+
+				numForciblyClosed := make(chan int, 1)
+				go func() {
+					n, _ := server.Drain(time.Minute)
+					numForciblyClosed <- n
+				}()
+
+				waitForHandler()
+				Expect(server.Shutdown()).To(Succeed())
+				Eventually(numForciblyClosed).Should(Receive(Equal(1)))
+				close(done)
+			}, 2)
+		})
+
+		Context("when a handler ignores its context and never returns", func() {
+			var router *BlockingRouter
+
+			BeforeEach(func(done Done) {
+				router = &BlockingRouter{Release: make(chan struct{})}
+				server = http.TCPServerBuilder("localhost").WithRouter(router).Build()
+				Expect(server.Start()).To(Succeed())
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				waitForHandler()
+				close(done)
+			})
+
+			AfterEach(func() {
+				close(router.Release)
+			})
+
+			It("returns after the timeout without waiting for the handler, once it has closed the connection", func(done Done) {
+				Expect(server.Drain(50 * time.Millisecond)).To(Equal(1))
+				Expect(readString(conn)).To(BeEmpty())
+				close(done)
+			}, 2)
+
+			It("returns when the server is shut down, without waiting for the handler", func(done Done) {
+				numForciblyClosed := make(chan int, 1)
+				go func() {
+					n, _ := server.Drain(time.Minute)
+					numForciblyClosed <- n
+				}()
+
+				waitForHandler()
+				Expect(server.Shutdown()).To(Succeed())
+				Eventually(numForciblyClosed).Should(Receive(Equal(1)))
+				close(done)
+			}, 2)
+		})
+	})
+
 	Describe("when running", func() {
 		Context("when it receives a request", func() {
 			var handler *HandlerMock
@@ -208,6 +441,376 @@ var _ = Describe("TCPServer", func() {
 			})
 		})
 
+		Context("when a request is in progress", func() {
+			var router *ContextRouter
+
+			BeforeEach(func(done Done) {
+				router = &ContextRouter{Contexts: make(chan context.Context, 1), WaitForDone: true}
+				server = http.TCPServerBuilder("localhost").WithRouter(router).Build()
+				Expect(server.Start()).To(Succeed())
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				waitForHandler()
+				close(done)
+			})
+
+			It("cancels the request's context when the client resets the connection", func(done Done) {
+				Expect(conn.(*net.TCPConn).SetLinger(0)).To(Succeed())
+				Expect(conn.Close()).To(Succeed())
+				conn = nil
+				Eventually(router.Contexts).Should(Receive())
+				close(done)
+			}, 2)
+
+			It("cancels the request's context when the server shuts down", func(done Done) {
+				Expect(server.Shutdown()).To(Succeed())
+				Eventually(router.Contexts).Should(Receive())
+				close(done)
+			}, 2)
+		})
+
+		Context("when the client closes its side of the connection after sending a request", func() {
+			const bodySize = 5000000
+
+			BeforeEach(func(done Done) {
+				server = http.TCPServerBuilder("localhost").WithRouter(&LargeBodyRouter{Size: bodySize}).Build()
+				Expect(server.Start()).To(Succeed())
+				conn = dial(server)
+				close(done)
+			})
+
+			It("still sends the whole response", func(done Done) {
+				writeString(conn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+				Expect(conn.(*net.TCPConn).CloseWrite()).To(Succeed())
+				waitForHandler()
+
+				response, err := readString(conn)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response).To(HavePrefix("HTTP/1.1 200 OK\r\n"))
+				_, body := splitResponse(response)
+				Expect(body).To(HaveLen(bodySize))
+				close(done)
+			}, 5)
+		})
+
+		Context("when its Router is replaced", func() {
+			BeforeEach(func(done Done) {
+				server = http.TCPServerBuilder("localhost").WithRouter(&StreamingRouter{Body: "old"}).Build()
+				Expect(server.Start()).To(Succeed())
+				Expect(server.SetRouter(&StreamingRouter{Body: "new"})).To(Succeed())
+				close(done)
+			})
+
+			It("routes new requests with the new Router", func(done Done) {
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.0\r\n\r\n")
+				response, _ := readString(conn)
+				Expect(response).To(HaveSuffix("\r\n\r\nnew"))
+				close(done)
+			}, 2)
+		})
+
+		Context("when it has a custom ConnectionHandler", func() {
+			It("returns an error from replacing its Router", func() {
+				server = http.TCPServerBuilder("localhost").WithConnectionHandler(&HandlerMock{}).Build()
+				Expect(server.SetRouter(http.NewRouter())).To(MatchError("TCPServer: the ConnectionHandler does not use a Router"))
+			})
+		})
+
+		Context("when handling a request fails", func() {
+			var (
+				router       *FailingRouter
+				handlerError chan error
+			)
+
+			BeforeEach(func(done Done) {
+				router = &FailingRouter{}
+				handlerError = make(chan error, 2)
+				server = http.TCPServerBuilder("localhost").
+					WithRouter(router).
+					OnHandlerError(func(err error) { handlerError <- err }).
+					Build()
+				Expect(server.Start()).To(Succeed())
+				close(done)
+			})
+
+			It("responds 500 Internal Server Error to a panic, and closes the connection", func(done Done) {
+				router.Panic = "boom"
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				response, _ := readString(conn)
+				Expect(response).To(HavePrefix("HTTP/1.1 500 Internal Server Error\r\n"))
+				Expect(response).To(ContainSubstring("Connection: close\r\n"))
+				close(done)
+			}, 2)
+
+			It("passes a recovered panic and its stack trace to OnHandlerError", func(done Done) {
+				router.Panic = "boom"
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				readString(conn)
+
+				var err error
+				Eventually(handlerError).Should(Receive(&err))
+				Expect(err).To(BeAssignableToTypeOf(&http.PanicError{}))
+				Expect(err.(*http.PanicError).Value).To(Equal("boom"))
+				Expect(string(err.(*http.PanicError).Stack)).To(ContainSubstring("goroutine"))
+				close(done)
+			}, 2)
+
+			It("frees the connection handler for another connection, after a panic", func(done Done) {
+				router.Panic = "boom"
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				readString(conn)
+
+				nextConn := dial(server)
+				defer nextConn.Close()
+				writeString(nextConn, "GET / HTTP/1.1\r\n\r\n")
+				expectHttpResponse(nextConn)
+				close(done)
+			}, 2)
+
+			It("responds 500 Internal Server Error to an error returned before the body is written", func(done Done) {
+				router.WritesStatus = true
+				router.Err = fmt.Errorf("bang")
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				response, _ := readString(conn)
+				Expect(response).To(HavePrefix("HTTP/1.1 500 Internal Server Error\r\n"))
+				Eventually(handlerError).Should(Receive(MatchError("bang")))
+				close(done)
+			}, 2)
+
+			It("closes the connection after an error returned once the body has been written", func(done Done) {
+				router.WritesStatus = true
+				router.WritesBody = "partial"
+				router.Err = fmt.Errorf("bang")
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				response, _ := readString(conn)
+				Expect(response).To(HavePrefix("HTTP/1.1 200 OK\r\n"))
+				Expect(response).To(HaveSuffix("partial\r\n"))
+				close(done)
+			}, 2)
+		})
+
+		Context("when configured to serve TLS", func() {
+			var (
+				certificate tls.Certificate
+				trusted     *x509.CertPool
+				tlsConn     *tls.Conn
+			)
+
+			BeforeEach(func() {
+				certificate, trusted = selfSignedCertificate()
+			})
+
+			AfterEach(func() {
+				if tlsConn != nil {
+					tlsConn.Close()
+					tlsConn = nil
+				}
+			})
+
+			It("responds to HTTPS requests", func(done Done) {
+				server = http.TCPServerBuilder("localhost").ServingTLSWithCertificate(certificate).Build()
+				Expect(server.Start()).To(Succeed())
+
+				tlsConn, err = tls.Dial("tcp", server.Address().String(), &tls.Config{RootCAs: trusted, ServerName: "localhost"})
+				Expect(err).NotTo(HaveOccurred())
+				writeString(tlsConn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+				expectHttpResponse(tlsConn)
+				close(done)
+			}, 2)
+
+			It("loads the certificate and key from files, when it starts", func(done Done) {
+				directory, _ := ioutil.TempDir("", "TCPServer-TLS")
+				defer os.RemoveAll(directory)
+				certFile, keyFile := path.Join(directory, "cert.pem"), path.Join(directory, "key.pem")
+				Expect(http.WriteSelfSignedCertificate(certFile, keyFile, "localhost")).To(Succeed())
+
+				server = http.TCPServerBuilder("localhost").ServingTLS(certFile, keyFile).Build()
+				Expect(server.Start()).To(Succeed())
+				tlsConn, err = tls.Dial("tcp", server.Address().String(), &tls.Config{InsecureSkipVerify: true})
+				Expect(err).NotTo(HaveOccurred())
+				close(done)
+			}, 2)
+
+			It("returns an error from starting, when the certificate files can not be loaded", func() {
+				server = http.TCPServerBuilder("localhost").ServingTLS("/missing/cert.pem", "/missing/key.pem").Build()
+				Expect(server.Start()).NotTo(Succeed())
+				Expect(server.Address()).To(BeNil())
+			})
+
+			It("refuses clients that do not support the minimum version of TLS", func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					ServingTLSWithCertificate(certificate).
+					WithMinTLSVersion(tls.VersionTLS13).
+					Build()
+				Expect(server.Start()).To(Succeed())
+
+				tlsConn, err = tls.Dial("tcp", server.Address().String(), &tls.Config{
+					RootCAs:    trusted,
+					ServerName: "localhost",
+					MaxVersion: tls.VersionTLS12,
+				})
+				Expect(err).To(HaveOccurred())
+				close(done)
+			}, 2)
+
+			Context("when verifying client certificates", func() {
+				var clientCertificate tls.Certificate
+
+				BeforeEach(func(done Done) {
+					var clientCAs *x509.CertPool
+					clientCertificate, clientCAs = selfSignedCertificate()
+					server = http.TCPServerBuilder("localhost").
+						ServingTLSWithCertificate(certificate).
+						VerifyingClientCertificates(clientCAs).
+						Build()
+					Expect(server.Start()).To(Succeed())
+					close(done)
+				})
+
+				It("responds to clients with a trusted certificate", func(done Done) {
+					tlsConn, err = tls.Dial("tcp", server.Address().String(), &tls.Config{
+						RootCAs:      trusted,
+						ServerName:   "localhost",
+						Certificates: []tls.Certificate{clientCertificate},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					writeString(tlsConn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+					expectHttpResponse(tlsConn)
+					close(done)
+				}, 2)
+
+				It("refuses clients without a certificate", func(done Done) {
+					tlsConn, err = tls.Dial("tcp", server.Address().String(), &tls.Config{
+						RootCAs:    trusted,
+						ServerName: "localhost",
+					})
+					if err == nil {
+						tlsConn.Write([]byte("GET / HTTP/1.1\r\nConnection: close\r\n\r\n"))
+						_, err = ioutil.ReadAll(tlsConn)
+					}
+
+					Expect(err).To(HaveOccurred())
+					close(done)
+				}, 2)
+			})
+		})
+
+		Context("when configured with RequestLimits", func() {
+			BeforeEach(func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					WithRequestLimits(http.RequestLimits{MaxHeaderFields: 1}).
+					Build()
+				Expect(server.Start()).To(Succeed())
+				close(done)
+			})
+
+			It("responds 431 Request Header Fields Too Large to a request exceeding them", func(done Done) {
+				conn = dial(server)
+				writeString(conn, "GET / HTTP/1.1\r\nAccept: */*\r\nConnection: close\r\n\r\n")
+				Expect(readString(conn)).To(HavePrefix("HTTP/1.1 431 Request Header Fields Too Large\r\n"))
+				close(done)
+			})
+		})
+
+		Context("when configured with Timeouts", func() {
+			BeforeEach(func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					WithTimeouts(http.Timeouts{
+						ReadHeader: 100 * time.Millisecond,
+						ReadBody:   100 * time.Millisecond,
+						Idle:       100 * time.Millisecond,
+					}).
+					Build()
+				Expect(server.Start()).To(Succeed())
+				conn = dial(server)
+				close(done)
+			})
+
+			It("responds 408 Request Timeout to a client that does not finish the header in time", func(done Done) {
+				writeString(conn, "GET / HTTP/1.1\r\n")
+				Expect(readString(conn)).To(HavePrefix("HTTP/1.1 408 Request Timeout\r\n"))
+				close(done)
+			}, 2)
+
+			It("responds 408 Request Timeout to a client that does not finish the body in time", func(done Done) {
+				writeString(conn, "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nAB")
+				Expect(readString(conn)).To(HavePrefix("HTTP/1.1 408 Request Timeout\r\n"))
+				close(done)
+			}, 2)
+
+			It("closes a persistent connection that is idle for too long", func(done Done) {
+				writeString(conn, "GET / HTTP/1.1\r\n\r\n")
+				response, readErr := readString(conn)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(response).To(HavePrefix("HTTP/1.1 "))
+				close(done)
+			}, 2)
+		})
+
+		Context("when every connection handler is busy", func() {
+			var busyConn net.Conn
+
+			AfterEach(func() {
+				Expect(busyConn.Close()).To(Succeed())
+			})
+
+			It("waits to handle another connection until a handler is free", func(done Done) {
+				server = http.TCPServerBuilder("localhost").Build()
+				Expect(server.Start()).To(Succeed())
+				busyConn = dial(server)
+				writeString(busyConn, "GET /busy HTTP/1.1\r\n")
+
+				conn = dial(server)
+				writeString(conn, "GET /waiting HTTP/1.1\r\nConnection: close\r\n\r\n")
+				writeString(busyConn, "Connection: close\r\n\r\n")
+				expectHttpResponse(busyConn)
+				expectHttpResponse(conn)
+				close(done)
+			}, 2)
+
+			It("answers 503 Service Unavailable with Retry-After, when configured to reject excess connections", func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					RejectingExcessConnections(7).
+					Build()
+				Expect(server.Start()).To(Succeed())
+				busyConn = dial(server)
+				writeString(busyConn, "GET /busy HTTP/1.1\r\n")
+
+				conn = dial(server)
+				writeString(conn, "GET /rejected HTTP/1.1\r\n\r\n")
+				response, _ := readString(conn)
+				Expect(response).To(HavePrefix("HTTP/1.1 503 Service Unavailable\r\n"))
+				Expect(response).To(ContainSubstring("Retry-After: 7\r\n"))
+				close(done)
+			}, 3)
+
+			It("closes excess connections without a response, while too many others are being rejected", func(done Done) {
+				server = http.TCPServerBuilder("localhost").
+					RejectingExcessConnections(7).
+					Build()
+				Expect(server.Start()).To(Succeed())
+				busyConn = dial(server)
+				writeString(busyConn, "GET /busy HTTP/1.1\r\n")
+
+				lingering := make([]net.Conn, 16)
+				for i := range lingering {
+					lingering[i] = dial(server)
+					defer lingering[i].Close()
+					Expect(readString(lingering[i])).To(HavePrefix("HTTP/1.1 503 Service Unavailable\r\n"))
+				}
+
+				conn = dial(server)
+				Expect(readString(conn)).To(BeEmpty())
+				close(done)
+			}, 3)
+		})
+
 		Context("when the given concurrency limit is 2 or more", func() {
 			BeforeEach(func(done Done) {
 				server = http.TCPServerBuilder("localhost").
@@ -220,11 +823,11 @@ var _ = Describe("TCPServer", func() {
 			It("can handle multiple connections at a time", func(done Done) {
 				slowConn := dial(server)
 				fastConn := dial(server)
-				writeString(fastConn, "GET / HTTP/1.1\r\n\r\n")
+				writeString(fastConn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
 				readString(fastConn)
 				Expect(fastConn.Close()).To(Succeed())
 
-				writeString(slowConn, "GET / HTTP/1.1\r\n\r\n")
+				writeString(slowConn, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
 				readString(slowConn)
 				Expect(slowConn.Close()).To(Succeed())
 
@@ -234,6 +837,23 @@ var _ = Describe("TCPServer", func() {
 	})
 })
 
+// Makes a self-signed certificate for localhost, and a pool of certificates that trusts it
+func selfSignedCertificate() (tls.Certificate, *x509.CertPool) {
+	certificate, err := http.SelfSignedCertificate("localhost", "127.0.0.1")
+	Expect(err).NotTo(HaveOccurred())
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	Expect(err).NotTo(HaveOccurred())
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(leaf)
+	return certificate, trusted
+}
+
+// Gives the server a moment to hand a connection to a handler, or for the handler to read what was sent
+func waitForHandler() {
+	time.Sleep(50 * time.Millisecond)
+}
+
 func dial(server *http.TCPServer) net.Conn {
 	conn, err := net.Dial("tcp", server.Address().String())
 	Expect(err).NotTo(HaveOccurred())
@@ -245,6 +865,11 @@ func expectHttpResponse(conn net.Conn) {
 	Expect(readString(conn)).To(MatchRegexp(rfc7230StatusLinePattern))
 }
 
+func splitResponse(response string) (header string, body string) {
+	parts := strings.SplitN(response, "\r\n\r\n", 2)
+	return parts[0], parts[1]
+}
+
 func readString(conn net.Conn) (string, error) {
 	readBytes, err := ioutil.ReadAll(conn)
 	return string(readBytes), err
@@ -0,0 +1,69 @@
+package http
+
+import "time"
+
+// How long to wait on a client, so that a slow or stalled one does not hold on to a connection forever.
+// A timeout of 0 means there is no timeout.
+type Timeouts struct {
+	ReadHeader time.Duration // For the request-line and header, once the client starts sending a request
+	ReadBody   time.Duration // For the body, once the header has been read
+	Write      time.Duration // For each response
+	Idle       time.Duration // For the next request on a persistent connection, before closing it
+}
+
+var DefaultTimeouts = Timeouts{
+	ReadHeader: 10 * time.Second,
+	ReadBody:   30 * time.Second,
+	Write:      30 * time.Second,
+	Idle:       5 * time.Second,
+}
+
+// Sets deadlines on a connection, like net.Conn
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// Deadlines for one connection, which do nothing when the connection does not support them
+type connectionDeadlines struct {
+	conn         deadliner
+	readDeadline time.Time
+}
+
+func deadlinesFor(connection interface{}) *connectionDeadlines {
+	conn, _ := connection.(deadliner)
+	return &connectionDeadlines{conn: conn}
+}
+
+func (deadlines *connectionDeadlines) readWithin(timeout time.Duration) {
+	deadlines.readDeadline = deadlineAfter(timeout)
+	if deadlines.conn != nil {
+		_ = deadlines.conn.SetReadDeadline(deadlines.readDeadline)
+	}
+}
+
+func (deadlines *connectionDeadlines) writeWithin(timeout time.Duration) {
+	if deadlines.conn != nil {
+		_ = deadlines.conn.SetWriteDeadline(deadlineAfter(timeout))
+	}
+}
+
+// Whether the client has run out of time to finish what it was sending
+func (deadlines *connectionDeadlines) readTimedOut() bool {
+	return deadlines.conn != nil && !deadlines.readDeadline.IsZero() && !time.Now().Before(deadlines.readDeadline)
+}
+
+func deadlineAfter(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(timeout)
+}
+
+// Makes a blocked read return right away, with a timeout error
+func (deadlines *connectionDeadlines) interruptRead() {
+	if deadlines.conn != nil {
+		_ = deadlines.conn.SetReadDeadline(time.Now())
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"time"
+)
+
+// The oldest version of TLS that a server accepts, unless configured otherwise
+const DefaultMinTLSVersion = tls.VersionTLS12
+
+// Makes the TLS configuration for a server, loading the certificate and key files if there are any
+func serverTLSConfig(config *tls.Config, certFile string, keyFile string) (*tls.Config, error) {
+	serverConfig := config.Clone()
+	if serverConfig == nil {
+		serverConfig = &tls.Config{MinVersion: DefaultMinTLSVersion}
+	}
+
+	if certFile == "" && keyFile == "" {
+		return serverConfig, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	serverConfig.Certificates = append(serverConfig.Certificates, certificate)
+	return serverConfig, nil
+}
+
+// Generates a self-signed certificate for the given host names and IP addresses, which is good for one year.
+// It is meant for local development and tests, where there is no need for a certificate authority.
+func SelfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	certPEM, keyPEM, err := selfSignedCertificatePEM(hosts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// Generates a self-signed certificate like SelfSignedCertificate, and writes it and its key to PEM files
+func WriteSelfSignedCertificate(certFile string, keyFile string, hosts ...string) error {
+	certPEM, keyPEM, err := selfSignedCertificatePEM(hosts)
+	if err != nil {
+		return err
+	} else if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(keyFile, keyPEM, 0600)
+}
+
+func selfSignedCertificatePEM(hosts []string) (certPEM []byte, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"gohttp self-signed"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/kkrull/gohttp/msg/clienterror"
+)
+
+func NewVirtualHostRouter() *VirtualHostRouter {
+	return &VirtualHostRouter{hosts: make(map[string]Router)}
+}
+
+// Routes each request with the Router for the host name in its Host header, for name-based virtual hosting.
+// A name like example.com matches itself, and a wildcard like *.example.com matches any subdomain of example.com.
+// Names are matched before wildcards, and longer wildcards before shorter ones.  Host names are not case-sensitive,
+// and any port in the Host header is ignored.
+//
+// Requests for any other host, and HTTP/1.0 requests without a Host header, go to the default Router.  Without one,
+// they are answered with 404 Not Found.  HTTP/1.1 requests must have exactly one Host header, so requests without
+// one or with more than one are answered with 400 Bad Request.  See RFC 7230, Section 5.4
+// (https://tools.ietf.org/html/rfc7230#section-5.4).
+type VirtualHostRouter struct {
+	hosts         map[string]Router
+	wildcards     []wildcardHost
+	defaultRouter Router
+}
+
+// A Router for every subdomain that ends in suffix, like .example.com for *.example.com
+type wildcardHost struct {
+	suffix string
+	router Router
+}
+
+// Routes requests for the host name, or every subdomain matching a wildcard like *.example.com, to the Router.
+// Returns an error when the name is not valid, or when it already has a Router.
+func (router *VirtualHostRouter) AddHost(name string, hostRouter Router) error {
+	normalized, err := ParseHostName(name)
+	if err != nil {
+		return err
+	} else if router.hasHost(normalized) {
+		return fmt.Errorf("VirtualHostRouter: %s already has a Router", normalized)
+	}
+
+	if !strings.HasPrefix(normalized, "*.") {
+		router.hosts[normalized] = hostRouter
+		return nil
+	}
+
+	router.wildcards = append(router.wildcards, wildcardHost{suffix: normalized[1:], router: hostRouter})
+	sort.SliceStable(router.wildcards, func(i, j int) bool {
+		return len(router.wildcards[i].suffix) > len(router.wildcards[j].suffix)
+	})
+	return nil
+}
+
+func (router *VirtualHostRouter) hasHost(normalized string) bool {
+	if _, exists := router.hosts[normalized]; exists {
+		return true
+	}
+
+	for _, wildcard := range router.wildcards {
+		if "*"+wildcard.suffix == normalized {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Routes requests for any host that does not have a Router of its own to this Router
+func (router *VirtualHostRouter) SetDefault(defaultRouter Router) {
+	router.defaultRouter = defaultRouter
+}
+
+// The host names, followed by wildcards, in the order in which they are matched
+func (router *VirtualHostRouter) Hosts() []string {
+	names := make([]string, 0, len(router.hosts)+len(router.wildcards))
+	for name := range router.hosts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, wildcard := range router.wildcards {
+		names = append(names, "*"+wildcard.suffix)
+	}
+
+	return names
+}
+
+func (router *VirtualHostRouter) RouteRequest(requested RequestMessage) (ok Request, err Response) {
+	hosts := requested.HeaderValues("Host")
+	switch {
+	case len(hosts) > 1:
+		return nil, &clienterror.BadRequest{DisplayText: "2 or more Host headers"}
+	case len(hosts) == 0 && requested.Version() == VERSION_1_1:
+		return nil, &clienterror.BadRequest{DisplayText: "missing Host header"}
+	case len(hosts) == 0:
+		return router.routeToDefault(requested)
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(withoutPort(hosts[0])), ".")
+	if hostRouter, exists := router.hosts[name]; exists {
+		return hostRouter.RouteRequest(requested)
+	}
+
+	for _, wildcard := range router.wildcards {
+		if strings.HasSuffix(name, wildcard.suffix) && len(name) > len(wildcard.suffix) {
+			return wildcard.router.RouteRequest(requested)
+		}
+	}
+
+	return router.routeToDefault(requested)
+}
+
+func (router *VirtualHostRouter) routeToDefault(requested RequestMessage) (ok Request, err Response) {
+	if router.defaultRouter == nil {
+		return clienterror.NotFound(requested.Path()), nil
+	}
+
+	return router.defaultRouter.RouteRequest(requested)
+}
+
+// The routes of the default Router, since the routes for other hosts depend upon the request
+func (router *VirtualHostRouter) Routes() []Route {
+	if router.defaultRouter == nil {
+		return nil
+	}
+
+	return router.defaultRouter.Routes()
+}
+
+// Parses a host name or a wildcard like *.example.com, returning it in lower case without a trailing dot or the
+// brackets around an IPv6 address.  Returns an error when the name is empty, has a misplaced wildcard, or has a port.
+func ParseHostName(name string) (string, error) {
+	normalized := strings.TrimSuffix(strings.ToLower(name), ".")
+	if strings.HasPrefix(normalized, "[") && strings.HasSuffix(normalized, "]") {
+		normalized = normalized[1 : len(normalized)-1]
+	}
+
+	withoutWildcard := strings.TrimPrefix(normalized, "*.")
+	switch {
+	case withoutWildcard == "":
+		return "", fmt.Errorf("invalid host name %q: must not be empty", name)
+	case strings.Contains(withoutWildcard, "*"):
+		return "", fmt.Errorf("invalid host name %q: a wildcard must be the whole first label, like *.example.com", name)
+	case strings.Contains(normalized, ":") && net.ParseIP(normalized) == nil:
+		return "", fmt.Errorf("invalid host name %q: must not have a port", name)
+	case strings.ContainsAny(withoutWildcard, "/ \t"):
+		return "", fmt.Errorf("invalid host name %q: must not have a path or whitespace", name)
+	default:
+		return normalized, nil
+	}
+}
@@ -0,0 +1,117 @@
+package http_test
+
+import (
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg/clienterror"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VirtualHostRouter", func() {
+	var (
+		router                                              *http.VirtualHostRouter
+		exampleRouter, wildcardRouter, nestedWildcardRouter *RouterMock
+		defaultRouter                                       *RouterMock
+	)
+
+	newRouterMock := func() *RouterMock {
+		return &RouterMock{ReturnsRequest: &httptest.RequestMock{}}
+	}
+
+	BeforeEach(func() {
+		exampleRouter = newRouterMock()
+		wildcardRouter = newRouterMock()
+		nestedWildcardRouter = newRouterMock()
+		defaultRouter = newRouterMock()
+
+		router = http.NewVirtualHostRouter()
+		Expect(router.AddHost("*.example.com", wildcardRouter)).To(Succeed())
+		Expect(router.AddHost("example.com", exampleRouter)).To(Succeed())
+		Expect(router.AddHost("*.api.example.com", nestedWildcardRouter)).To(Succeed())
+		router.SetDefault(defaultRouter)
+	})
+
+	Describe("#AddHost", func() {
+		It("returns an error for a name that already has a Router, in any case", func() {
+			err := router.AddHost("Example.COM", newRouterMock())
+			Expect(err).To(MatchError("VirtualHostRouter: example.com already has a Router"))
+			err = router.AddHost("*.example.com", newRouterMock())
+			Expect(err).To(MatchError("VirtualHostRouter: *.example.com already has a Router"))
+		})
+
+		It("returns an error for a name that is not valid", func() {
+			Expect(router.AddHost("", newRouterMock())).To(MatchError(`invalid host name "": must not be empty`))
+			Expect(router.AddHost("www.*.com", newRouterMock())).To(MatchError(
+				`invalid host name "www.*.com": a wildcard must be the whole first label, like *.example.com`))
+			Expect(router.AddHost("example.org:8080", newRouterMock())).To(MatchError(
+				`invalid host name "example.org:8080": must not have a port`))
+		})
+
+		It("accepts IPv6 addresses, with or without brackets", func() {
+			Expect(router.AddHost("[::1]", newRouterMock())).To(Succeed())
+			Expect(router.AddHost("::1", newRouterMock())).To(MatchError("VirtualHostRouter: ::1 already has a Router"))
+		})
+	})
+
+	Describe("#Hosts", func() {
+		It("lists names before wildcards, and longer wildcards before shorter ones", func() {
+			Expect(router.Hosts()).To(Equal([]string{"example.com", "*.api.example.com", "*.example.com"}))
+		})
+	})
+
+	Describe("#RouteRequest", func() {
+		routeHost := func(host string) http.Request {
+			requested := &httptest.RequestMessage{MethodReturns: http.GET, PathReturns: "/", VersionReturns: http.VERSION_1_1}
+			requested.AddHeader("Host", host)
+			request, err := router.RouteRequest(requested)
+			ExpectWithOffset(1, err).To(BeNil())
+			return request
+		}
+
+		It("routes a request for a host name to its Router, ignoring the case and port", func() {
+			Expect(routeHost("example.com")).To(BeIdenticalTo(exampleRouter.ReturnsRequest))
+			Expect(routeHost("EXAMPLE.com:8080")).To(BeIdenticalTo(exampleRouter.ReturnsRequest))
+			Expect(routeHost("example.com.")).To(BeIdenticalTo(exampleRouter.ReturnsRequest))
+		})
+
+		It("routes a request for a subdomain to the longest wildcard that matches it", func() {
+			Expect(routeHost("www.example.com")).To(BeIdenticalTo(wildcardRouter.ReturnsRequest))
+			Expect(routeHost("a.b.example.com")).To(BeIdenticalTo(wildcardRouter.ReturnsRequest))
+			Expect(routeHost("v1.api.example.com")).To(BeIdenticalTo(nestedWildcardRouter.ReturnsRequest))
+		})
+
+		It("routes a request for any other host to the default Router", func() {
+			Expect(routeHost("example.org")).To(BeIdenticalTo(defaultRouter.ReturnsRequest))
+			Expect(routeHost("notexample.com")).To(BeIdenticalTo(defaultRouter.ReturnsRequest))
+		})
+
+		It("routes an HTTP/1.0 request without a Host header to the default Router", func() {
+			requested := &httptest.RequestMessage{MethodReturns: http.GET, PathReturns: "/", VersionReturns: http.VERSION_1_0}
+			Expect(router.RouteRequest(requested)).To(BeIdenticalTo(defaultRouter.ReturnsRequest))
+		})
+
+		It("responds 400 Bad Request to an HTTP/1.1 request without a Host header", func() {
+			requested := &httptest.RequestMessage{MethodReturns: http.GET, PathReturns: "/", VersionReturns: http.VERSION_1_1}
+			_, err := router.RouteRequest(requested)
+			Expect(err).To(BeEquivalentTo(&clienterror.BadRequest{DisplayText: "missing Host header"}))
+		})
+
+		It("responds 400 Bad Request to a request with 2 or more Host headers", func() {
+			requested := &httptest.RequestMessage{MethodReturns: http.GET, PathReturns: "/", VersionReturns: http.VERSION_1_1}
+			requested.AddHeader("Host", "example.com")
+			requested.AddHeader("Host", "example.org")
+			_, err := router.RouteRequest(requested)
+			Expect(err).To(BeEquivalentTo(&clienterror.BadRequest{DisplayText: "2 or more Host headers"}))
+		})
+
+		It("responds 404 Not Found to a request for any other host, when there is no default Router", func() {
+			router = http.NewVirtualHostRouter()
+			Expect(router.AddHost("example.com", exampleRouter)).To(Succeed())
+
+			response := &httptest.ResponseBuffer{}
+			Expect(routeHost("example.org").Handle(response)).To(Succeed())
+			httptest.ParseResponse(response).StatusShouldBe(404, "Not Found")
+		})
+	})
+})
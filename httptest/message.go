@@ -29,9 +29,9 @@ func (message *ResponseMessage) StatusShouldBe(status int, reason string) {
 	ExpectWithOffset(1, message.Text).To(HavePrefix("HTTP/1.1 %d %s\r\n", status, reason))
 }
 
+// Matches the body, after decoding any chunked transfer coding
 func (message *ResponseMessage) BodyShould(matcher types.GomegaMatcher) {
-	_, body := message.splitMessageHeaderAndBody()
-	ExpectWithOffset(1, body).To(matcher)
+	ExpectWithOffset(1, message.body()).To(matcher)
 }
 
 func (message ResponseMessage) HeaderAsInt(name string) (int, error) {
@@ -70,15 +70,40 @@ func (message ResponseMessage) headerFields() map[string]string {
 	return headers
 }
 
+func (message ResponseMessage) body() string {
+	_, body := message.splitMessageHeaderAndBody()
+	if message.headerFields()["Transfer-Encoding"] != "chunked" {
+		return body
+	}
+
+	return decodeChunks(body)
+}
+
 func (message ResponseMessage) splitMessageHeaderAndBody() (messageHeader, messageBody string) {
 	const headerBodySeparator = "\r\n\r\n"
-	split := strings.Split(message.Text, headerBodySeparator)
+	split := strings.SplitN(message.Text, headerBodySeparator, 2)
 	return split[0], split[1]
 }
 
+func decodeChunks(chunkedBody string) string {
+	decoded := &strings.Builder{}
+	remaining := chunkedBody
+	for {
+		sizeLine := strings.SplitN(remaining, "\r\n", 2)
+		size, err := strconv.ParseInt(strings.SplitN(sizeLine[0], ";", 2)[0], 16, 64)
+		if err != nil || size == 0 || len(sizeLine) < 2 {
+			return decoded.String()
+		}
+
+		chunk := sizeLine[1][:size]
+		decoded.WriteString(chunk)
+		remaining = strings.TrimPrefix(sizeLine[1][size:], "\r\n")
+	}
+}
+
 func parseHeader(line string) (field, value string) {
 	const optionalWhitespaceCharacters = " \t"
-	fields := strings.Split(line, ":")
+	fields := strings.SplitN(line, ":", 2)
 	field = fields[0]
 	value = strings.Trim(fields[1], optionalWhitespaceCharacters)
 	return
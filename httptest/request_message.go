@@ -2,14 +2,18 @@ package httptest
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 
 	. "github.com/onsi/gomega"
 )
 
 type RequestMessage struct {
+	ContextReturns context.Context
 	MethodReturns  string
 	PathReturns    string
 	TargetReturns  string
@@ -19,10 +23,19 @@ type RequestMessage struct {
 	makeResourceRequestReceived http.Resource
 
 	queryParameters []http.QueryParameter
-	headers         []header
+	pathParameters  map[string]string
+	headers         msg.Header
 	body            []byte
 }
 
+func (message *RequestMessage) Context() context.Context {
+	if message.ContextReturns == nil {
+		return context.Background()
+	}
+
+	return message.ContextReturns
+}
+
 func (message *RequestMessage) Method() string {
 	return message.MethodReturns
 }
@@ -47,26 +60,34 @@ func (message *RequestMessage) QueryParameters() []http.QueryParameter {
 	return message.queryParameters
 }
 
+func (message *RequestMessage) PathParameter(name string) string {
+	return message.pathParameters[name]
+}
+
+func (message *RequestMessage) SetPathParameters(parameters map[string]string) {
+	message.pathParameters = parameters
+}
+
 func (message *RequestMessage) AddHeader(field string, value string) {
-	message.headers = append(message.headers, header{Name: field, Value: value})
+	message.headers.Add(field, value)
 }
 
 func (message *RequestMessage) HeaderLines() []string {
-	lines := make([]string, len(message.headers))
-	for i, header := range message.headers {
-		lines[i] = fmt.Sprintf("%s: %s", header.Name, header.Value)
+	fields := message.headers.Fields()
+	lines := make([]string, len(fields))
+	for i, field := range fields {
+		lines[i] = fmt.Sprintf("%s: %s", field.Name, field.Value)
 	}
 
 	return lines
 }
 
 func (message *RequestMessage) HeaderValues(field string) (values []string) {
-	values = make([]string, 0)
-	for _, header := range message.headers {
-		values = append(values, header.Value)
-	}
+	return message.headers.Values(field)
+}
 
-	return values
+func (message *RequestMessage) CombinedHeaderValue(field string) string {
+	return strings.Join(message.headers.Values(field), ", ")
 }
 
 func (message *RequestMessage) Body() []byte {
@@ -86,7 +107,3 @@ func (message *RequestMessage) MakeResourceRequest(resource http.Resource) http.
 func (message *RequestMessage) MakeResourceRequestShouldHaveReceived(resource http.Resource) {
 	ExpectWithOffset(1, message.makeResourceRequestReceived).To(BeIdenticalTo(resource))
 }
-
-type header struct {
-	Name, Value string
-}
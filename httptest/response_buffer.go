@@ -0,0 +1,76 @@
+package httptest
+
+import (
+	"bytes"
+
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
+)
+
+// A msg.ResponseWriter that writes a response to memory, for inspection with ParseResponse.
+// The zero value is ready to use, and writes a response to an HTTP/1.1 GET request.  With Requested, it frames the
+// response for that request instead, like the server does.
+type ResponseBuffer struct {
+	Requested http.RequestMessage
+
+	text   bytes.Buffer
+	writer msg.ResponseWriter
+}
+
+func (buffer *ResponseBuffer) Status() msg.Status {
+	return buffer.response().Status()
+}
+
+func (buffer *ResponseBuffer) WriteStatus(status msg.Status) error {
+	return buffer.response().WriteStatus(status)
+}
+
+func (buffer *ResponseBuffer) Header() *msg.Header {
+	return buffer.response().Header()
+}
+
+func (buffer *ResponseBuffer) AddHeader(name string, value string) error {
+	return buffer.response().AddHeader(name, value)
+}
+
+func (buffer *ResponseBuffer) EndHeader() error {
+	return buffer.response().EndHeader()
+}
+
+func (buffer *ResponseBuffer) Write(body []byte) (int, error) {
+	return buffer.response().Write(body)
+}
+
+func (buffer *ResponseBuffer) AddTrailer(name string, value string) error {
+	return buffer.response().AddTrailer(name, value)
+}
+
+func (buffer *ResponseBuffer) BodyBytesWritten() int64 {
+	return buffer.response().BodyBytesWritten()
+}
+
+func (buffer *ResponseBuffer) EndMessage() error {
+	return buffer.response().EndMessage()
+}
+
+// Discards the response, so the buffer can be used for another one
+func (buffer *ResponseBuffer) Reset() {
+	buffer.text.Reset()
+	buffer.writer = nil
+}
+
+// Ends the message, as the server would after handling a request, and returns the text of the whole response
+func (buffer *ResponseBuffer) String() string {
+	buffer.response().EndMessage()
+	return buffer.text.String()
+}
+
+func (buffer *ResponseBuffer) response() msg.ResponseWriter {
+	if buffer.writer == nil && buffer.Requested != nil {
+		buffer.writer = http.NewResponseWriterFor(&buffer.text, buffer.Requested)
+	} else if buffer.writer == nil {
+		buffer.writer = http.NewResponseWriter(&buffer.text)
+	}
+
+	return buffer.writer
+}
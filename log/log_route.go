@@ -48,13 +48,14 @@ func (viewer *Viewer) Name() string {
 	return "Log viewer"
 }
 
-func (viewer *Viewer) Get(client io.Writer, message http.RequestMessage) {
+func (viewer *Viewer) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	machine := logWriterStateMachine{
 		requests: viewer.Requests,
 		client:   client,
 		viewer:   viewer,
 	}
 	machine.FindAuthorizationHeader(message)
+	return nil
 }
 
 func (viewer *Viewer) isAuthorized(encodedCredentials string) bool {
@@ -68,7 +69,7 @@ func (viewer *Viewer) isAuthorized(encodedCredentials string) bool {
 // State machine to go through the workflow of parsing and validating authorization, before writing request logs
 type logWriterStateMachine struct {
 	requests RequestBuffer
-	client   io.Writer
+	client   msg.ResponseWriter
 	viewer   *Viewer
 }
 
@@ -106,11 +107,7 @@ func (state *logWriterStateMachine) ambiguouslyAuthorized() {
 }
 
 func (state *logWriterStateMachine) authorized() {
-	msg.WriteStatus(state.client, success.OKStatus)
-	msg.WriteContentLengthHeader(state.client, state.requests.NumBytes())
-	msg.WriteContentTypeHeader(state.client, "text/plain")
-	msg.WriteEndOfMessageHeader(state.client)
-	state.requests.WriteTo(state.client)
+	success.RespondOKWithStreamedBody(state.client, "text/plain", state.requests.WriteTo)
 }
 
 func (state *logWriterStateMachine) forbidden() {
@@ -126,6 +123,5 @@ func (state *logWriterStateMachine) unauthorized() {
 
 // Writes HTTP requests
 type RequestBuffer interface {
-	NumBytes() int
 	WriteTo(client io.Writer)
 }
@@ -2,11 +2,11 @@ package log_test
 
 import (
 	"bytes"
-	"io"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/log"
+	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/clienterror"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -33,7 +33,7 @@ var _ = Describe("::NewLogRoute", func() {
 var _ = Describe("Route", func() {
 	var (
 		router   http.Route
-		response = &bytes.Buffer{}
+		response = &httptest.ResponseBuffer{}
 	)
 
 	Describe("#Route", func() {
@@ -79,8 +79,7 @@ var _ = Describe("Viewer", func() {
 
 	BeforeEach(func() {
 		logger = &RequestBufferStub{
-			NumBytesReturns: 4,
-			WriteToWill:     bytes.NewBufferString("ASDF"),
+			WriteToWill: bytes.NewBufferString("ASDF"),
 		}
 		viewer = &log.Viewer{
 			Requests:           logger,
@@ -111,8 +110,8 @@ var _ = Describe("Viewer", func() {
 				response.ShouldBeWellFormed()
 				response.StatusShouldBe(200, "OK")
 			})
-			It("responds with the contents of the configured readable buffer", func() {
-				response.HeaderShould("Content-Length", Equal("4"))
+			It("streams the contents of the configured readable buffer", func() {
+				response.HeaderShould("Transfer-Encoding", Equal("chunked"))
 				response.BodyShould(Equal("ASDF"))
 			})
 		})
@@ -195,9 +194,9 @@ var _ = Describe("Viewer", func() {
 })
 
 func invokeResourceMethod(invokeMethod httpResourceMethod, request http.RequestMessage) *httptest.ResponseMessage {
-	response := &bytes.Buffer{}
+	response := &httptest.ResponseBuffer{}
 	invokeMethod(response, request)
 	return httptest.ParseResponse(response)
 }
 
-type httpResourceMethod = func(io.Writer, http.RequestMessage)
+type httpResourceMethod = func(msg.ResponseWriter, http.RequestMessage) error
@@ -17,12 +17,7 @@ func TestLog(t *testing.T) {
 /* RequestBufferStub */
 
 type RequestBufferStub struct {
-	NumBytesReturns int
-	WriteToWill     *bytes.Buffer
-}
-
-func (stub *RequestBufferStub) NumBytes() int {
-	return stub.NumBytesReturns
+	WriteToWill *bytes.Buffer
 }
 
 func (stub *RequestBufferStub) WriteTo(client io.Writer) {
@@ -31,10 +31,6 @@ func (logger TextLogger) Parsed(message http.RequestMessage) {
 	fmt.Fprintf(logger.buffer, "%s", message.Body())
 }
 
-func (logger TextLogger) NumBytes() int {
-	return logger.buffer.Len()
-}
-
 func (logger TextLogger) WriteTo(client io.Writer) {
 	logger.buffer.WriteTo(client)
 }
@@ -5,16 +5,32 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/main/cmd"
 )
 
-const maxConnections uint = 4
+const (
+	drainTimeout   = 10 * time.Second
+	restartTimeout = 10 * time.Second
+)
 
 func main() {
+	handoff, err := http.ReceiveHandoff()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gohttp: %s\n", err.Error())
+		os.Exit(1)
+	}
+
 	factory := &cmd.InterruptFactory{
-		Interrupts:     subscribeToSignals(os.Interrupt),
-		MaxConnections: maxConnections}
+		Interrupts:   subscribeToSignals(os.Interrupt),
+		DrainTimeout: drainTimeout,
+		Handoff:      handoff,
+		Restarts:     subscribeToSignals(syscall.SIGUSR2),
+		Restarter:    &cmd.HandoffRestarter{Handoff: handoff, ReadyTimeout: restartTimeout},
+		Reloads:      subscribeToSignals(syscall.SIGHUP)}
 	gohttp := &GoHTTP{
 		CommandParser: factory.CliCommandParser(),
 		Stderr:        os.Stderr}
@@ -1,10 +1,12 @@
 package cmd_test
 
 import (
+	"errors"
 	"flag"
-	"fmt"
 	"io"
 	"net"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -40,6 +42,15 @@ func (mock *CliCommandMock) Run(stderr io.Writer) (code int, err error) {
 /* AppFactoryMock */
 
 type AppFactoryMock struct {
+	ActivatedServerFails    string
+	activatedServerReceived string
+
+	ConfiguredServerFails    string
+	configuredServerReceived string
+
+	connectionsReceived  *cmd.ConnectionOptions
+	virtualHostsReceived []cmd.VirtualHost
+
 	ErrorCommandReturns  *CliCommandMock
 	errorCommandReceived error
 
@@ -49,9 +60,46 @@ type AppFactoryMock struct {
 	RunCommandReturns        *CliCommandMock
 	RunCommandReturnsChannel chan bool
 
+	TCPServerFails        string
 	tcpServerReceivedPath string
 	tcpServerReceivedHost string
 	tcpServerReceivedPort uint16
+	tcpServerReceivedTLS  cmd.TLSOptions
+
+	UnixSocketServerFails          string
+	unixSocketServerReceivedPath   string
+	unixSocketServerReceivedSocket string
+	unixSocketServerReceivedMode   os.FileMode
+}
+
+func (mock *AppFactoryMock) ActivatedServer(contentBasePath string, virtualHosts []cmd.VirtualHost,
+	connections cmd.ConnectionOptions) (cmd.Server, error) {
+	mock.activatedServerReceived = contentBasePath
+	mock.virtualHostsReceived = virtualHosts
+	mock.connectionsReceived = &connections
+	if mock.ActivatedServerFails != "" {
+		return nil, errors.New(mock.ActivatedServerFails)
+	}
+
+	return &ServerMock{}, nil
+}
+
+func (mock *AppFactoryMock) ActivatedServerShouldHaveReceived(contentRootPath string) {
+	ExpectWithOffset(1, mock.activatedServerReceived).To(Equal(contentRootPath))
+}
+
+func (mock *AppFactoryMock) ConfiguredServer(configPath string, connections cmd.ConnectionOptions) (cmd.Server, error) {
+	mock.configuredServerReceived = configPath
+	mock.connectionsReceived = &connections
+	if mock.ConfiguredServerFails != "" {
+		return nil, errors.New(mock.ConfiguredServerFails)
+	}
+
+	return &ServerMock{}, nil
+}
+
+func (mock *AppFactoryMock) ConfiguredServerShouldHaveReceived(configPath string) {
+	ExpectWithOffset(1, mock.configuredServerReceived).To(Equal(configPath))
 }
 
 func (mock *AppFactoryMock) ErrorCommand(err error) cmd.CliCommand {
@@ -82,11 +130,29 @@ func (mock *AppFactoryMock) RunCommand(server cmd.Server) (command cmd.CliComman
 	return mock.RunCommandReturns, mock.RunCommandReturnsChannel
 }
 
-func (mock *AppFactoryMock) TCPServer(contentBasePath string, host string, port uint16) cmd.Server {
+func (mock *AppFactoryMock) TCPServer(contentBasePath string, virtualHosts []cmd.VirtualHost, host string, port uint16,
+	tlsOptions cmd.TLSOptions, connections cmd.ConnectionOptions) (cmd.Server, error) {
+	mock.connectionsReceived = &connections
+	mock.virtualHostsReceived = virtualHosts
 	mock.tcpServerReceivedPath = contentBasePath
 	mock.tcpServerReceivedHost = host
 	mock.tcpServerReceivedPort = port
-	return nil
+	mock.tcpServerReceivedTLS = tlsOptions
+	if mock.TCPServerFails != "" {
+		return nil, errors.New(mock.TCPServerFails)
+	}
+
+	return nil, nil
+}
+
+func (mock *AppFactoryMock) TCPServerShouldHaveReceivedTLS(certFile string, keyFile string) {
+	ExpectWithOffset(1, mock.tcpServerReceivedTLS.CertFile).To(Equal(certFile))
+	ExpectWithOffset(1, mock.tcpServerReceivedTLS.KeyFile).To(Equal(keyFile))
+}
+
+func (mock *AppFactoryMock) TCPServerShouldHaveReceivedTLSPort(port uint16, redirectHTTP bool) {
+	ExpectWithOffset(1, mock.tcpServerReceivedTLS.Port).To(Equal(port))
+	ExpectWithOffset(1, mock.tcpServerReceivedTLS.RedirectHTTP).To(Equal(redirectHTTP))
 }
 
 func (mock *AppFactoryMock) TCPServerShouldHaveReceived(contentRootPath string, host string, port uint16) {
@@ -95,47 +161,221 @@ func (mock *AppFactoryMock) TCPServerShouldHaveReceived(contentRootPath string,
 	ExpectWithOffset(1, mock.tcpServerReceivedPort).To(Equal(port))
 }
 
+func (mock *AppFactoryMock) UnixSocketServer(contentBasePath string, virtualHosts []cmd.VirtualHost, socketPath string,
+	mode os.FileMode, connections cmd.ConnectionOptions) (cmd.Server, error) {
+	mock.connectionsReceived = &connections
+	mock.virtualHostsReceived = virtualHosts
+	mock.unixSocketServerReceivedPath = contentBasePath
+	mock.unixSocketServerReceivedSocket = socketPath
+	mock.unixSocketServerReceivedMode = mode
+	if mock.UnixSocketServerFails != "" {
+		return nil, errors.New(mock.UnixSocketServerFails)
+	}
+
+	return nil, nil
+}
+
+func (mock *AppFactoryMock) UnixSocketServerShouldHaveReceived(contentRootPath string, socketPath string, mode os.FileMode) {
+	ExpectWithOffset(1, mock.unixSocketServerReceivedPath).To(Equal(contentRootPath))
+	ExpectWithOffset(1, mock.unixSocketServerReceivedSocket).To(Equal(socketPath))
+	ExpectWithOffset(1, mock.unixSocketServerReceivedMode).To(Equal(mode))
+}
+
+func (mock *AppFactoryMock) ServerShouldHaveReceivedVirtualHosts(virtualHosts ...cmd.VirtualHost) {
+	ExpectWithOffset(1, mock.virtualHostsReceived).To(ConsistOf(virtualHosts))
+}
+
+func (mock *AppFactoryMock) ServerShouldHaveReceivedConnectionOptions(connections cmd.ConnectionOptions) {
+	ExpectWithOffset(1, mock.connectionsReceived).NotTo(BeNil())
+	ExpectWithOffset(1, *mock.connectionsReceived).To(Equal(connections))
+}
+
 /* ServerMock */
 
+// A Server that records its calls under a mutex, since RunServerCommand calls it from more than one goroutine
 type ServerMock struct {
-	StartFails  string
-	startCalled bool
+	StartFails string
 
-	ShutdownFails  string
+	DrainFails          string
+	DrainForciblyCloses int
+	DrainUntilShutdown  bool
+	DrainBlocksUntil    chan bool
+
+	ShutdownFails string
+
+	ListenerFilesFails string
+
+	mutex          sync.Mutex
+	started        chan bool
+	startCalled    bool
+	drainTimeout   time.Duration
+	drainCalled    bool
 	shutdownCalled bool
+	shutdown       chan bool
 }
 
-func (ServerMock) Address() net.Addr {
+func (*ServerMock) Address() net.Addr {
 	panic("implement me")
 }
 
 func (mock *ServerMock) Start() error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.startCalled = true
+	close(mock.startedChannel())
 	if mock.StartFails != "" {
-		return fmt.Errorf(mock.StartFails)
+		return errors.New(mock.StartFails)
 	}
 
 	return nil
 }
 
-func (mock ServerMock) VerifyStart() {
-	Expect(mock.startCalled).To(BeTrue())
+// Waits for the server to start, when the command is running on another goroutine
+func (mock *ServerMock) WaitForStart() {
+	mock.mutex.Lock()
+	started := mock.startedChannel()
+	mock.mutex.Unlock()
+	EventuallyWithOffset(1, started).Should(BeClosed())
+}
+
+func (mock *ServerMock) startedChannel() chan bool {
+	if mock.started == nil {
+		mock.started = make(chan bool)
+	}
+
+	return mock.started
+}
+
+func (mock *ServerMock) VerifyStart() {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	ExpectWithOffset(1, mock.startCalled).To(BeTrue())
+}
+
+func (mock *ServerMock) Drain(timeout time.Duration) (numForciblyClosed int, err error) {
+	mock.mutex.Lock()
+	mock.drainCalled = true
+	mock.drainTimeout = timeout
+	shutdown := mock.shutdown
+	mock.mutex.Unlock()
+
+	if mock.DrainUntilShutdown {
+		<-shutdown
+	}
+	if mock.DrainBlocksUntil != nil {
+		<-mock.DrainBlocksUntil
+	}
+
+	if mock.DrainFails != "" {
+		return mock.DrainForciblyCloses, errors.New(mock.DrainFails)
+	}
+
+	return mock.DrainForciblyCloses, nil
+}
+
+func (mock *ServerMock) VerifyDrained(timeout time.Duration) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	ExpectWithOffset(1, mock.drainCalled).To(BeTrue())
+	ExpectWithOffset(1, mock.drainTimeout).To(Equal(timeout))
 }
 
 func (mock *ServerMock) Shutdown() error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.shutdownCalled = true
+	if mock.shutdown != nil {
+		close(mock.shutdown)
+	}
+
 	if mock.ShutdownFails != "" {
-		return fmt.Errorf(mock.ShutdownFails)
+		return errors.New(mock.ShutdownFails)
 	}
 
 	return nil
 }
 
-func (mock ServerMock) VerifyRunning() {
-	Expect(mock.startCalled).To(BeTrue())
-	Expect(mock.shutdownCalled).To(BeFalse())
+func (mock *ServerMock) ListenerFiles() ([]*os.File, error) {
+	if mock.ListenerFilesFails != "" {
+		return nil, errors.New(mock.ListenerFilesFails)
+	}
+
+	return []*os.File{}, nil
+}
+
+func (mock *ServerMock) VerifyRunning() {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	ExpectWithOffset(1, mock.startCalled).To(BeTrue())
+	ExpectWithOffset(1, mock.drainCalled).To(BeFalse())
+	ExpectWithOffset(1, mock.shutdownCalled).To(BeFalse())
+}
+
+func (mock *ServerMock) VerifyShutdown() {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	ExpectWithOffset(1, mock.shutdownCalled).To(BeTrue())
+}
+
+func (mock *ServerMock) VerifyNotShutdown() {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	ExpectWithOffset(1, mock.shutdownCalled).To(BeFalse())
+}
+
+/* ReloadableServerMock */
+
+type ReloadableServerMock struct {
+	ServerMock
+	ReloadFails string
+	reloadCalls chan bool
 }
 
-func (mock ServerMock) VerifyShutdown() {
-	Expect(mock.shutdownCalled).To(BeTrue())
+func (mock *ReloadableServerMock) Reload() error {
+	mock.reloadCalls <- true
+	if mock.ReloadFails != "" {
+		return errors.New(mock.ReloadFails)
+	}
+
+	return nil
+}
+
+/* RestarterMock */
+
+type RestarterMock struct {
+	NotifyReadyFails string
+	mutex            sync.Mutex
+	notifyReadyCalls int
+
+	RestartFails string
+	restartCalls chan bool
+}
+
+func (mock *RestarterMock) NotifyReady() error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.notifyReadyCalls++
+	if mock.NotifyReadyFails != "" {
+		return errors.New(mock.NotifyReadyFails)
+	}
+
+	return nil
+}
+
+func (mock *RestarterMock) VerifyNotifiedReady() {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	ExpectWithOffset(1, mock.notifyReadyCalls).To(Equal(1))
+}
+
+func (mock *RestarterMock) Restart(listeners []*os.File) error {
+	if mock.restartCalls != nil {
+		mock.restartCalls <- true
+	}
+
+	if mock.RestartFails != "" {
+		return errors.New(mock.RestartFails)
+	}
+
+	return nil
 }
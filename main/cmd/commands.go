@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"net"
+	"os"
+	"time"
+
+	"github.com/kkrull/gohttp/http"
 )
 
 type ErrorCommand struct {
@@ -24,9 +29,20 @@ func (command HelpCommand) Run(stderr io.Writer) (code int, err error) {
 	return 0, nil
 }
 
+// Runs the server until the first quit request, then drains its connections.
+// A second quit request while draining stops the server immediately, without waiting for any handler that is still
+// running.
+// A restart request hands the server's listeners off to a replacement, then drains once the replacement is ready.
+// A reload request reloads the server's routes, when it is Reloadable, and keeps serving the old ones if that fails.
 type RunServerCommand struct {
-	Server Server
-	Quit   <-chan bool
+	Server       Server
+	Quit         <-chan bool
+	DrainTimeout time.Duration
+
+	Restarts  <-chan os.Signal
+	Restarter Restarter
+
+	Reloads <-chan os.Signal
 }
 
 func (command RunServerCommand) Run(stderr io.Writer) (code int, err error) {
@@ -34,20 +50,139 @@ func (command RunServerCommand) Run(stderr io.Writer) (code int, err error) {
 		return 2, err
 	}
 
-	command.waitForShutdownRequest()
-	if err := command.Server.Shutdown(); err != nil {
+	if command.Restarter != nil {
+		if err := command.Restarter.NotifyReady(); err != nil {
+			fmt.Fprintf(stderr, "gohttp: %s\n", err)
+		}
+	}
+
+	command.waitForShutdownRequest(stderr)
+	numForciblyClosed, err := command.drain()
+	if numForciblyClosed > 0 {
+		fmt.Fprintf(stderr, "gohttp: forcibly closed %d connection(s)\n", numForciblyClosed)
+	}
+
+	if err != nil {
 		return 3, err
 	}
 
 	return 0, nil
 }
 
-func (command RunServerCommand) waitForShutdownRequest() {
-	<-command.Quit
+func (command RunServerCommand) waitForShutdownRequest(stderr io.Writer) {
+	restarts := command.Restarts
+	if command.Restarter == nil {
+		restarts = nil
+	}
+
+	for {
+		select {
+		case <-command.Quit:
+			return
+		case <-restarts:
+			if err := command.restart(); err != nil {
+				fmt.Fprintf(stderr, "gohttp: restart failed: %s\n", err)
+				continue
+			}
+
+			return
+		case <-command.Reloads:
+			command.reload(stderr)
+		}
+	}
+}
+
+func (command RunServerCommand) reload(stderr io.Writer) {
+	reloadable, ok := command.Server.(Reloadable)
+	if !ok {
+		fmt.Fprintf(stderr, "gohttp: reload failed: only a server with -config can reload\n")
+		return
+	}
+
+	if err := reloadable.Reload(); err != nil {
+		fmt.Fprintf(stderr, "gohttp: reload failed: %s\n", err)
+	}
+}
+
+func (command RunServerCommand) restart() error {
+	listeners, err := command.Server.ListenerFiles()
+	if err != nil {
+		return err
+	}
+
+	defer closeFiles(listeners)
+	return command.Restarter.Restart(listeners)
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+func (command RunServerCommand) drain() (numForciblyClosed int, err error) {
+	drained := make(chan drainResult, 1)
+	go func() {
+		numForciblyClosed, err := command.Server.Drain(command.DrainTimeout)
+		drained <- drainResult{numForciblyClosed: numForciblyClosed, err: err}
+	}()
+
+	select {
+	case result := <-drained:
+		return result.numForciblyClosed, result.err
+	case <-command.Quit:
+		return 0, command.Server.Shutdown()
+	}
+}
+
+type drainResult struct {
+	numForciblyClosed int
+	err               error
 }
 
 type Server interface {
 	Address() net.Addr
 	Start() error
+
+	// Stops accepting connections and waits up to timeout for open connections to finish, before closing them
+	Drain(timeout time.Duration) (numForciblyClosed int, err error)
+
+	// Stops accepting connections and closes open connections right away
 	Shutdown() error
+
+	// Copies of the listening sockets, to hand off to a replacement process
+	ListenerFiles() ([]*os.File, error)
+}
+
+// A Server that can reload its routes while it is running
+type Reloadable interface {
+	Reload() error
+}
+
+// Starts a replacement for this process, which takes over the server's listeners
+type Restarter interface {
+	// Tells the process that this one replaced, if any, that the server is ready
+	NotifyReady() error
+
+	// Starts the replacement with copies of the listeners, and waits for it to be ready
+	Restart(listeners []*os.File) error
+}
+
+// Restarts by starting a new copy of this program with the same arguments, handing off listeners with http.Handoff
+type HandoffRestarter struct {
+	Handoff      *http.Handoff
+	ReadyTimeout time.Duration
+}
+
+func (restarter *HandoffRestarter) NotifyReady() error {
+	return restarter.Handoff.Ready()
+}
+
+func (restarter *HandoffRestarter) Restart(listeners []*os.File) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	return http.StartReplacement(executable, os.Args, nil, listeners, restarter.ReadyTimeout)
 }
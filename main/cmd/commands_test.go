@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"syscall"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -56,30 +58,31 @@ var _ = Describe("CliCommands", func() {
 		var (
 			command cmd.CliCommand
 			factory *cmd.InterruptFactory
-			server  ServerMock
+			server  *ServerMock
 			quit    chan bool
 		)
 
 		BeforeEach(func() {
-			factory = &cmd.InterruptFactory{Interrupts: make(chan os.Signal, 1)}
+			factory = &cmd.InterruptFactory{Interrupts: make(chan os.Signal, 1), DrainTimeout: 5 * time.Second}
 		})
 
 		Describe("#Run", func() {
 			Context("given a workable configuration", func() {
 				BeforeEach(func() {
-					server = ServerMock{}
-					command, quit = factory.RunCommand(&server)
+					server = &ServerMock{}
+					command, quit = factory.RunCommand(server)
 				})
 
-				It("runs the server until the quit channel receives something", func(done Done) {
+				It("runs the server until the quit channel receives something, then drains it", func(done Done) {
 					go func() {
 						defer GinkgoRecover()
 						command.Run(stderr)
-						server.VerifyShutdown()
+						server.VerifyDrained(5 * time.Second)
+						server.VerifyNotShutdown()
 						close(done)
 					}()
 
-					waitForStart()
+					server.WaitForStart()
 					server.VerifyRunning()
 					quit <- true
 				})
@@ -87,8 +90,8 @@ var _ = Describe("CliCommands", func() {
 
 			Context("when everything has run ok", func() {
 				BeforeEach(func() {
-					server = ServerMock{}
-					command, quit = factory.RunCommand(&server)
+					server = &ServerMock{}
+					command, quit = factory.RunCommand(server)
 				})
 
 				It("returns 0 and no error", func() {
@@ -101,24 +104,219 @@ var _ = Describe("CliCommands", func() {
 
 			Context("when there is an error starting the server", func() {
 				It("returns the error and an exit code indicating failure", func() {
-					server = ServerMock{StartFails: "no listening ears"}
-					command, quit = factory.RunCommand(&server)
+					server = &ServerMock{StartFails: "no listening ears"}
+					command, quit = factory.RunCommand(server)
 					code, err = command.Run(stderr)
 					Expect(code).To(Equal(2))
 					Expect(err).To(MatchError("no listening ears"))
 				})
 			})
 
-			Context("when there is an error shutting down", func() {
+			Context("when there is an error draining the server", func() {
 				It("returns the error and an exit code indicating failure", func() {
-					server = ServerMock{ShutdownFails: "backfire"}
-					command, quit = factory.RunCommand(&server)
+					server = &ServerMock{DrainFails: "backfire"}
+					command, quit = factory.RunCommand(server)
 					go scheduleShutdown(quit)
 					code, err = command.Run(stderr)
 					Expect(code).To(Equal(3))
 					Expect(err).To(MatchError("backfire"))
 				})
 			})
+
+			Context("when draining forcibly closes connections", func() {
+				It("reports how many connections were closed", func() {
+					server = &ServerMock{DrainForciblyCloses: 2}
+					command, quit = factory.RunCommand(server)
+					go scheduleShutdown(quit)
+					code, err = command.Run(stderr)
+					Expect(code).To(Equal(0))
+					Expect(stderr.String()).To(ContainSubstring("forcibly closed 2 connection(s)"))
+				})
+			})
+
+			Context("given a Restarter", func() {
+				var (
+					restarter *RestarterMock
+					restarts  chan os.Signal
+				)
+
+				BeforeEach(func() {
+					restarter = &RestarterMock{restartCalls: make(chan bool, 1)}
+					restarts = make(chan os.Signal, 1)
+					factory.Restarter = restarter
+					factory.Restarts = restarts
+					server = &ServerMock{}
+				})
+
+				It("tells the process it replaced that the server is ready, once the server starts", func() {
+					command, quit = factory.RunCommand(server)
+					go scheduleShutdown(quit)
+					code, err = command.Run(stderr)
+					restarter.VerifyNotifiedReady()
+				})
+
+				It("reports any error telling the process it replaced, and keeps running", func() {
+					restarter.NotifyReadyFails = "nobody listening"
+					command, quit = factory.RunCommand(server)
+					go scheduleShutdown(quit)
+					code, err = command.Run(stderr)
+					Expect(code).To(Equal(0))
+					Expect(stderr.String()).To(ContainSubstring("gohttp: nobody listening"))
+				})
+
+				It("hands off to a replacement on a restart signal, then drains the server", func() {
+					command, quit = factory.RunCommand(server)
+					go func() {
+						waitForStart()
+						restarts <- syscall.SIGUSR2
+					}()
+
+					code, err = command.Run(stderr)
+					Expect(restarter.restartCalls).To(Receive())
+					server.VerifyDrained(5 * time.Second)
+					Expect(code).To(Equal(0))
+				})
+
+				It("reports when the replacement fails, and keeps running until it is asked to quit", func(done Done) {
+					restarter.RestartFails = "not ready"
+					command, quit = factory.RunCommand(server)
+					go func() {
+						defer GinkgoRecover()
+						code, err = command.Run(stderr)
+						Expect(code).To(Equal(0))
+						close(done)
+					}()
+
+					server.WaitForStart()
+					restarts <- syscall.SIGUSR2
+					Eventually(restarter.restartCalls).Should(Receive())
+					server.VerifyRunning()
+					quit <- true
+				})
+
+				It("reports when the listeners can not be copied, and keeps running", func() {
+					server.ListenerFilesFails = "no listener"
+					command, quit = factory.RunCommand(server)
+					go func() {
+						waitForStart()
+						restarts <- syscall.SIGUSR2
+						scheduleShutdown(quit)
+					}()
+
+					code, err = command.Run(stderr)
+					Expect(code).To(Equal(0))
+					Expect(restarter.restartCalls).NotTo(Receive())
+					Expect(stderr.String()).To(ContainSubstring("gohttp: restart failed: no listener"))
+				})
+			})
+
+			Context("given reload requests", func() {
+				var reloads chan os.Signal
+
+				BeforeEach(func() {
+					reloads = make(chan os.Signal, 1)
+					factory.Reloads = reloads
+				})
+
+				It("reloads a Reloadable server and keeps running", func() {
+					reloadable := &ReloadableServerMock{reloadCalls: make(chan bool, 1)}
+					command, quit = factory.RunCommand(reloadable)
+					go func() {
+						waitForStart()
+						reloads <- syscall.SIGHUP
+						scheduleShutdown(quit)
+					}()
+
+					code, err = command.Run(stderr)
+					Expect(reloadable.reloadCalls).To(Receive())
+					Expect(code).To(Equal(0))
+					Expect(stderr.String()).To(BeEmpty())
+				})
+
+				It("reports when reloading fails, and keeps running", func() {
+					reloadable := &ReloadableServerMock{ReloadFails: "routes[0]: missing type", reloadCalls: make(chan bool, 1)}
+					command, quit = factory.RunCommand(reloadable)
+					go func() {
+						waitForStart()
+						reloads <- syscall.SIGHUP
+						scheduleShutdown(quit)
+					}()
+
+					code, err = command.Run(stderr)
+					Expect(code).To(Equal(0))
+					Expect(stderr.String()).To(ContainSubstring("gohttp: reload failed: routes[0]: missing type"))
+				})
+
+				It("reports that a server that is not Reloadable can not reload, and keeps running", func() {
+					server = &ServerMock{}
+					command, quit = factory.RunCommand(server)
+					go func() {
+						waitForStart()
+						reloads <- syscall.SIGHUP
+						scheduleShutdown(quit)
+					}()
+
+					code, err = command.Run(stderr)
+					Expect(code).To(Equal(0))
+					Expect(stderr.String()).To(ContainSubstring("gohttp: reload failed: only a server with -config can reload"))
+				})
+			})
+
+			Context("when the quit channel receives something again while draining", func() {
+				BeforeEach(func() {
+					server = &ServerMock{DrainUntilShutdown: true, shutdown: make(chan bool)}
+					command, quit = factory.RunCommand(server)
+				})
+
+				It("shuts down the server immediately", func() {
+					go func() {
+						scheduleShutdown(quit)
+						quit <- true
+					}()
+
+					code, err = command.Run(stderr)
+					server.VerifyShutdown()
+					Expect(code).To(Equal(0))
+				})
+
+				It("returns an error from shutting down", func() {
+					server.ShutdownFails = "backfire"
+					go func() {
+						scheduleShutdown(quit)
+						quit <- true
+					}()
+
+					code, err = command.Run(stderr)
+					Expect(code).To(Equal(3))
+					Expect(err).To(MatchError("backfire"))
+				})
+			})
+
+			Context("when the quit channel receives something again while a handler keeps draining from finishing", func() {
+				var release chan bool
+
+				BeforeEach(func() {
+					release = make(chan bool)
+					server = &ServerMock{DrainBlocksUntil: release}
+					command, quit = factory.RunCommand(server)
+				})
+
+				AfterEach(func() {
+					close(release)
+				})
+
+				It("shuts down the server and returns without waiting for draining to finish", func(done Done) {
+					go func() {
+						scheduleShutdown(quit)
+						quit <- true
+					}()
+
+					code, err = command.Run(stderr)
+					server.VerifyShutdown()
+					Expect(code).To(Equal(0))
+					close(done)
+				}, 2)
+			})
 		})
 	})
 })
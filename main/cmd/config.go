@@ -0,0 +1,692 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kkrull/gohttp/capability"
+	"github.com/kkrull/gohttp/fs"
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/log"
+	"github.com/kkrull/gohttp/playground"
+	"github.com/kkrull/gohttp/teapot"
+)
+
+// Listeners, content, and routes for a server, as read from a JSON file with LoadConfig.
+// With Hosts, each request is routed by the host name in its Host header instead of with Routes for every host.
+// Middleware is wrapped around the routes of every host, in which the first one is the outermost.
+type Config struct {
+	ContentRoot string             `json:"contentRoot"`
+	Listeners   []ListenerConfig   `json:"listeners"`
+	Routes      []RouteConfig      `json:"routes"`
+	Hosts       []HostConfig       `json:"hosts"`
+	Middleware  []MiddlewareConfig `json:"middleware"`
+}
+
+// A TCP port or Unix socket on which to listen
+type ListenerConfig struct {
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+
+	// Serves HTTPS with the certificate and key in these PEM files
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+
+	// Redirects every request to HTTPS on this port, instead of serving the routes
+	RedirectToHTTPSPort uint16 `json:"redirectToHTTPSPort"`
+
+	// Listens on a Unix domain socket instead of a TCP port, with permissions in octal like "0660"
+	Socket     string `json:"socket"`
+	SocketMode string `json:"socketMode"`
+}
+
+// One route in a server's router, which handles requests before the routes that come after it.
+// Which options apply depends upon the Type.  Routes with a path pattern like /items/{id} share one http.RouteTable,
+// which takes the place of the first one and tries the most specific pattern first.
+type RouteConfig struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+
+	ReadPath string `json:"readPath"` // For a cookie route, where clients read the cookie that was set on Path
+	Root     string `json:"root"`     // For file routes, instead of the content root
+	User     string `json:"user"`     // For a logs route, who may view the logs
+	Password string `json:"password"` // For a logs route, the password for User
+}
+
+// Middleware around every route, such as a response-header with the Name and Value of a field to add to every response
+type MiddlewareConfig struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Content and routes for requests with one of the names in their Host header, for name-based virtual hosting.
+// A host without its own contentRoot or routes uses the ones at the top of the configuration.
+type HostConfig struct {
+	Names       []string      `json:"names"`   // Like example.com, or *.example.com for every subdomain
+	Default     bool          `json:"default"` // Also serves requests for every host that is not named
+	ContentRoot string        `json:"contentRoot"`
+	Routes      []RouteConfig `json:"routes"`
+}
+
+// The routes a server has when it is not configured with a file
+var DefaultRoutes = []RouteConfig{
+	{Type: "logs", Path: "/logs"},
+
+	{Type: "capability", Path: "*"},
+	{Type: "teapot"},
+
+	{Type: "singleton", Path: "/cat-form"},
+	{Type: "cookie", Path: "/cookie", ReadPath: "/eat_cookie"},
+	{Type: "nop-post", Path: "/form"},
+	{Type: "read-write", Path: "/method_options"},
+	{Type: "read-only", Path: "/method_options2"},
+	{Type: "parameters", Path: "/parameters"},
+	{Type: "redirect", Path: "/redirect"},
+
+	//A couple paths are backed by real files and are meant to support write operations; the rest default to read-only
+	{Type: "writable-file", Path: "/patch-content.txt"},
+	{Type: "writable-file", Path: "/put-target"},
+	{Type: "file-system"},
+}
+
+// Reads and validates the configuration in a JSON file.
+// Errors name the file, and the line and column or the entry with the problem.
+func LoadConfig(path string) (*Config, error) {
+	content, readErr := ioutil.ReadFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	config, parseErr := parseConfig(content)
+	if parseErr != nil {
+		return nil, fmt.Errorf("%s: %s", path, parseErr)
+	}
+
+	return config, nil
+}
+
+func parseConfig(content []byte) (*Config, error) {
+	var raw struct {
+		ContentRoot string            `json:"contentRoot"`
+		Listeners   []json.RawMessage `json:"listeners"`
+		Routes      []json.RawMessage `json:"routes"`
+		Hosts       []json.RawMessage `json:"hosts"`
+		Middleware  []json.RawMessage `json:"middleware"`
+	}
+	if err := decodeStrictly(content, &raw); err != nil {
+		return nil, describeJSONError(content, err)
+	}
+
+	config := &Config{
+		ContentRoot: raw.ContentRoot,
+		Listeners:   make([]ListenerConfig, len(raw.Listeners)),
+		Routes:      make([]RouteConfig, len(raw.Routes)),
+		Hosts:       make([]HostConfig, len(raw.Hosts)),
+		Middleware:  make([]MiddlewareConfig, len(raw.Middleware)),
+	}
+	for i, listener := range raw.Listeners {
+		if err := decodeStrictly(listener, &config.Listeners[i]); err != nil {
+			return nil, fmt.Errorf("listeners[%d]: %s", i, err)
+		}
+	}
+	for i, route := range raw.Routes {
+		if err := decodeStrictly(route, &config.Routes[i]); err != nil {
+			return nil, fmt.Errorf("routes[%d]: %s", i, err)
+		}
+	}
+	for i, host := range raw.Hosts {
+		if err := decodeStrictly(host, &config.Hosts[i]); err != nil {
+			return nil, fmt.Errorf("hosts[%d]: %s", i, err)
+		}
+	}
+	for i, middleware := range raw.Middleware {
+		if err := decodeStrictly(middleware, &config.Middleware[i]); err != nil {
+			return nil, fmt.Errorf("middleware[%d]: %s", i, err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func decodeStrictly(content []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// Adds the line and column to a syntax error, which only has an offset
+func describeJSONError(content []byte, err error) error {
+	syntaxErr, isSyntaxErr := err.(*json.SyntaxError)
+	if !isSyntaxErr {
+		return err
+	}
+
+	before := content[:syntaxErr.Offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n') - 1
+	return fmt.Errorf("line %d, column %d: %s", line, column, syntaxErr)
+}
+
+// Checks that there is at least one listener and that each listener, route, host, and middleware has the options it
+// needs, without building any routes
+func (config *Config) Validate() error {
+	if len(config.Listeners) == 0 {
+		return fmt.Errorf("missing listeners")
+	}
+
+	for i, listener := range config.Listeners {
+		if err := listener.validate(config.Listeners); err != nil {
+			return fmt.Errorf("listeners[%d]: %s", i, err)
+		}
+	}
+
+	return config.validateRouting()
+}
+
+// Checks the middleware, and the routes or the names and routes of each host when there are hosts
+func (config *Config) validateRouting() error {
+	for i, middleware := range config.Middleware {
+		if err := middleware.validate(); err != nil {
+			return fmt.Errorf("middleware[%d]%s: %s", i, middleware.describeType(), err)
+		}
+	}
+
+	if len(config.Hosts) == 0 {
+		return validateRoutes(config.Routes, config.ContentRoot)
+	}
+
+	names := http.NewVirtualHostRouter() // Checks the names with the same rules as the router, without any routes
+	hasDefault := false
+	for i, host := range config.Hosts {
+		if err := host.validate(config, names); err != nil {
+			return fmt.Errorf("hosts[%d]: %s", i, err)
+		} else if host.Default && hasDefault {
+			return fmt.Errorf("hosts[%d]: there can only be one default host", i)
+		}
+
+		hasDefault = hasDefault || host.Default
+	}
+
+	return nil
+}
+
+// Builds a new router with the configured routes, or with a router of its own for each host when there are hosts
+func (config *Config) Router() (http.Router, error) {
+	if err := config.validateRouting(); err != nil {
+		return nil, err
+	} else if len(config.Hosts) == 0 {
+		return config.withMiddleware(buildRouter(config.Routes, config.ContentRoot)), nil
+	}
+
+	router := http.NewVirtualHostRouter()
+	for _, host := range config.Hosts {
+		hostRouter := config.withMiddleware(buildRouter(host.routing(config)))
+		for _, name := range host.Names {
+			_ = router.AddHost(name, hostRouter) // Already validated
+		}
+
+		if host.Default {
+			router.SetDefault(hostRouter)
+		}
+	}
+
+	return router, nil
+}
+
+func (host HostConfig) validate(config *Config, names *http.VirtualHostRouter) error {
+	if len(host.Names) == 0 {
+		return fmt.Errorf("missing names")
+	} else if err := validateRoutes(host.routing(config)); err != nil {
+		return err
+	}
+
+	for _, name := range host.Names {
+		if err := names.AddHost(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The routes and content root for the host, or the ones at the top of the configuration when it has none of its own
+func (host HostConfig) routing(config *Config) (routes []RouteConfig, contentRoot string) {
+	routes = host.Routes
+	if len(routes) == 0 {
+		routes = config.Routes
+	}
+
+	contentRoot = host.ContentRoot
+	if contentRoot == "" {
+		contentRoot = config.ContentRoot
+	}
+
+	return routes, contentRoot
+}
+
+func (listener ListenerConfig) validate(allListeners []ListenerConfig) error {
+	switch {
+	case listener.Socket != "" && (listener.Port != 0 || listener.Host != ""):
+		return fmt.Errorf("socket can not be used with a host or port")
+	case listener.Socket != "" && (listener.Cert != "" || listener.Key != ""):
+		return fmt.Errorf("socket can not be used with TLS")
+	case listener.Socket != "":
+		_, err := listener.socketMode()
+		return err
+	case listener.SocketMode != "":
+		return fmt.Errorf("socketMode can only be used with a socket")
+	case listener.Port == 0:
+		return fmt.Errorf("missing port or socket")
+	case listener.Cert != "" && listener.Key == "":
+		return fmt.Errorf("missing key")
+	case listener.Key != "" && listener.Cert == "":
+		return fmt.Errorf("missing cert")
+	case listener.RedirectToHTTPSPort != 0 && listener.Cert != "":
+		return fmt.Errorf("redirectToHTTPSPort can only be used on a listener without TLS")
+	case listener.RedirectToHTTPSPort != 0 && !hasTLSListener(allListeners, listener.RedirectToHTTPSPort):
+		return fmt.Errorf("redirectToHTTPSPort %d is not the port of a listener with TLS", listener.RedirectToHTTPSPort)
+	}
+
+	_, err := listener.host()
+	return err
+}
+
+func hasTLSListener(listeners []ListenerConfig, port uint16) bool {
+	for _, listener := range listeners {
+		if listener.Port == port && listener.Cert != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (listener ListenerConfig) host() (string, error) {
+	if listener.Host == "" {
+		return "localhost", nil
+	}
+
+	return parseHost(listener.Host)
+}
+
+func (listener ListenerConfig) socketMode() (os.FileMode, error) {
+	if listener.SocketMode == "" {
+		return DefaultSocketMode, nil
+	}
+
+	mode, err := strconv.ParseUint(listener.SocketMode, 8, 32)
+	if err != nil || os.FileMode(mode) > os.ModePerm {
+		return 0, fmt.Errorf("invalid socketMode: %s", listener.SocketMode)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+/* Routes */
+
+// Makes a route of one type from its configuration, after checking that it has the options it needs.
+// Routes with a root option are given the content root when they do not have their own.
+type routeType struct {
+	options  []string // Options that routes of this type may have, other than type
+	required []string // Options that routes of this type must have
+	newRoute func(route RouteConfig) http.Route
+
+	// The resource at the path, for types of routes that may have a path pattern
+	newResource func(route RouteConfig) http.Resource
+}
+
+var routeTypes = map[string]routeType{
+	"capability": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return capability.NewRoute(route.Path)
+		},
+	},
+	"cookie": {
+		options:  []string{"path", "readPath"},
+		required: []string{"path", "readPath"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewCookieRoute(route.Path, route.ReadPath)
+		},
+	},
+	"file-system": {
+		options: []string{"root"},
+		newRoute: func(route RouteConfig) http.Route {
+			return fs.NewRoute(route.Root)
+		},
+	},
+	"logs": {
+		options:  []string{"path", "user", "password"},
+		required: []string{"path"},
+	},
+	"nop-post": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewNopPostRoute(route.Path)
+		},
+		newResource: func(route RouteConfig) http.Resource {
+			return playground.NewNopPostRoute(route.Path).Resource
+		},
+	},
+	"nop-put": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewNopPutRoute(route.Path)
+		},
+		newResource: func(route RouteConfig) http.Resource {
+			return playground.NewNopPutRoute(route.Path).Resource
+		},
+	},
+	"parameters": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewParameterRoute(route.Path)
+		},
+	},
+	"read-only": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewReadOnlyRoute(route.Path)
+		},
+		newResource: func(route RouteConfig) http.Resource {
+			return playground.NewReadOnlyRoute(route.Path).Resource
+		},
+	},
+	"read-write": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewReadWriteRoute(route.Path)
+		},
+		newResource: func(route RouteConfig) http.Resource {
+			return playground.NewReadWriteRoute(route.Path).Resource
+		},
+	},
+	"redirect": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewRedirectRoute(route.Path)
+		},
+		newResource: func(route RouteConfig) http.Resource {
+			return playground.NewRedirectRoute(route.Path).Resource
+		},
+	},
+	"singleton": {
+		options:  []string{"path"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return playground.NewSingletonRoute(route.Path)
+		},
+	},
+	"teapot": {
+		newRoute: func(route RouteConfig) http.Route {
+			return teapot.NewRoute()
+		},
+	},
+	"writable-file": {
+		options:  []string{"path", "root"},
+		required: []string{"path"},
+		newRoute: func(route RouteConfig) http.Route {
+			return fs.NewWritableFileRoute(route.Path, route.Root)
+		},
+	},
+}
+
+// Builds a router with the routes in order, or returns an error naming the first route that is not valid
+func newRouter(routes []RouteConfig, contentRoot string) (*http.RequestLineRouter, error) {
+	if err := validateRoutes(routes, contentRoot); err != nil {
+		return nil, err
+	}
+
+	return buildRouter(routes, contentRoot), nil
+}
+
+// Returns an error naming the first route that is not valid, if any
+func validateRoutes(routes []RouteConfig, contentRoot string) error {
+	hasLogs := false
+	patterns := http.NewRouteTable() // Checks for conflicting patterns, the same way as the router
+	for i, route := range routes {
+		if err := route.validate(contentRoot); err != nil {
+			return fmt.Errorf("routes[%d]%s: %s", i, route.describeType(), err)
+		} else if route.Type == "logs" && hasLogs {
+			return fmt.Errorf("routes[%d]%s: there can only be one logs route", i, route.describeType())
+		} else if route.hasPattern() {
+			if err := patterns.AddResource(route.Path, routeTypes[route.Type].newResource(route)); err != nil {
+				return fmt.Errorf("routes[%d]%s: %s", i, route.describeType(), err)
+			}
+		}
+
+		hasLogs = hasLogs || route.Type == "logs"
+	}
+
+	return nil
+}
+
+// Builds a router with routes that have already been validated
+func buildRouter(routes []RouteConfig, contentRoot string) *http.RequestLineRouter {
+	router := http.NewRouter()
+	var patterns *http.RouteTable
+	for _, route := range routes {
+		if contains(routeTypes[route.Type].options, "root") {
+			route.Root = route.rootOr(contentRoot)
+		}
+
+		if route.hasPattern() {
+			if patterns == nil {
+				patterns = http.NewRouteTable()
+				router.AddRoute(patterns)
+			}
+
+			_ = patterns.AddResource(route.Path, routeTypes[route.Type].newResource(route)) // Already validated
+			continue
+		} else if route.Type != "logs" {
+			router.AddRoute(routeTypes[route.Type].newRoute(route))
+			continue
+		}
+
+		logger := log.NewBufferedRequestLogger()
+		router.LogRequests(logger)
+		router.AddRoute(route.logRoute(logger))
+	}
+
+	return router
+}
+
+func (route RouteConfig) validate(contentRoot string) error {
+	routeType, known := routeTypes[route.Type]
+	if route.Type == "" {
+		return fmt.Errorf("missing type")
+	} else if !known {
+		return fmt.Errorf("unknown type %q", route.Type)
+	}
+
+	for option, value := range route.options() {
+		if value != "" && !contains(routeType.options, option) {
+			return fmt.Errorf("%s can not be used with this type", option)
+		}
+	}
+
+	for _, option := range routeType.required {
+		if route.options()[option] == "" {
+			return fmt.Errorf("missing %s", option)
+		}
+	}
+
+	for _, option := range []string{"path", "readPath"} {
+		value := route.options()[option]
+		if value != "" && !strings.HasPrefix(value, "/") && !(route.Type == "capability" && value == "*") {
+			return fmt.Errorf("%s must start with /: %s", option, value)
+		}
+	}
+
+	if contains(routeType.options, "root") && route.rootOr(contentRoot) == "" {
+		return fmt.Errorf("missing root, and there is no contentRoot")
+	} else if route.hasPattern() && routeType.newResource == nil {
+		return fmt.Errorf("path patterns can not be used with this type: %s", route.Path)
+	} else if route.hasPattern() {
+		_, err := http.ParsePathPattern(route.Path)
+		return err
+	}
+
+	return nil
+}
+
+// Whether the path is a pattern with parameters, like /items/{id}, instead of one exact path
+func (route RouteConfig) hasPattern() bool {
+	return strings.ContainsAny(route.Path, "{}")
+}
+
+func (route RouteConfig) options() map[string]string {
+	return map[string]string{
+		"path":     route.Path,
+		"readPath": route.ReadPath,
+		"root":     route.Root,
+		"user":     route.User,
+		"password": route.Password,
+	}
+}
+
+func (route RouteConfig) describeType() string {
+	if route.Type == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (%s)", route.Type)
+}
+
+func (route RouteConfig) rootOr(contentRoot string) string {
+	if route.Root != "" {
+		return route.Root
+	}
+
+	return contentRoot
+}
+
+func (route RouteConfig) logRoute(logger log.RequestBuffer) http.Route {
+	logRoute := log.NewLogRoute(route.Path, logger)
+	viewer := logRoute.(*log.Route).Viewer
+	if route.User != "" {
+		viewer.AuthorizedUser = route.User
+	}
+	if route.Password != "" {
+		viewer.AuthorizedPassword = route.Password
+	}
+
+	return logRoute
+}
+
+func contains(values []string, value string) bool {
+	for _, each := range values {
+		if each == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* Middleware */
+
+// Makes middleware of each type from its configuration, which has already been validated
+var middlewareTypes = map[string]func(middleware MiddlewareConfig) http.Middleware{
+	"response-header": func(middleware MiddlewareConfig) http.Middleware {
+		return http.ResponseHeader(middleware.Name, middleware.Value)
+	},
+}
+
+// Wraps the configured middleware around every route in the router
+func (config *Config) withMiddleware(router *http.RequestLineRouter) *http.RequestLineRouter {
+	for _, middleware := range config.Middleware {
+		router.Use(middlewareTypes[middleware.Type](middleware))
+	}
+
+	return router
+}
+
+func (middleware MiddlewareConfig) validate() error {
+	if middleware.Type == "" {
+		return fmt.Errorf("missing type")
+	} else if _, known := middlewareTypes[middleware.Type]; !known {
+		return fmt.Errorf("unknown type %q", middleware.Type)
+	}
+
+	switch {
+	case middleware.Name == "":
+		return fmt.Errorf("missing name")
+	case strings.IndexFunc(middleware.Name, isNotTokenRune) >= 0:
+		return fmt.Errorf("invalid name: %q", middleware.Name)
+	case strings.IndexFunc(middleware.Value, isControlRune) >= 0:
+		return fmt.Errorf("invalid value: %q", middleware.Value)
+	}
+
+	return nil
+}
+
+func (middleware MiddlewareConfig) describeType() string {
+	if middleware.Type == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (%s)", middleware.Type)
+}
+
+// Whether the rune may not be in a header field name.  See RFC 7230, Section 3.2.6
+// (https://tools.ietf.org/html/rfc7230#section-3.2.6).
+func isNotTokenRune(r rune) bool {
+	isLetter := ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+	isDigit := '0' <= r && r <= '9'
+	return !(isLetter || isDigit || strings.ContainsRune("!#$%&'*+-.^_`|~", r))
+}
+
+// Whether the rune is a control character that may not be in a header field value, which allows horizontal tabs
+func isControlRune(r rune) bool {
+	return (r < ' ' && r != '\t') || r == 0x7f
+}
+
+/* Reloading */
+
+// A server for the listeners in a configuration file, which reloads its routes from the same file.
+// Changes to the listeners only apply after a restart.
+type ReloadableServer struct {
+	Server
+	ConfigPath string
+	Routed     []*http.TCPServer // Servers for the configured routes, as opposed to ones that redirect to HTTPS
+}
+
+// Builds a router from the configuration file and gives it to each server for new requests.
+// When the configuration is not valid, the servers keep using their old routes.
+func (server *ReloadableServer) Reload() error {
+	config, err := LoadConfig(server.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	router, err := config.Router()
+	if err != nil {
+		return err
+	}
+
+	for _, routed := range server.Routed {
+		if err := routed.SetRouter(router); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,361 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/kkrull/gohttp/fs"
+	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/main/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadConfig", func() {
+	var (
+		directory  string
+		configPath string
+		config     *cmd.Config
+		err        error
+	)
+
+	BeforeEach(func() {
+		directory, err = ioutil.TempDir("", "LoadConfig")
+		Expect(err).NotTo(HaveOccurred())
+		configPath = path.Join(directory, "gohttp.json")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(directory)).To(Succeed())
+	})
+
+	loadConfig := func(content string) {
+		Expect(ioutil.WriteFile(configPath, []byte(content), 0644)).To(Succeed())
+		config, err = cmd.LoadConfig(configPath)
+	}
+
+	Context("given a valid configuration", func() {
+		BeforeEach(func() {
+			loadConfig(`{
+  "contentRoot": "/public",
+  "listeners": [
+    { "port": 8080, "redirectToHTTPSPort": 8443 },
+    { "host": "[::]", "port": 8443, "cert": "cert.pem", "key": "key.pem" },
+    { "socket": "/run/gohttp.sock", "socketMode": "0600" }
+  ],
+  "routes": [
+    { "type": "logs", "path": "/logs", "user": "operator", "password": "secret" },
+    { "type": "capability", "path": "*" },
+    { "type": "writable-file", "path": "/uploads", "root": "/var/uploads" },
+    { "type": "file-system" }
+  ]
+}`)
+		})
+
+		It("returns the listeners and routes in order", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.ContentRoot).To(Equal("/public"))
+			Expect(config.Listeners).To(Equal([]cmd.ListenerConfig{
+				{Port: 8080, RedirectToHTTPSPort: 8443},
+				{Host: "[::]", Port: 8443, Cert: "cert.pem", Key: "key.pem"},
+				{Socket: "/run/gohttp.sock", SocketMode: "0600"},
+			}))
+			Expect(config.Routes).To(Equal([]cmd.RouteConfig{
+				{Type: "logs", Path: "/logs", User: "operator", Password: "secret"},
+				{Type: "capability", Path: "*"},
+				{Type: "writable-file", Path: "/uploads", Root: "/var/uploads"},
+				{Type: "file-system"},
+			}))
+		})
+
+		It("builds a router with each route", func() {
+			router, routerErr := config.Router()
+			Expect(routerErr).NotTo(HaveOccurred())
+			Expect(router.Routes()).To(HaveLen(4))
+		})
+	})
+
+	Context("given hosts", func() {
+		BeforeEach(func() {
+			loadConfig(`{
+  "contentRoot": "/public",
+  "listeners": [{ "port": 8080 }],
+  "routes": [{ "type": "teapot" }, { "type": "file-system" }],
+  "hosts": [
+    { "names": ["example.com", "www.example.com"], "contentRoot": "/srv/example", "default": true },
+    { "names": ["*.example.org"], "routes": [{ "type": "file-system" }] }
+  ]
+}`)
+		})
+
+		It("returns the hosts in order", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Hosts).To(Equal([]cmd.HostConfig{
+				{Names: []string{"example.com", "www.example.com"}, ContentRoot: "/srv/example", Default: true},
+				{Names: []string{"*.example.org"}, Routes: []cmd.RouteConfig{{Type: "file-system"}}},
+			}))
+		})
+
+		It("builds a router for each host, with the content root and routes at the top for any it does not have", func() {
+			router, routerErr := config.Router()
+			Expect(routerErr).NotTo(HaveOccurred())
+			Expect(router).To(BeAssignableToTypeOf(http.NewVirtualHostRouter()))
+			virtualHostRouter := router.(*http.VirtualHostRouter)
+			Expect(virtualHostRouter.Hosts()).To(Equal([]string{"example.com", "www.example.com", "*.example.org"}))
+			Expect(virtualHostRouter.Routes()).To(HaveLen(2))
+			Expect(virtualHostRouter.Routes()[1]).To(Equal(fs.NewRoute("/srv/example")))
+		})
+	})
+
+	Context("when the file does not exist", func() {
+		It("returns the error from reading it", func() {
+			config, err = cmd.LoadConfig(path.Join(directory, "missing.json"))
+			Expect(err).To(HaveOccurred())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("when the file is not valid JSON", func() {
+		It("returns an error with the line and column of the problem", func() {
+			loadConfig("{\n  \"listeners\": [\n    { \"port\": 8080 },,\n  ]\n}")
+			Expect(err).To(MatchError(configPath + ": line 3, column 22: invalid character ',' looking for beginning of value"))
+		})
+	})
+
+	Context("when there is an unknown setting", func() {
+		It("returns an error naming the setting", func() {
+			loadConfig(`{ "listeners": [{ "port": 8080 }], "public": "/public" }`)
+			Expect(err).To(MatchError(configPath + `: json: unknown field "public"`))
+		})
+	})
+
+	Context("when there are no listeners", func() {
+		It("returns an error", func() {
+			loadConfig(`{ "contentRoot": "/public" }`)
+			Expect(err).To(MatchError(configPath + ": missing listeners"))
+		})
+	})
+
+	Describe("listener errors", func() {
+		It("names a listener with an unknown option", func() {
+			loadConfig(`{ "listeners": [{ "port": 8080 }, { "port": 8081, "tls": true }] }`)
+			Expect(err).To(MatchError(configPath + `: listeners[1]: json: unknown field "tls"`))
+		})
+
+		It("names a listener without a port or socket", func() {
+			loadConfig(`{ "listeners": [{ "host": "0.0.0.0" }] }`)
+			Expect(err).To(MatchError(configPath + ": listeners[0]: missing port or socket"))
+		})
+
+		It("names a listener with a certificate but no key", func() {
+			loadConfig(`{ "listeners": [{ "port": 8443, "cert": "cert.pem" }] }`)
+			Expect(err).To(MatchError(configPath + ": listeners[0]: missing key"))
+		})
+
+		It("names a listener with an invalid host", func() {
+			loadConfig(`{ "listeners": [{ "host": "local host", "port": 8080 }] }`)
+			Expect(err).To(MatchError(configPath + ": listeners[0]: invalid host: local host"))
+		})
+
+		It("names a socket listener that also has a port", func() {
+			loadConfig(`{ "listeners": [{ "socket": "/run/gohttp.sock", "port": 8080 }] }`)
+			Expect(err).To(MatchError(configPath + ": listeners[0]: socket can not be used with a host or port"))
+		})
+
+		It("names a socket listener with invalid permissions", func() {
+			loadConfig(`{ "listeners": [{ "socket": "/run/gohttp.sock", "socketMode": "0999" }] }`)
+			Expect(err).To(MatchError(configPath + ": listeners[0]: invalid socketMode: 0999"))
+		})
+
+		It("names a listener that redirects to a port without TLS", func() {
+			loadConfig(`{ "listeners": [{ "port": 8080, "redirectToHTTPSPort": 8443 }, { "port": 8443 }] }`)
+			Expect(err).To(MatchError(configPath + ": listeners[0]: redirectToHTTPSPort 8443 is not the port of a listener with TLS"))
+		})
+	})
+
+	Context("given routes with path patterns", func() {
+		BeforeEach(func() {
+			loadConfig(`{
+  "contentRoot": "/public",
+  "listeners": [{ "port": 8080 }],
+  "routes": [
+    { "type": "teapot" },
+    { "type": "read-only", "path": "/items/{id}" },
+    { "type": "redirect", "path": "/items/{id}/home" },
+    { "type": "file-system" }
+  ]
+}`)
+		})
+
+		It("builds a router with one RouteTable for the patterns, in the position of the first one", func() {
+			Expect(err).NotTo(HaveOccurred())
+			router, routerErr := config.Router()
+			Expect(routerErr).NotTo(HaveOccurred())
+			Expect(router.Routes()).To(HaveLen(3))
+			Expect(router.Routes()[1]).To(BeAssignableToTypeOf(http.NewRouteTable()))
+
+			table := router.Routes()[1].(*http.RouteTable)
+			Expect(table.Patterns()).To(Equal([]string{"/items/{id}/home", "/items/{id}"}))
+		})
+	})
+
+	Context("given middleware", func() {
+		BeforeEach(func() {
+			loadConfig(`{
+  "listeners": [{ "port": 8080 }],
+  "routes": [{ "type": "teapot" }],
+  "middleware": [{ "type": "response-header", "name": "X-Frame-Options", "value": "DENY" }]
+}`)
+		})
+
+		It("returns the middleware in order", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Middleware).To(Equal([]cmd.MiddlewareConfig{
+				{Type: "response-header", Name: "X-Frame-Options", Value: "DENY"},
+			}))
+		})
+	})
+
+	Describe("route errors", func() {
+		routesConfig := func(routes string) string {
+			return `{ "contentRoot": "/public", "listeners": [{ "port": 8080 }], "routes": [` + routes + `] }`
+		}
+
+		It("names a route without a type", func() {
+			loadConfig(routesConfig(`{ "type": "teapot" }, { "path": "/form" }`))
+			Expect(err).To(MatchError(configPath + ": routes[1]: missing type"))
+		})
+
+		It("names a route with an unknown type", func() {
+			loadConfig(routesConfig(`{ "type": "coffee" }`))
+			Expect(err).To(MatchError(configPath + `: routes[0] (coffee): unknown type "coffee"`))
+		})
+
+		It("names a route with an unknown option", func() {
+			loadConfig(routesConfig(`{ "type": "teapot", "brew": "earl grey" }`))
+			Expect(err).To(MatchError(configPath + `: routes[0]: json: unknown field "brew"`))
+		})
+
+		It("names a route with an option that does not apply to its type", func() {
+			loadConfig(routesConfig(`{ "type": "teapot", "path": "/tea" }`))
+			Expect(err).To(MatchError(configPath + ": routes[0] (teapot): path can not be used with this type"))
+		})
+
+		It("names a route that is missing a required option", func() {
+			loadConfig(routesConfig(`{ "type": "cookie", "path": "/cookie" }`))
+			Expect(err).To(MatchError(configPath + ": routes[0] (cookie): missing readPath"))
+		})
+
+		It("names a route with a relative path", func() {
+			loadConfig(routesConfig(`{ "type": "redirect", "path": "redirect" }`))
+			Expect(err).To(MatchError(configPath + ": routes[0] (redirect): path must start with /: redirect"))
+		})
+
+		It("names a file route without a root, when there is no content root", func() {
+			loadConfig(`{ "listeners": [{ "port": 8080 }], "routes": [{ "type": "file-system" }] }`)
+			Expect(err).To(MatchError(configPath + ": routes[0] (file-system): missing root, and there is no contentRoot"))
+		})
+
+		It("names a route with a path pattern that is not valid", func() {
+			loadConfig(routesConfig(`{ "type": "read-only", "path": "/items/{id" }`))
+			Expect(err).To(MatchError(configPath +
+				`: routes[0] (read-only): invalid path pattern "/items/{id": a parameter must be the whole segment: {id`))
+		})
+
+		It("names a route with a path pattern, when its type only routes one exact path", func() {
+			loadConfig(routesConfig(`{ "type": "parameters", "path": "/items/{id}" }`))
+			Expect(err).To(MatchError(configPath +
+				": routes[0] (parameters): path patterns can not be used with this type: /items/{id}"))
+		})
+
+		It("names a route with a path pattern, when another route with that pattern handles the same method", func() {
+			loadConfig(routesConfig(`{ "type": "read-only", "path": "/items/{id}" }, { "type": "read-write", "path": "/items/{id}" }`))
+			Expect(err).To(MatchError(configPath + ": routes[1] (read-write): RouteTable: GET /items/{id} already has a handler"))
+		})
+
+		It("names a route with a path pattern that matches the same paths as another one", func() {
+			loadConfig(routesConfig(`{ "type": "read-only", "path": "/items/{id}" }, { "type": "redirect", "path": "/items/{name}" }`))
+			Expect(err).To(MatchError(configPath + ": routes[1] (redirect): RouteTable: /items/{name} conflicts with /items/{id}"))
+		})
+
+		It("names a second logs route", func() {
+			loadConfig(routesConfig(`{ "type": "logs", "path": "/logs" }, { "type": "logs", "path": "/more-logs" }`))
+			Expect(err).To(MatchError(configPath + ": routes[1] (logs): there can only be one logs route"))
+		})
+	})
+
+	Describe("middleware errors", func() {
+		middlewareConfig := func(middleware string) string {
+			return `{ "listeners": [{ "port": 8080 }], "routes": [{ "type": "teapot" }], "middleware": [` + middleware + `] }`
+		}
+
+		It("names middleware without a type", func() {
+			loadConfig(middlewareConfig(`{ "name": "X-Frame-Options", "value": "DENY" }`))
+			Expect(err).To(MatchError(configPath + ": middleware[0]: missing type"))
+		})
+
+		It("names middleware with an unknown type", func() {
+			loadConfig(middlewareConfig(`{ "type": "compress" }`))
+			Expect(err).To(MatchError(configPath + `: middleware[0] (compress): unknown type "compress"`))
+		})
+
+		It("names middleware with an unknown option", func() {
+			loadConfig(middlewareConfig(`{ "type": "response-header", "path": "/" }`))
+			Expect(err).To(MatchError(configPath + `: middleware[0]: json: unknown field "path"`))
+		})
+
+		It("names a response-header without a name", func() {
+			loadConfig(middlewareConfig(`{ "type": "response-header", "value": "DENY" }`))
+			Expect(err).To(MatchError(configPath + ": middleware[0] (response-header): missing name"))
+		})
+
+		It("names a response-header with a name that is not a token", func() {
+			loadConfig(middlewareConfig(`{ "type": "response-header", "name": "X-Frame Options", "value": "DENY" }`))
+			Expect(err).To(MatchError(configPath + `: middleware[0] (response-header): invalid name: "X-Frame Options"`))
+		})
+
+		It("names a response-header with a control character in its value", func() {
+			loadConfig(middlewareConfig(`{ "type": "response-header", "name": "X-Frame-Options", "value": "DENY\r\nX-Evil: 1" }`))
+			Expect(err).To(MatchError(configPath +
+				`: middleware[0] (response-header): invalid value: "DENY\r\nX-Evil: 1"`))
+		})
+	})
+
+	Describe("host errors", func() {
+		hostsConfig := func(hosts string) string {
+			return `{ "contentRoot": "/public", "listeners": [{ "port": 8080 }], "hosts": [` + hosts + `] }`
+		}
+
+		It("names a host without names", func() {
+			loadConfig(hostsConfig(`{ "names": ["example.com"] }, { "contentRoot": "/srv" }`))
+			Expect(err).To(MatchError(configPath + ": hosts[1]: missing names"))
+		})
+
+		It("names a host with an unknown option", func() {
+			loadConfig(hostsConfig(`{ "names": ["example.com"], "port": 8080 }`))
+			Expect(err).To(MatchError(configPath + `: hosts[0]: json: unknown field "port"`))
+		})
+
+		It("names a host with a name that is not valid", func() {
+			loadConfig(hostsConfig(`{ "names": ["www.*.com"] }`))
+			Expect(err).To(MatchError(configPath +
+				`: hosts[0]: invalid host name "www.*.com": a wildcard must be the whole first label, like *.example.com`))
+		})
+
+		It("names a host with a name that another host already has", func() {
+			loadConfig(hostsConfig(`{ "names": ["example.com"] }, { "names": ["Example.com"] }`))
+			Expect(err).To(MatchError(configPath + ": hosts[1]: VirtualHostRouter: example.com already has a Router"))
+		})
+
+		It("names a second default host", func() {
+			loadConfig(hostsConfig(`{ "names": ["example.com"], "default": true }, { "names": ["example.org"], "default": true }`))
+			Expect(err).To(MatchError(configPath + ": hosts[1]: there can only be one default host"))
+		})
+
+		It("names a host with a route that is not valid", func() {
+			loadConfig(hostsConfig(`{ "names": ["example.com"], "routes": [{ "type": "cookie", "path": "/cookie" }] }`))
+			Expect(err).To(MatchError(configPath + ": hosts[0]: routes[0] (cookie): missing readPath"))
+		})
+	})
+})
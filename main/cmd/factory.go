@@ -2,19 +2,94 @@ package cmd
 
 import (
 	"flag"
+	"fmt"
+	"net"
 	"os"
+	"time"
 
-	"github.com/kkrull/gohttp/capability"
-	"github.com/kkrull/gohttp/fs"
 	"github.com/kkrull/gohttp/http"
-	"github.com/kkrull/gohttp/log"
-	"github.com/kkrull/gohttp/playground"
-	"github.com/kkrull/gohttp/teapot"
 )
 
 type InterruptFactory struct {
-	Interrupts     <-chan os.Signal
-	MaxConnections uint
+	Interrupts   <-chan os.Signal
+	DrainTimeout time.Duration
+
+	// Finds the listening sockets passed in by systemd, instead of http.ActivationListeners
+	ActivationListeners func() ([]net.Listener, error)
+
+	// Listening sockets handed off by the process that this one replaced, and how to hand them off again on a restart
+	Handoff   *http.Handoff
+	Restarts  <-chan os.Signal
+	Restarter Restarter
+
+	// Requests to reload the routes of a server from a configuration file
+	Reloads <-chan os.Signal
+}
+
+// Serves on every listening socket that was passed in with systemd's socket activation protocol
+func (factory *InterruptFactory) ActivatedServer(contentRootPath string, virtualHosts []VirtualHost,
+	connections ConnectionOptions) (Server, error) {
+	activationListeners := factory.ActivationListeners
+	if activationListeners == nil {
+		activationListeners = http.ActivationListeners
+	}
+
+	listeners, err := activationListeners()
+	if err != nil {
+		return nil, err
+	} else if len(listeners) == 0 {
+		listeners = factory.Handoff.TakeAll()
+	}
+
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("no sockets were passed in by systemd")
+	}
+
+	router, err := factory.routerWithAllRoutes(contentRootPath, virtualHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*http.TCPServer, len(listeners))
+	for i, listener := range listeners {
+		servers[i] = factory.listenerServer(http.NewExistingListener(listener), router, connections)
+	}
+
+	if len(servers) == 1 {
+		return servers[0], nil
+	}
+
+	return http.NewMultiServer(servers...), nil
+}
+
+// Serves the routes in a configuration file on each of its listeners, and reloads them from the same file
+func (factory *InterruptFactory) ConfiguredServer(configPath string, connections ConnectionOptions) (Server, error) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := config.Router()
+	if err != nil {
+		return nil, err
+	}
+
+	reloadable := &ReloadableServer{ConfigPath: configPath}
+	servers := make([]*http.TCPServer, len(config.Listeners))
+	for i, listener := range config.Listeners {
+		servers[i] = factory.configuredListenerServer(listener, router, connections)
+		if listener.RedirectToHTTPSPort == 0 {
+			reloadable.Routed = append(reloadable.Routed, servers[i])
+		}
+	}
+
+	if len(servers) == 1 {
+		reloadable.Server = servers[0]
+	} else {
+		reloadable.Server = http.NewMultiServer(servers...)
+	}
+
+	return reloadable, nil
 }
 
 func (factory *InterruptFactory) CliCommandParser() *CliCommandParser {
@@ -34,41 +109,121 @@ func (factory *InterruptFactory) HelpCommand(flagSet *flag.FlagSet) CliCommand {
 
 func (factory *InterruptFactory) RunCommand(server Server) (command CliCommand, quit chan bool) {
 	quit = make(chan bool, 1)
-	command = RunServerCommand{Server: server, Quit: quit}
+	command = RunServerCommand{
+		Server:       server,
+		Quit:         quit,
+		DrainTimeout: factory.DrainTimeout,
+		Restarts:     factory.Restarts,
+		Restarter:    factory.Restarter,
+		Reloads:      factory.Reloads,
+	}
 	return
 }
 
-func (factory *InterruptFactory) TCPServer(contentRootPath string, host string, port uint16) Server {
-	router := factory.routerWithAllRoutes(contentRootPath)
-	handler := http.NewConnectionHandler(router)
-	return http.TCPServerBuilder(host).
+func (factory *InterruptFactory) TCPServer(contentRootPath string, virtualHosts []VirtualHost, host string, port uint16,
+	tlsOptions TLSOptions, connections ConnectionOptions) (Server, error) {
+	router, err := factory.routerWithAllRoutes(contentRootPath, virtualHosts)
+	if err != nil {
+		return nil, err
+	} else if tlsOptions.Port == 0 {
+		return factory.tcpServer(host, port, router, tlsOptions.CertFile, tlsOptions.KeyFile, connections), nil
+	}
+
+	var plainRouter http.Router = router
+	if tlsOptions.RedirectHTTP {
+		plainRouter = http.NewHTTPSRedirectRouter(host, tlsOptions.Port)
+	}
+
+	return http.NewMultiServer(
+		factory.tcpServer(host, port, plainRouter, "", "", connections),
+		factory.tcpServer(host, tlsOptions.Port, router, tlsOptions.CertFile, tlsOptions.KeyFile, connections)), nil
+}
+
+func (factory *InterruptFactory) UnixSocketServer(contentRootPath string, virtualHosts []VirtualHost, socketPath string,
+	mode os.FileMode, connections ConnectionOptions) (Server, error) {
+	router, err := factory.routerWithAllRoutes(contentRootPath, virtualHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory.listenerServer(http.NewUnixSocket(socketPath, mode), router, connections), nil
+}
+
+// Builds a server for one port, which serves HTTPS when there is a certificate file or plain HTTP otherwise
+func (factory *InterruptFactory) tcpServer(host string, port uint16, router http.Router, certFile string, keyFile string,
+	connections ConnectionOptions) *http.TCPServer {
+	builder := http.TCPServerBuilder(host).
 		ListeningOnPort(port).
-		WithConnectionHandler(handler).
-		WithMaxConnections(factory.MaxConnections).
+		InheritingFrom(factory.Handoff).
+		WithRouter(router).
+		WithMaxConnections(connections.MaxConnections).
+		WithRequestLimits(connections.Limits).
+		WithTimeouts(connections.Timeouts)
+	if certFile != "" {
+		builder.ServingTLS(certFile, keyFile)
+	}
+
+	return builder.Build()
+}
+
+// Builds a server for a listener in a configuration file, which has already been validated
+func (factory *InterruptFactory) configuredListenerServer(listener ListenerConfig, router http.Router,
+	connections ConnectionOptions) *http.TCPServer {
+	if listener.Socket != "" {
+		mode, _ := listener.socketMode()
+		return factory.listenerServer(http.NewUnixSocket(listener.Socket, mode), router, connections)
+	}
+
+	host, _ := listener.host()
+	if listener.RedirectToHTTPSPort != 0 {
+		router = http.NewHTTPSRedirectRouter(host, listener.RedirectToHTTPSPort)
+	}
+
+	return factory.tcpServer(host, listener.Port, router, listener.Cert, listener.Key, connections)
+}
+
+// Builds a server for a listener other than a TCP port, such as a Unix socket or one that is already open
+func (factory *InterruptFactory) listenerServer(listener http.ListenerFactory, router http.Router,
+	connections ConnectionOptions) *http.TCPServer {
+	return http.TCPServerBuilder("localhost").
+		ListeningWith(listener).
+		InheritingFrom(factory.Handoff).
+		WithRouter(router).
+		WithMaxConnections(connections.MaxConnections).
+		WithRequestLimits(connections.Limits).
+		WithTimeouts(connections.Timeouts).
 		Build()
 }
 
-func (factory *InterruptFactory) routerWithAllRoutes(contentRootPath string) http.Router {
-	router := http.NewRouter()
-
-	logger := log.NewBufferedRequestLogger()
-	router.LogRequests(logger)
-	router.AddRoute(log.NewLogRoute("/logs", logger))
-
-	router.AddRoute(capability.NewRoute("*"))
-	router.AddRoute(teapot.NewRoute())
-
-	router.AddRoute(playground.NewSingletonRoute("/cat-form"))
-	router.AddRoute(playground.NewCookieRoute("/cookie", "/eat_cookie"))
-	router.AddRoute(playground.NewNopPostRoute("/form"))
-	router.AddRoute(playground.NewReadWriteRoute("/method_options"))
-	router.AddRoute(playground.NewReadOnlyRoute("/method_options2"))
-	router.AddRoute(playground.NewParameterRoute("/parameters"))
-	router.AddRoute(playground.NewRedirectRoute("/redirect"))
-
-	//A couple paths are backed by real files and are meant to support write operations; the rest default to read-only
-	router.AddRoute(fs.NewWritableFileRoute("/patch-content.txt", contentRootPath))
-	router.AddRoute(fs.NewWritableFileRoute("/put-target", contentRootPath))
-	router.AddRoute(fs.NewRoute(contentRootPath))
-	return router
+// Builds a router with DefaultRoutes, or a router for each virtual host and one for every other host.
+// Returns an error when a virtual host has the same name as another one, or when a content root is missing.
+func (factory *InterruptFactory) routerWithAllRoutes(contentRootPath string, virtualHosts []VirtualHost) (
+	http.Router, error) {
+	defaultRouter, err := factory.routerWithDefaultRoutes(contentRootPath)
+	if err != nil || len(virtualHosts) == 0 {
+		return defaultRouter, err
+	}
+
+	router := http.NewVirtualHostRouter()
+	router.SetDefault(defaultRouter)
+	for _, host := range virtualHosts {
+		hostRouter, hostErr := factory.routerWithDefaultRoutes(host.ContentRoot)
+		if hostErr != nil {
+			return nil, fmt.Errorf("%s: %s", host.Name, hostErr)
+		} else if err := router.AddHost(host.Name, hostRouter); err != nil {
+			return nil, err
+		}
+	}
+
+	return router, nil
+}
+
+// Builds a router with DefaultRoutes, which are valid as long as there is a content root
+func (factory *InterruptFactory) routerWithDefaultRoutes(contentRootPath string) (http.Router, error) {
+	router, err := newRouter(DefaultRoutes, contentRootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return router, nil
 }
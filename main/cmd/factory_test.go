@@ -1,9 +1,16 @@
 package cmd_test
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/kkrull/gohttp/capability"
 	"github.com/kkrull/gohttp/fs"
@@ -19,8 +26,13 @@ import (
 
 var _ = Describe("InterruptFactory", func() {
 	var (
-		factory    *cmd.InterruptFactory
-		interrupts chan os.Signal
+		factory     *cmd.InterruptFactory
+		interrupts  chan os.Signal
+		connections = cmd.ConnectionOptions{
+			MaxConnections: 42,
+			Limits:         http.DefaultRequestLimits,
+			Timeouts:       http.DefaultTimeouts,
+		}
 	)
 
 	Describe("CliCommand methods", func() {
@@ -74,16 +86,14 @@ var _ = Describe("InterruptFactory", func() {
 		var (
 			server      cmd.Server
 			typedServer *http.TCPServer
+			err         error
 		)
 
 		BeforeEach(func() {
 			interrupts = make(chan os.Signal, 1)
-			factory = &cmd.InterruptFactory{
-				Interrupts:     interrupts,
-				MaxConnections: 42,
-			}
-
-			server = factory.TCPServer("/public", "localhost", 8421)
+			factory = &cmd.InterruptFactory{Interrupts: interrupts}
+			server, err = factory.TCPServer("/public", nil, "localhost", 8421, cmd.TLSOptions{}, connections)
+			Expect(err).NotTo(HaveOccurred())
 			typedServer, _ = server.(*http.TCPServer)
 		})
 
@@ -92,6 +102,73 @@ var _ = Describe("InterruptFactory", func() {
 			Expect(typedServer.MaxConnections).To(Equal(uint(42)))
 		})
 
+		It("returns an error when there is no content root for the routes that serve files", func() {
+			_, err = factory.TCPServer("", nil, "localhost", 8421, cmd.TLSOptions{}, connections)
+			Expect(err).To(MatchError("routes[10] (writable-file): missing root, and there is no contentRoot"))
+		})
+
+		It("serves plain HTTP, given no TLS files", func() {
+			Expect(typedServer.TLSConfig).To(BeNil())
+		})
+
+		It("serves HTTPS with the certificate and key, given TLS files", func() {
+			server, err = factory.TCPServer("/public", nil, "localhost", 8421, cmd.TLSOptions{CertFile: "cert.pem", KeyFile: "key.pem"}, connections)
+			Expect(err).NotTo(HaveOccurred())
+			typedServer, _ = server.(*http.TCPServer)
+			Expect(typedServer.TLSConfig).NotTo(BeNil())
+			Expect(typedServer.TLSCertFile).To(Equal("cert.pem"))
+			Expect(typedServer.TLSKeyFile).To(Equal("key.pem"))
+		})
+
+		Context("given an HTTPS port", func() {
+			var (
+				multiServer            *http.MultiServer
+				plainServer, tlsServer *http.TCPServer
+			)
+
+			BeforeEach(func() {
+				server, err = factory.TCPServer("/public", nil, "localhost", 8421, cmd.TLSOptions{
+					CertFile: "cert.pem",
+					KeyFile:  "key.pem",
+					Port:     8443,
+				}, connections)
+				Expect(err).NotTo(HaveOccurred())
+				multiServer, _ = server.(*http.MultiServer)
+				Expect(multiServer).NotTo(BeNil())
+				Expect(multiServer.Servers).To(HaveLen(2))
+				plainServer, tlsServer = multiServer.Servers[0], multiServer.Servers[1]
+			})
+
+			It("serves plain HTTP on the port", func() {
+				Expect(plainServer.Port).To(Equal(uint16(8421)))
+				Expect(plainServer.TLSConfig).To(BeNil())
+			})
+
+			It("serves HTTPS on the HTTPS port", func() {
+				Expect(tlsServer.Port).To(Equal(uint16(8443)))
+				Expect(tlsServer.TLSConfig).NotTo(BeNil())
+				Expect(tlsServer.TLSCertFile).To(Equal("cert.pem"))
+			})
+
+			It("shares the same routes on both ports", func() {
+				Expect(plainServer.Routes()).NotTo(BeEmpty())
+				Expect(plainServer.Routes()).To(Equal(tlsServer.Routes()))
+			})
+
+			It("redirects every plain HTTP request to HTTPS instead, when asked to", func() {
+				server, err = factory.TCPServer("/public", nil, "localhost", 8421, cmd.TLSOptions{
+					CertFile:     "cert.pem",
+					KeyFile:      "key.pem",
+					Port:         8443,
+					RedirectHTTP: true,
+				}, connections)
+				Expect(err).NotTo(HaveOccurred())
+				multiServer, _ = server.(*http.MultiServer)
+				Expect(multiServer.Servers[0].Routes()).To(BeEmpty())
+				Expect(multiServer.Servers[1].Routes()).NotTo(BeEmpty())
+			})
+		})
+
 		Describe("it has built-in routes that are reasonable defaults in many applications", func() {
 			It("* - target, not path - is for server-wide capabilities", func() {
 				Expect(typedServer.Routes()).To(ContainElement(BeAssignableToTypeOf(capability.NewRoute("*"))))
@@ -153,4 +230,333 @@ var _ = Describe("InterruptFactory", func() {
 			})
 		})
 	})
+
+	Describe("TCPServer with virtual hosts", func() {
+		var (
+			directory   string
+			typedServer *http.TCPServer
+		)
+
+		BeforeEach(func() {
+			var err error
+			directory, err = ioutil.TempDir("", "VirtualHosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(path.Join(directory, "hello.txt"), []byte("hello from example.com"), 0644)).To(Succeed())
+
+			factory = &cmd.InterruptFactory{}
+			server, err := factory.TCPServer("/public", []cmd.VirtualHost{{Name: "example.com", ContentRoot: directory}},
+				"localhost", 8421, cmd.TLSOptions{}, connections)
+			Expect(err).NotTo(HaveOccurred())
+			typedServer, _ = server.(*http.TCPServer)
+			Expect(typedServer).NotTo(BeNil())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(directory)).To(Succeed())
+		})
+
+		handle := func(request string) string {
+			response := &bytes.Buffer{}
+			typedServer.Handler.Handle(context.Background(), bufio.NewReader(strings.NewReader(request)), response)
+			return response.String()
+		}
+
+		It("serves each virtual host from its own content directory", func() {
+			response := handle("GET /hello.txt HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n")
+			Expect(response).To(HavePrefix("HTTP/1.1 200 OK\r\n"))
+			Expect(response).To(HaveSuffix("hello from example.com"))
+		})
+
+		It("serves every other host from the content directory", func() {
+			Expect(typedServer.Routes()).To(ContainElement(Equal(fs.NewRoute("/public"))))
+			response := handle("GET /hello.txt HTTP/1.1\r\nHost: example.org\r\nConnection: close\r\n\r\n")
+			Expect(response).To(HavePrefix("HTTP/1.1 404 Not Found\r\n"))
+		})
+
+		It("responds 400 Bad Request to an HTTP/1.1 request without a Host header", func() {
+			response := handle("GET /hello.txt HTTP/1.1\r\nConnection: close\r\n\r\n")
+			Expect(response).To(HavePrefix("HTTP/1.1 400 Bad Request\r\n"))
+		})
+
+		It("returns an error when 2 virtual hosts have the same name", func() {
+			_, err := factory.TCPServer("/public", []cmd.VirtualHost{
+				{Name: "example.com", ContentRoot: directory},
+				{Name: "EXAMPLE.com", ContentRoot: "/public"},
+			}, "localhost", 8421, cmd.TLSOptions{}, connections)
+			Expect(err).To(MatchError("VirtualHostRouter: example.com already has a Router"))
+		})
+	})
+
+	Describe("UnixSocketServer", func() {
+		var typedServer *http.TCPServer
+
+		BeforeEach(func() {
+			factory = &cmd.InterruptFactory{}
+			server, err := factory.UnixSocketServer("/public", nil, "/run/gohttp.sock", 0600, connections)
+			Expect(err).NotTo(HaveOccurred())
+			typedServer, _ = server.(*http.TCPServer)
+		})
+
+		It("returns an http.TCPServer listening on the Unix socket with the given permissions", func() {
+			Expect(typedServer).NotTo(BeNil())
+			Expect(typedServer.Listener).To(Equal(http.NewUnixSocket("/run/gohttp.sock", 0600)))
+			Expect(typedServer.MaxConnections).To(Equal(uint(42)))
+		})
+
+		It("has the same routes as a TCP server", func() {
+			Expect(typedServer.Routes()).To(ContainElement(BeAssignableToTypeOf(fs.NewRoute("/tmp"))))
+		})
+	})
+
+	Describe("ActivatedServer", func() {
+		var (
+			listeners []net.Listener
+			server    cmd.Server
+			err       error
+		)
+
+		BeforeEach(func() {
+			listeners = nil
+		})
+
+		AfterEach(func() {
+			for _, listener := range listeners {
+				_ = listener.Close()
+			}
+		})
+
+		activatedWith := func(count int) {
+			for i := 0; i < count; i++ {
+				listener, listenErr := net.Listen("tcp", "localhost:0")
+				Expect(listenErr).NotTo(HaveOccurred())
+				listeners = append(listeners, listener)
+			}
+
+			factory = &cmd.InterruptFactory{
+				ActivationListeners: func() ([]net.Listener, error) { return listeners, nil },
+			}
+			server, err = factory.ActivatedServer("/public", nil, connections)
+		}
+
+		It("returns an http.TCPServer for the socket, when there is one", func() {
+			activatedWith(1)
+			Expect(err).NotTo(HaveOccurred())
+			typedServer, _ := server.(*http.TCPServer)
+			Expect(typedServer).NotTo(BeNil())
+			Expect(typedServer.Listener).To(Equal(http.NewExistingListener(listeners[0])))
+			Expect(typedServer.MaxConnections).To(Equal(uint(42)))
+		})
+
+		It("returns an http.MultiServer with a server for each socket, when there are several", func() {
+			activatedWith(2)
+			Expect(err).NotTo(HaveOccurred())
+			multiServer, _ := server.(*http.MultiServer)
+			Expect(multiServer).NotTo(BeNil())
+			Expect(multiServer.Servers).To(HaveLen(2))
+			Expect(multiServer.Servers[1].Listener).To(Equal(http.NewExistingListener(listeners[1])))
+		})
+
+		It("returns an error, when there are no sockets", func() {
+			activatedWith(0)
+			Expect(err).To(MatchError("no sockets were passed in by systemd"))
+		})
+
+		It("returns any error from finding the sockets", func() {
+			factory = &cmd.InterruptFactory{
+				ActivationListeners: func() ([]net.Listener, error) { return nil, fmt.Errorf("bad fds") },
+			}
+			_, err = factory.ActivatedServer("/public", nil, connections)
+			Expect(err).To(MatchError("bad fds"))
+		})
+	})
+
+	Describe("ConfiguredServer", func() {
+		var (
+			directory  string
+			configPath string
+			server     cmd.Server
+			err        error
+		)
+
+		BeforeEach(func() {
+			directory, err = ioutil.TempDir("", "ConfiguredServer")
+			Expect(err).NotTo(HaveOccurred())
+			configPath = path.Join(directory, "gohttp.json")
+			factory = &cmd.InterruptFactory{}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(directory)).To(Succeed())
+		})
+
+		configuredWith := func(content string) {
+			Expect(ioutil.WriteFile(configPath, []byte(content), 0644)).To(Succeed())
+			server, err = factory.ConfiguredServer(configPath, connections)
+		}
+
+		It("returns an http.TCPServer with the configured routes, when there is one listener", func() {
+			configuredWith(`{
+  "contentRoot": "/public",
+  "listeners": [{ "host": "0.0.0.0", "port": 8080 }],
+  "routes": [{ "type": "teapot" }, { "type": "file-system" }]
+}`)
+			Expect(err).NotTo(HaveOccurred())
+			reloadable, _ := server.(*cmd.ReloadableServer)
+			Expect(reloadable).NotTo(BeNil())
+			typedServer, _ := reloadable.Server.(*http.TCPServer)
+			Expect(typedServer).NotTo(BeNil())
+			Expect(typedServer.Host).To(Equal("0.0.0.0"))
+			Expect(typedServer.Port).To(Equal(uint16(8080)))
+			Expect(typedServer.MaxConnections).To(Equal(uint(42)))
+			Expect(typedServer.Routes()).To(HaveLen(2))
+			Expect(typedServer.Routes()[0]).To(BeAssignableToTypeOf(teapot.NewRoute()))
+			Expect(typedServer.Routes()[1]).To(Equal(fs.NewRoute("/public")))
+		})
+
+		It("returns an http.MultiServer with a server for each listener, when there are several", func() {
+			configuredWith(`{
+  "contentRoot": "/public",
+  "listeners": [
+    { "port": 8080, "redirectToHTTPSPort": 8443 },
+    { "port": 8443, "cert": "cert.pem", "key": "key.pem" },
+    { "socket": "/run/gohttp.sock" }
+  ],
+  "routes": [{ "type": "file-system" }]
+}`)
+			Expect(err).NotTo(HaveOccurred())
+			reloadable, _ := server.(*cmd.ReloadableServer)
+			Expect(reloadable).NotTo(BeNil())
+			multiServer, _ := reloadable.Server.(*http.MultiServer)
+			Expect(multiServer).NotTo(BeNil())
+			Expect(multiServer.Servers).To(HaveLen(3))
+			Expect(multiServer.Servers[0].Routes()).To(BeEmpty())
+			Expect(multiServer.Servers[1].TLSCertFile).To(Equal("cert.pem"))
+			Expect(multiServer.Servers[1].Routes()).To(HaveLen(1))
+			Expect(multiServer.Servers[2].Listener).To(Equal(http.NewUnixSocket("/run/gohttp.sock", cmd.DefaultSocketMode)))
+		})
+
+		Context("given routes with path patterns", func() {
+			var typedServer *http.TCPServer
+
+			BeforeEach(func() {
+				configuredWith(`{
+  "contentRoot": "/public",
+  "listeners": [{ "port": 8080 }],
+  "routes": [
+    { "type": "redirect", "path": "/items/{id}/home" },
+    { "type": "read-only", "path": "/items/{id}" },
+    { "type": "nop-put", "path": "/items/{id}" },
+    { "type": "file-system" }
+  ]
+}`)
+				Expect(err).NotTo(HaveOccurred())
+				typedServer = server.(*cmd.ReloadableServer).Server.(*http.TCPServer)
+			})
+
+			handle := func(request string) string {
+				response := &bytes.Buffer{}
+				typedServer.Handler.Handle(context.Background(), bufio.NewReader(strings.NewReader(request)), response)
+				return response.String()
+			}
+
+			It("routes each path that matches a pattern to the resource for it", func() {
+				response := handle("GET /items/42 HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+				Expect(response).To(HavePrefix("HTTP/1.1 200 OK\r\n"))
+				response = handle("GET /items/42/home HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+				Expect(response).To(HavePrefix("HTTP/1.1 302 Found\r\n"))
+			})
+
+			It("routes each method to the route for it, when routes share a pattern", func() {
+				response := handle("PUT /items/42 HTTP/1.1\r\nHost: localhost\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+				Expect(response).To(HavePrefix("HTTP/1.1 200 OK\r\n"))
+			})
+
+			It("responds 405 Method Not Allowed with the methods that the routes with a pattern handle", func() {
+				response := handle("DELETE /items/42 HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+				Expect(response).To(HavePrefix("HTTP/1.1 405 Method Not Allowed\r\n"))
+				Expect(response).To(ContainSubstring("Allow: GET,HEAD,OPTIONS,PUT\r\n"))
+			})
+
+			It("responds to OPTIONS with the methods that the routes with a pattern handle", func() {
+				response := handle("OPTIONS /items/42 HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+				Expect(response).To(HavePrefix("HTTP/1.1 200 OK\r\n"))
+				Expect(response).To(ContainSubstring("Allow: GET,HEAD,OPTIONS,PUT\r\n"))
+			})
+
+			It("routes paths that no pattern matches to the routes after them", func() {
+				response := handle("GET /items HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+				Expect(response).To(HavePrefix("HTTP/1.1 404 Not Found\r\n"))
+			})
+		})
+
+		It("wraps the configured middleware around the routes of every host", func() {
+			configuredWith(`{
+  "contentRoot": "/public",
+  "listeners": [{ "port": 8080 }],
+  "routes": [{ "type": "teapot" }],
+  "hosts": [{ "names": ["example.com"], "default": true }],
+  "middleware": [
+    { "type": "response-header", "name": "X-Frame-Options", "value": "DENY" },
+    { "type": "response-header", "name": "X-Content-Type-Options", "value": "nosniff" }
+  ]
+}`)
+			Expect(err).NotTo(HaveOccurred())
+			typedServer := server.(*cmd.ReloadableServer).Server.(*http.TCPServer)
+
+			for _, host := range []string{"example.com", "example.org"} {
+				response := &bytes.Buffer{}
+				request := "GET /coffee HTTP/1.1\r\nHost: " + host + "\r\nConnection: close\r\n\r\n"
+				typedServer.Handler.Handle(context.Background(), bufio.NewReader(strings.NewReader(request)), response)
+				Expect(response.String()).To(HavePrefix("HTTP/1.1 418 I'm a teapot\r\n"))
+				Expect(response.String()).To(ContainSubstring("X-Frame-Options: DENY\r\nX-Content-Type-Options: nosniff\r\n"))
+			}
+		})
+
+		It("returns any error from loading the configuration", func() {
+			configuredWith(`{ "contentRoot": "/public" }`)
+			Expect(err).To(MatchError(configPath + ": missing listeners"))
+		})
+
+		Describe("reloading", func() {
+			var reloadable *cmd.ReloadableServer
+
+			BeforeEach(func() {
+				configuredWith(`{
+  "contentRoot": "/public",
+  "listeners": [
+    { "port": 8080, "redirectToHTTPSPort": 8443 },
+    { "port": 8443, "cert": "cert.pem", "key": "key.pem" }
+  ],
+  "routes": [{ "type": "file-system" }]
+}`)
+				Expect(err).NotTo(HaveOccurred())
+				reloadable, _ = server.(*cmd.ReloadableServer)
+			})
+
+			It("gives the servers for the routes a router with the routes in the file now", func() {
+				Expect(ioutil.WriteFile(configPath, []byte(`{
+  "contentRoot": "/srv",
+  "listeners": [{ "port": 8080 }],
+  "routes": [{ "type": "teapot" }, { "type": "file-system" }]
+}`), 0644)).To(Succeed())
+				Expect(reloadable.Reload()).To(Succeed())
+
+				multiServer := reloadable.Server.(*http.MultiServer)
+				Expect(multiServer.Servers[0].Routes()).To(BeEmpty())
+				Expect(multiServer.Servers[1].Routes()).To(HaveLen(2))
+				Expect(multiServer.Servers[1].Routes()[1]).To(Equal(fs.NewRoute("/srv")))
+			})
+
+			It("returns an error and keeps the old routes, when the file is no longer valid", func() {
+				Expect(ioutil.WriteFile(configPath, []byte(`{
+  "listeners": [{ "port": 8080 }],
+  "routes": [{ "type": "coffee" }]
+}`), 0644)).To(Succeed())
+				Expect(reloadable.Reload()).To(MatchError(configPath + `: routes[0] (coffee): unknown type "coffee"`))
+
+				multiServer := reloadable.Server.(*http.MultiServer)
+				Expect(multiServer.Servers[1].Routes()).To(Equal([]http.Route{fs.NewRoute("/public")}))
+			})
+		})
+	})
 })
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kkrull/gohttp/http"
+)
+
+// How many connections the server handles at once, unless it is told otherwise
+const DefaultMaxConnections uint = 4
+
+// How many connections the server handles at once, and how long and how much it waits on each client
+type ConnectionOptions struct {
+	MaxConnections uint
+	Limits         http.RequestLimits
+	Timeouts       http.Timeouts
+}
+
+var DefaultConnectionOptions = ConnectionOptions{
+	MaxConnections: DefaultMaxConnections,
+	Limits:         http.DefaultRequestLimits,
+	Timeouts:       http.DefaultTimeouts,
+}
+
+// Flags for ConnectionOptions, which default to DefaultConnectionOptions
+type connectionFlags struct {
+	maxConnections *uint
+
+	maxRequestLineBytes *int
+	maxHeaderBytes      *int
+	maxHeaderFields     *int
+	maxBodyBytes        *int64
+
+	readHeaderTimeout *time.Duration
+	readBodyTimeout   *time.Duration
+	writeTimeout      *time.Duration
+	idleTimeout       *time.Duration
+}
+
+func addConnectionFlags(flagSet *flag.FlagSet) *connectionFlags {
+	defaults := DefaultConnectionOptions
+	return &connectionFlags{
+		maxConnections: flagSet.Uint("max-connections", defaults.MaxConnections,
+			"The most connections to handle at once"),
+
+		maxRequestLineBytes: flagSet.Int("max-request-line-bytes", defaults.Limits.MaxRequestLineBytes,
+			"The longest request-line to accept, or 0 for no limit"),
+		maxHeaderBytes: flagSet.Int("max-header-bytes", defaults.Limits.MaxHeaderBytes,
+			"The largest request header to accept, or 0 for no limit"),
+		maxHeaderFields: flagSet.Int("max-header-fields", defaults.Limits.MaxHeaderFields,
+			"The most header fields to accept in a request, or 0 for no limit"),
+		maxBodyBytes: flagSet.Int64("max-body-bytes", defaults.Limits.MaxBodyBytes,
+			"The largest request body to accept, or 0 for no limit"),
+
+		readHeaderTimeout: flagSet.Duration("read-header-timeout", defaults.Timeouts.ReadHeader,
+			"How long to wait for a request's header, or 0 to wait forever"),
+		readBodyTimeout: flagSet.Duration("read-body-timeout", defaults.Timeouts.ReadBody,
+			"How long to wait for a request's body, or 0 to wait forever"),
+		writeTimeout: flagSet.Duration("write-timeout", defaults.Timeouts.Write,
+			"How long to wait for a client to take a response, or 0 to wait forever"),
+		idleTimeout: flagSet.Duration("idle-timeout", defaults.Timeouts.Idle,
+			"How long to keep an idle connection open, or 0 to keep it open forever"),
+	}
+}
+
+func (flags *connectionFlags) options() (ConnectionOptions, error) {
+	options := ConnectionOptions{
+		MaxConnections: *flags.maxConnections,
+		Limits: http.RequestLimits{
+			MaxRequestLineBytes: *flags.maxRequestLineBytes,
+			MaxHeaderBytes:      *flags.maxHeaderBytes,
+			MaxHeaderFields:     *flags.maxHeaderFields,
+			MaxBodyBytes:        *flags.maxBodyBytes,
+		},
+		Timeouts: http.Timeouts{
+			ReadHeader: *flags.readHeaderTimeout,
+			ReadBody:   *flags.readBodyTimeout,
+			Write:      *flags.writeTimeout,
+			Idle:       *flags.idleTimeout,
+		},
+	}
+
+	switch {
+	case options.MaxConnections == 0:
+		return options, fmt.Errorf("max-connections must be at least 1")
+	case options.Limits.MaxRequestLineBytes < 0:
+		return options, fmt.Errorf("invalid max-request-line-bytes: %d", options.Limits.MaxRequestLineBytes)
+	case options.Limits.MaxHeaderBytes < 0:
+		return options, fmt.Errorf("invalid max-header-bytes: %d", options.Limits.MaxHeaderBytes)
+	case options.Limits.MaxHeaderFields < 0:
+		return options, fmt.Errorf("invalid max-header-fields: %d", options.Limits.MaxHeaderFields)
+	case options.Limits.MaxBodyBytes < 0:
+		return options, fmt.Errorf("invalid max-body-bytes: %d", options.Limits.MaxBodyBytes)
+	case options.Timeouts.ReadHeader < 0:
+		return options, fmt.Errorf("invalid read-header-timeout: %s", options.Timeouts.ReadHeader)
+	case options.Timeouts.ReadBody < 0:
+		return options, fmt.Errorf("invalid read-body-timeout: %s", options.Timeouts.ReadBody)
+	case options.Timeouts.Write < 0:
+		return options, fmt.Errorf("invalid write-timeout: %s", options.Timeouts.Write)
+	case options.Timeouts.Idle < 0:
+		return options, fmt.Errorf("invalid idle-timeout: %s", options.Timeouts.Idle)
+	default:
+		return options, nil
+	}
+}
+
+// The host to bind to, which may be a host name or an IPv4 or IPv6 address like 0.0.0.0 or [::]
+func parseHost(host string) (string, error) {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+		if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+			return "", fmt.Errorf("invalid host: [%s]", host)
+		}
+
+		return host, nil
+	}
+
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+
+	if host == "" || len(host) > 253 || strings.IndexFunc(host, isInvalidHostNameRune) >= 0 {
+		return "", fmt.Errorf("invalid host: %s", host)
+	}
+
+	return host, nil
+}
+
+func isInvalidHostNameRune(r rune) bool {
+	isLetter := ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+	isDigit := '0' <= r && r <= '9'
+	return !(isLetter || isDigit || r == '-' || r == '.')
+}
+
+// A host name with content of its own, for name-based virtual hosting
+type VirtualHost struct {
+	Name        string // Like example.com, or *.example.com for every subdomain
+	ContentRoot string
+}
+
+// A flag for virtual hosts like example.com=/srv/example, which may be repeated once for each host
+type virtualHostFlag []VirtualHost
+
+func (hosts *virtualHostFlag) String() string {
+	values := make([]string, len(*hosts))
+	for i, host := range *hosts {
+		values[i] = host.Name + "=" + host.ContentRoot
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (hosts *virtualHostFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return fmt.Errorf("must be name=directory")
+	}
+
+	name, err := http.ParseHostName(parts[0])
+	if err != nil {
+		return err
+	}
+
+	for _, host := range *hosts {
+		if host.Name == name {
+			return fmt.Errorf("duplicate host name %s", name)
+		}
+	}
+
+	*hosts = append(*hosts, VirtualHost{Name: name, ContentRoot: parts[1]})
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strings"
 )
 
 type CliCommandParser struct {
@@ -15,43 +17,177 @@ type CliCommandParser struct {
 
 func (parser *CliCommandParser) Parse(args []string) CliCommand {
 	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	configPath := flagSet.String("config", "", "A JSON file with the listeners and routes to serve, instead of the other flags for them")
 	path := flagSet.String("d", "", "The root content directory, from which to operate")
-	host := "localhost"
+	virtualHosts := &virtualHostFlag{}
+	flagSet.Var(virtualHosts, "vhost",
+		"A host name and its content directory, like example.com=/srv/example or *.example.com=/srv/example, "+
+			"which may be repeated.  Requests for other host names are served from -d")
+	hostFlag := flagSet.String("host", "localhost", "The host name or IP address on which to listen, such as 0.0.0.0 or [::]")
 	port := flagSet.Uint("p", 0, "The TCP port on which to listen")
+	certFile := flagSet.String("cert", "", "A PEM file with the TLS certificate, to serve HTTPS instead of HTTP")
+	keyFile := flagSet.String("key", "", "A PEM file with the private key for the TLS certificate")
+	tlsPort := flagSet.Uint("tls-port", 0, "A TCP port on which to serve HTTPS, while still serving HTTP on the other port")
+	redirectHTTP := flagSet.Bool("redirect-http", false, "Redirect every plain HTTP request to HTTPS, instead of serving it")
+	socketPath := flagSet.String("socket", "", "A Unix domain socket on which to listen, instead of a TCP port")
+	systemd := flagSet.Bool("systemd", false, "Serve on the listening sockets passed in by systemd, instead of opening its own")
+	socketMode := flagSet.Uint("socket-mode", uint(DefaultSocketMode), "The file permissions of the Unix domain socket")
+	connectionFlags := addConnectionFlags(flagSet)
 	suppressUntimelyOutput(flagSet)
 
 	err := flagSet.Parse(args[1:])
+	host, hostErr := parseHost(*hostFlag)
+	connections, connectionsErr := connectionFlags.options()
 	switch {
 	case err == flag.ErrHelp:
 		return parser.Factory.HelpCommand(flagSet)
 	case err != nil:
 		return parser.Factory.ErrorCommand(err)
+	case *configPath != "":
+		return parser.configuredCommand(flagSet, *configPath, connections, connectionsErr)
 	case *path == "":
 		return parser.Factory.ErrorCommand(fmt.Errorf("missing path"))
+	case hostErr != nil:
+		return parser.Factory.ErrorCommand(hostErr)
+	case connectionsErr != nil:
+		return parser.Factory.ErrorCommand(connectionsErr)
+	case *systemd && (*port != 0 || *tlsPort != 0 || *socketPath != ""):
+		return parser.Factory.ErrorCommand(fmt.Errorf("systemd can not be used with a port or socket"))
+	case *systemd && (*certFile != "" || *keyFile != ""):
+		return parser.Factory.ErrorCommand(fmt.Errorf("systemd can not be used with TLS"))
+	case *systemd:
+		server, activationErr := parser.Factory.ActivatedServer(*path, *virtualHosts, connections)
+		if activationErr != nil {
+			return parser.Factory.ErrorCommand(activationErr)
+		}
+
+		return parser.runCommand(server)
+	case *socketPath != "" && (*port != 0 || *tlsPort != 0):
+		return parser.Factory.ErrorCommand(fmt.Errorf("socket can not be used with a port"))
+	case *socketPath != "" && (*certFile != "" || *keyFile != ""):
+		return parser.Factory.ErrorCommand(fmt.Errorf("socket can not be used with TLS"))
+	case *socketMode > uint(os.ModePerm):
+		return parser.Factory.ErrorCommand(fmt.Errorf("invalid socket-mode: %o", *socketMode))
+	case *socketPath != "":
+		server, serverErr := parser.Factory.UnixSocketServer(*path, *virtualHosts, *socketPath, os.FileMode(*socketMode),
+			connections)
+		if serverErr != nil {
+			return parser.Factory.ErrorCommand(serverErr)
+		}
+
+		return parser.runCommand(server)
 	case *port == 0:
 		return parser.Factory.ErrorCommand(fmt.Errorf("missing port"))
+	case *port > math.MaxUint16:
+		return parser.Factory.ErrorCommand(fmt.Errorf("invalid port: %d", *port))
+	case *tlsPort > math.MaxUint16:
+		return parser.Factory.ErrorCommand(fmt.Errorf("invalid tls-port: %d", *tlsPort))
+	case *certFile != "" && *keyFile == "":
+		return parser.Factory.ErrorCommand(fmt.Errorf("missing key"))
+	case *keyFile != "" && *certFile == "":
+		return parser.Factory.ErrorCommand(fmt.Errorf("missing cert"))
+	case *tlsPort != 0 && *certFile == "":
+		return parser.Factory.ErrorCommand(fmt.Errorf("missing cert"))
+	case *tlsPort != 0 && *tlsPort == *port:
+		return parser.Factory.ErrorCommand(fmt.Errorf("tls-port must differ from port"))
+	case *redirectHTTP && *tlsPort == 0:
+		return parser.Factory.ErrorCommand(fmt.Errorf("missing tls-port"))
 	default:
-		server := parser.Factory.TCPServer(*path, host, uint16(*port))
-		command, quit := parser.Factory.RunCommand(server)
-		go parser.sendTrueOnFirstInterruption(quit)
-		return command
+		tlsOptions := TLSOptions{
+			CertFile:     *certFile,
+			KeyFile:      *keyFile,
+			Port:         uint16(*tlsPort),
+			RedirectHTTP: *redirectHTTP,
+		}
+		server, serverErr := parser.Factory.TCPServer(*path, *virtualHosts, host, uint16(*port), tlsOptions, connections)
+		if serverErr != nil {
+			return parser.Factory.ErrorCommand(serverErr)
+		}
+
+		return parser.runCommand(server)
+	}
+}
+
+// Runs a server from a configuration file, which replaces the flags for listeners and content but not the ones for
+// connections
+func (parser *CliCommandParser) configuredCommand(flagSet *flag.FlagSet, configPath string,
+	connections ConnectionOptions, connectionsErr error) CliCommand {
+	var conflicts []string
+	flagSet.Visit(func(f *flag.Flag) {
+		if configuredFlags[f.Name] {
+			conflicts = append(conflicts, "-"+f.Name)
+		}
+	})
+
+	if len(conflicts) > 0 {
+		return parser.Factory.ErrorCommand(fmt.Errorf("config can not be used with %s", strings.Join(conflicts, ", ")))
+	} else if connectionsErr != nil {
+		return parser.Factory.ErrorCommand(connectionsErr)
+	}
+
+	server, err := parser.Factory.ConfiguredServer(configPath, connections)
+	if err != nil {
+		return parser.Factory.ErrorCommand(err)
 	}
+
+	return parser.runCommand(server)
+}
+
+// Flags for settings that a configuration file has instead
+var configuredFlags = map[string]bool{
+	"d":             true,
+	"vhost":         true,
+	"host":          true,
+	"p":             true,
+	"cert":          true,
+	"key":           true,
+	"tls-port":      true,
+	"redirect-http": true,
+	"socket":        true,
+	"socket-mode":   true,
+	"systemd":       true,
+}
+
+func (parser *CliCommandParser) runCommand(server Server) CliCommand {
+	command, quit := parser.Factory.RunCommand(server)
+	go parser.sendTrueOnFirstInterruption(quit)
+	return command
 }
 
 func suppressUntimelyOutput(flagSet *flag.FlagSet) {
 	flagSet.SetOutput(&bytes.Buffer{})
 }
 
+// Asks the command to quit on the first interruption, and to stop immediately on the second one
 func (parser *CliCommandParser) sendTrueOnFirstInterruption(quit chan<- bool) {
 	<-parser.Interrupts
 	quit <- true
+	<-parser.Interrupts
+	quit <- true
 }
 
 type AppFactory interface {
+	ActivatedServer(contentBasePath string, virtualHosts []VirtualHost, connections ConnectionOptions) (Server, error)
+	ConfiguredServer(configPath string, connections ConnectionOptions) (Server, error)
 	ErrorCommand(err error) CliCommand
 	HelpCommand(flagSet *flag.FlagSet) CliCommand
 	RunCommand(server Server) (command CliCommand, quit chan bool)
-	TCPServer(contentBasePath string, host string, port uint16) Server
+	TCPServer(contentBasePath string, virtualHosts []VirtualHost, host string, port uint16, tlsOptions TLSOptions,
+		connections ConnectionOptions) (Server, error)
+	UnixSocketServer(contentBasePath string, virtualHosts []VirtualHost, socketPath string, mode os.FileMode,
+		connections ConnectionOptions) (Server, error)
+}
+
+// Lets the owner and group of the socket file connect to it, such as a reverse proxy in the same group
+const DefaultSocketMode os.FileMode = 0660
+
+// How to serve HTTPS.  The zero value is for serving plain HTTP.
+// Without a Port, the server only serves HTTPS.  With one, it serves HTTP on the other port at the same time and
+// RedirectHTTP decides whether the HTTP port serves the same routes or redirects every request to HTTPS.
+type TLSOptions struct {
+	CertFile, KeyFile string
+	Port              uint16
+	RedirectHTTP      bool
 }
 
 type CliCommand interface {
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"syscall"
+	"time"
 
+	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/main/cmd"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -42,6 +44,35 @@ var _ = Describe("CliCommandParser", func() {
 			It("the command has usage for the root directory parameter", func() {
 				factory.HelpCommandShouldHaveFlag("p", "The TCP port on which to listen")
 			})
+			It("the command has usage for the TLS certificate and key parameters", func() {
+				factory.HelpCommandShouldHaveFlag("cert", "A PEM file with the TLS certificate, to serve HTTPS instead of HTTP")
+				factory.HelpCommandShouldHaveFlag("key", "A PEM file with the private key for the TLS certificate")
+			})
+			It("the command has usage for serving HTTP and HTTPS at the same time", func() {
+				factory.HelpCommandShouldHaveFlag("tls-port", "A TCP port on which to serve HTTPS, while still serving HTTP on the other port")
+				factory.HelpCommandShouldHaveFlag("redirect-http", "Redirect every plain HTTP request to HTTPS, instead of serving it")
+			})
+			It("the command has usage for listening on a Unix domain socket", func() {
+				factory.HelpCommandShouldHaveFlag("socket", "A Unix domain socket on which to listen, instead of a TCP port")
+				factory.HelpCommandShouldHaveFlag("socket-mode", "The file permissions of the Unix domain socket")
+			})
+			It("the command has usage for the host and the limits on connections", func() {
+				factory.HelpCommandShouldHaveFlag("host", "The host name or IP address on which to listen, such as 0.0.0.0 or [::]")
+				factory.HelpCommandShouldHaveFlag("max-connections", "The most connections to handle at once")
+				factory.HelpCommandShouldHaveFlag("max-body-bytes", "The largest request body to accept, or 0 for no limit")
+				factory.HelpCommandShouldHaveFlag("idle-timeout", "How long to keep an idle connection open, or 0 to keep it open forever")
+			})
+			It("the command has usage for virtual hosts", func() {
+				factory.HelpCommandShouldHaveFlag("vhost", "A host name and its content directory, like example.com=/srv/example "+
+					"or *.example.com=/srv/example, which may be repeated.  Requests for other host names are served from -d")
+			})
+			It("the command has usage for a configuration file", func() {
+				factory.HelpCommandShouldHaveFlag("config", "A JSON file with the listeners and routes to serve, instead of the other flags for them")
+			})
+
+			It("the command has usage for systemd socket activation", func() {
+				factory.HelpCommandShouldHaveFlag("systemd", "Serve on the listening sockets passed in by systemd, instead of opening its own")
+			})
 		})
 
 		Context("given a complete configuration for the HTTP server", func() {
@@ -59,6 +90,40 @@ var _ = Describe("CliCommandParser", func() {
 				factory.TCPServerShouldHaveReceived("/tmp", "localhost", 4242)
 			})
 
+			It("passes no TLS files, when -cert and -key are not given", func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp"})
+				factory.TCPServerShouldHaveReceivedTLS("", "")
+			})
+
+			It("passes the certificate and key files for HTTPS, when -cert and -key are given", func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem", "-key", "key.pem"})
+				factory.TCPServerShouldHaveReceivedTLS("cert.pem", "key.pem")
+				factory.TCPServerShouldHaveReceivedTLSPort(0, false)
+			})
+
+			It("passes the HTTPS port, when -tls-port is given", func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem", "-key", "key.pem", "-tls-port", "4243"})
+				factory.TCPServerShouldHaveReceived("/tmp", "localhost", 4242)
+				factory.TCPServerShouldHaveReceivedTLSPort(4243, false)
+			})
+
+			It("asks to redirect HTTP to HTTPS, when -redirect-http is given", func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem", "-key", "key.pem", "-tls-port", "4243", "-redirect-http"})
+				factory.TCPServerShouldHaveReceivedTLSPort(4243, true)
+			})
+
 			It("returns a RunServerCommand", func() {
 				runCommand = &CliCommandMock{}
 				factory = &AppFactoryMock{RunCommandReturns: runCommand}
@@ -83,6 +148,206 @@ var _ = Describe("CliCommandParser", func() {
 				interrupts <- syscall.SIGINT
 				Eventually(quitCommand).Should(Receive())
 			})
+
+			It("wires a second interrupt signal to the same channel, to stop the command immediately", func() {
+				quitCommand := make(chan bool, 1)
+				factory = &AppFactoryMock{RunCommandReturnsChannel: quitCommand}
+				parser = &cmd.CliCommandParser{
+					Factory:    factory,
+					Interrupts: interrupts,
+				}
+
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp"})
+				interrupts <- syscall.SIGINT
+				Eventually(quitCommand).Should(Receive())
+				interrupts <- syscall.SIGINT
+				Eventually(quitCommand).Should(Receive())
+			})
+		})
+
+		Context("given a host and options for connections", func() {
+			BeforeEach(func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+			})
+
+			It("uses the default connection options, when there are no flags for them", func() {
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp"})
+				factory.ServerShouldHaveReceivedConnectionOptions(cmd.DefaultConnectionOptions)
+			})
+
+			It("binds to any IPv4 address, given -host 0.0.0.0", func() {
+				parser.Parse([]string{"gohttp", "-host", "0.0.0.0", "-p", "4242", "-d", "/tmp"})
+				factory.TCPServerShouldHaveReceived("/tmp", "0.0.0.0", 4242)
+			})
+
+			It("binds to an IPv6 address, given one in brackets", func() {
+				parser.Parse([]string{"gohttp", "-host", "[::]", "-p", "4242", "-d", "/tmp"})
+				factory.TCPServerShouldHaveReceived("/tmp", "::", 4242)
+			})
+
+			It("binds to an IPv6 address, given one without brackets", func() {
+				parser.Parse([]string{"gohttp", "-host", "::1", "-p", "4242", "-d", "/tmp"})
+				factory.TCPServerShouldHaveReceived("/tmp", "::1", 4242)
+			})
+
+			It("passes the connection limits, request limits, and timeouts from the flags", func() {
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp",
+					"-max-connections", "16",
+					"-max-request-line-bytes", "1024",
+					"-max-header-bytes", "2048",
+					"-max-header-fields", "10",
+					"-max-body-bytes", "0",
+					"-read-header-timeout", "1s",
+					"-read-body-timeout", "2s",
+					"-write-timeout", "3s",
+					"-idle-timeout", "0"})
+				factory.ServerShouldHaveReceivedConnectionOptions(cmd.ConnectionOptions{
+					MaxConnections: 16,
+					Limits: http.RequestLimits{
+						MaxRequestLineBytes: 1024,
+						MaxHeaderBytes:      2048,
+						MaxHeaderFields:     10,
+						MaxBodyBytes:        0,
+					},
+					Timeouts: http.Timeouts{
+						ReadHeader: 1 * time.Second,
+						ReadBody:   2 * time.Second,
+						Write:      3 * time.Second,
+						Idle:       0,
+					},
+				})
+			})
+
+			It("passes the connection options to a server on a Unix socket", func() {
+				parser.Parse([]string{"gohttp", "-socket", "/run/gohttp.sock", "-d", "/tmp", "-max-connections", "8"})
+				expected := cmd.DefaultConnectionOptions
+				expected.MaxConnections = 8
+				factory.ServerShouldHaveReceivedConnectionOptions(expected)
+			})
+		})
+
+		Context("given virtual hosts", func() {
+			BeforeEach(func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+			})
+
+			It("passes no virtual hosts, when -vhost is not given", func() {
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp"})
+				factory.ServerShouldHaveReceivedVirtualHosts()
+			})
+
+			It("passes each host name in lower case, with its content directory", func() {
+				parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp",
+					"-vhost", "Example.com=/srv/example",
+					"-vhost", "*.example.org=/srv/org"})
+				factory.TCPServerShouldHaveReceived("/tmp", "localhost", 4242)
+				factory.ServerShouldHaveReceivedVirtualHosts(
+					cmd.VirtualHost{Name: "example.com", ContentRoot: "/srv/example"},
+					cmd.VirtualHost{Name: "*.example.org", ContentRoot: "/srv/org"})
+			})
+
+			It("passes the virtual hosts to a server on a Unix socket", func() {
+				parser.Parse([]string{"gohttp", "-socket", "/run/gohttp.sock", "-d", "/tmp", "-vhost", "example.com=/srv/example"})
+				factory.ServerShouldHaveReceivedVirtualHosts(cmd.VirtualHost{Name: "example.com", ContentRoot: "/srv/example"})
+			})
+
+			It("returns an ErrorCommand when the server can not route to the virtual hosts", func() {
+				errorCommand := &CliCommandMock{}
+				factory = &AppFactoryMock{TCPServerFails: "duplicate host", ErrorCommandReturns: errorCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				returned := parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-vhost", "example.com=/srv/example"})
+				factory.ErrorCommandShouldHaveReceived(fmt.Errorf("duplicate host"))
+				Expect(returned).To(BeIdenticalTo(errorCommand))
+			})
+
+			It("returns an ErrorCommand when a server on a Unix socket can not route to the virtual hosts", func() {
+				errorCommand := &CliCommandMock{}
+				factory = &AppFactoryMock{UnixSocketServerFails: "duplicate host", ErrorCommandReturns: errorCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				returned := parser.Parse([]string{"gohttp", "-socket", "/run/gohttp.sock", "-d", "/tmp",
+					"-vhost", "example.com=/srv/example"})
+				factory.ErrorCommandShouldHaveReceived(fmt.Errorf("duplicate host"))
+				Expect(returned).To(BeIdenticalTo(errorCommand))
+			})
+		})
+
+		Context("given a Unix domain socket instead of a port", func() {
+			var runCommand *CliCommandMock
+
+			BeforeEach(func() {
+				runCommand = &CliCommandMock{}
+				factory = &AppFactoryMock{RunCommandReturns: runCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+			})
+
+			It("creates a server listening on the socket, with the default permissions", func() {
+				returned = parser.Parse([]string{"gohttp", "-socket", "/run/gohttp.sock", "-d", "/tmp"})
+				factory.UnixSocketServerShouldHaveReceived("/tmp", "/run/gohttp.sock", cmd.DefaultSocketMode)
+				Expect(returned).To(BeIdenticalTo(runCommand))
+			})
+
+			It("uses the permissions in -socket-mode, as an octal number", func() {
+				parser.Parse([]string{"gohttp", "-socket", "/run/gohttp.sock", "-socket-mode", "0600", "-d", "/tmp"})
+				factory.UnixSocketServerShouldHaveReceived("/tmp", "/run/gohttp.sock", 0600)
+			})
+		})
+
+		Context("given -systemd instead of a port", func() {
+			It("creates a server for the sockets passed in by systemd", func() {
+				runCommand := &CliCommandMock{}
+				factory = &AppFactoryMock{RunCommandReturns: runCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				returned = parser.Parse([]string{"gohttp", "-systemd", "-d", "/tmp"})
+				factory.ActivatedServerShouldHaveReceived("/tmp")
+				Expect(returned).To(BeIdenticalTo(runCommand))
+			})
+
+			It("returns an ErrorCommand when there are no sockets to serve on", func() {
+				errorCommand := &CliCommandMock{}
+				factory = &AppFactoryMock{ActivatedServerFails: "no sockets", ErrorCommandReturns: errorCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				returned = parser.Parse([]string{"gohttp", "-systemd", "-d", "/tmp"})
+				factory.ErrorCommandShouldHaveReceived(fmt.Errorf("no sockets"))
+				Expect(returned).To(BeIdenticalTo(errorCommand))
+			})
+		})
+
+		Context("given -config instead of a port and content directory", func() {
+			It("creates a server from the configuration file", func() {
+				runCommand := &CliCommandMock{}
+				factory = &AppFactoryMock{RunCommandReturns: runCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				returned = parser.Parse([]string{"gohttp", "-config", "gohttp.json"})
+				factory.ConfiguredServerShouldHaveReceived("gohttp.json")
+				Expect(returned).To(BeIdenticalTo(runCommand))
+			})
+
+			It("still applies the connection flags", func() {
+				factory = &AppFactoryMock{RunCommandReturns: &CliCommandMock{}}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				parser.Parse([]string{"gohttp", "-config", "gohttp.json", "-max-connections", "8"})
+				connections := cmd.DefaultConnectionOptions
+				connections.MaxConnections = 8
+				factory.ServerShouldHaveReceivedConnectionOptions(connections)
+			})
+
+			It("returns an ErrorCommand when the configuration is not valid", func() {
+				errorCommand := &CliCommandMock{}
+				factory = &AppFactoryMock{ConfiguredServerFails: "gohttp.json: missing listeners", ErrorCommandReturns: errorCommand}
+				parser = &cmd.CliCommandParser{Factory: factory, Interrupts: interrupts}
+
+				returned = parser.Parse([]string{"gohttp", "-config", "gohttp.json"})
+				factory.ErrorCommandShouldHaveReceived(fmt.Errorf("gohttp.json: missing listeners"))
+				Expect(returned).To(BeIdenticalTo(errorCommand))
+			})
 		})
 
 		Describe("parsing failures", func() {
@@ -101,6 +366,152 @@ var _ = Describe("CliCommandParser", func() {
 				})
 			})
 
+			Context("when -config is given with flags for settings in the configuration file", func() {
+				It("returns an ErrorCommand naming those flags", func() {
+					returned = parser.Parse([]string{"gohttp", "-config", "gohttp.json", "-p", "4242", "-d", "/tmp"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("config can not be used with -d, -p"))
+				})
+			})
+
+			Context("when -vhost is not a host name and directory", func() {
+				It("returns an ErrorCommand stating what it must be", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-vhost", "example.com"})
+					factory.ErrorCommandShouldHaveReceived(
+						fmt.Errorf(`invalid value "example.com" for flag -vhost: must be name=directory`))
+				})
+			})
+
+			Context("when -vhost has a host name that is not valid", func() {
+				It("returns an ErrorCommand stating what is wrong with the name", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-vhost", "example.com:80=/srv"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf(
+						`invalid value "example.com:80=/srv" for flag -vhost: invalid host name "example.com:80": must not have a port`))
+				})
+			})
+
+			Context("when -vhost is given twice for the same host name", func() {
+				It("returns an ErrorCommand naming the host", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp",
+						"-vhost", "example.com=/srv/a", "-vhost", "EXAMPLE.com=/srv/b"})
+					factory.ErrorCommandShouldHaveReceived(
+						fmt.Errorf(`invalid value "EXAMPLE.com=/srv/b" for flag -vhost: duplicate host name example.com`))
+				})
+			})
+
+			Context("when -cert is given without -key", func() {
+				It("returns an ErrorCommand stating that the key is missing", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("missing key"))
+				})
+			})
+
+			Context("when -key is given without -cert", func() {
+				It("returns an ErrorCommand stating that the certificate is missing", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-key", "key.pem"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("missing cert"))
+				})
+			})
+
+			Context("when -tls-port is given without -cert and -key", func() {
+				It("returns an ErrorCommand stating that the certificate is missing", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-tls-port", "4243"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("missing cert"))
+				})
+			})
+
+			Context("when -p or -tls-port is greater than the largest TCP port", func() {
+				It("returns an ErrorCommand stating which port is not valid", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "70000", "-d", "/tmp"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid port: 70000"))
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem", "-key", "key.pem", "-tls-port", "70000"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid tls-port: 70000"))
+				})
+			})
+
+			Context("when -tls-port is the same as -p", func() {
+				It("returns an ErrorCommand stating that the ports must differ", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem", "-key", "key.pem", "-tls-port", "4242"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("tls-port must differ from port"))
+				})
+			})
+
+			Context("when -redirect-http is given without -tls-port", func() {
+				It("returns an ErrorCommand stating that the HTTPS port is missing", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-cert", "cert.pem", "-key", "key.pem", "-redirect-http"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("missing tls-port"))
+				})
+			})
+
+			Context("when -socket is given with a port", func() {
+				It("returns an ErrorCommand stating that they can not be used together", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-socket", "/run/gohttp.sock"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("socket can not be used with a port"))
+				})
+			})
+
+			Context("when -socket is given with TLS files", func() {
+				It("returns an ErrorCommand stating that they can not be used together", func() {
+					returned = parser.Parse([]string{"gohttp", "-d", "/tmp", "-socket", "/run/gohttp.sock", "-cert", "cert.pem", "-key", "key.pem"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("socket can not be used with TLS"))
+				})
+			})
+
+			Context("when -systemd is given with a port", func() {
+				It("returns an ErrorCommand stating that they can not be used together", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-systemd"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("systemd can not be used with a port or socket"))
+				})
+			})
+
+			Context("when -socket-mode has more than permission bits", func() {
+				It("returns an ErrorCommand stating that the mode is invalid", func() {
+					returned = parser.Parse([]string{"gohttp", "-d", "/tmp", "-socket", "/run/gohttp.sock", "-socket-mode", "01777"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid socket-mode: 1777"))
+				})
+			})
+
+			Context("when the host is not a host name or IP address", func() {
+				It("returns an ErrorCommand stating that the host is invalid", func() {
+					returned = parser.Parse([]string{"gohttp", "-host", "local_host", "-p", "4242", "-d", "/tmp"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid host: local_host"))
+				})
+			})
+
+			Context("when the host has brackets around something other than an IPv6 address", func() {
+				It("returns an ErrorCommand stating that the host is invalid", func() {
+					returned = parser.Parse([]string{"gohttp", "-host", "[127.0.0.1]", "-p", "4242", "-d", "/tmp"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid host: [127.0.0.1]"))
+				})
+			})
+
+			Context("when -max-connections is 0", func() {
+				It("returns an ErrorCommand stating that there must be at least 1", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-max-connections", "0"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("max-connections must be at least 1"))
+				})
+			})
+
+			Context("when a request limit is negative", func() {
+				It("returns an ErrorCommand naming the limit", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-max-header-fields", "-1"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid max-header-fields: -1"))
+				})
+			})
+
+			Context("when a timeout is negative", func() {
+				It("returns an ErrorCommand naming the timeout", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-write-timeout", "-5s"})
+					factory.ErrorCommandShouldHaveReceived(fmt.Errorf("invalid write-timeout: -5s"))
+				})
+			})
+
+			Context("when a timeout is not a duration", func() {
+				It("returns an ErrorCommand", func() {
+					returned = parser.Parse([]string{"gohttp", "-p", "4242", "-d", "/tmp", "-idle-timeout", "soon"})
+					Expect(returned).To(BeIdenticalTo(errorCommand))
+				})
+			})
+
 			Context("when the path is missing", func() {
 				It("returns an ErrorCommand stating that the path is missing", func() {
 					returned = parser.Parse([]string{"gohttp", "-p", "4242"})
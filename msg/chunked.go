@@ -0,0 +1,58 @@
+package msg
+
+import (
+	"fmt"
+	"io"
+)
+
+func NewChunkedWriter(client io.Writer) *ChunkedWriter {
+	return &ChunkedWriter{client: client}
+}
+
+// Writes a message body of unknown length as a series of chunks, followed by any trailer fields.
+// Each call to Write sends one chunk, so callers may want to buffer small writes.
+// See RFC 7230, Section 4.1 (https://tools.ietf.org/html/rfc7230#section-4.1).
+type ChunkedWriter struct {
+	client   io.Writer
+	trailers []trailerField
+}
+
+func (writer *ChunkedWriter) Write(chunk []byte) (n int, err error) {
+	if len(chunk) == 0 {
+		return 0, nil
+	}
+
+	if _, err = fmt.Fprintf(writer.client, "%x\r\n", len(chunk)); err != nil {
+		return 0, err
+	} else if n, err = writer.client.Write(chunk); err != nil {
+		return n, err
+	}
+
+	_, err = fmt.Fprint(writer.client, "\r\n")
+	return n, err
+}
+
+// Adds a field to send after the last chunk.  Declare it ahead of time with WriteTrailerHeader.
+func (writer *ChunkedWriter) AddTrailer(name string, value string) {
+	writer.trailers = append(writer.trailers, trailerField{Name: name, Value: value})
+}
+
+// Ends the body with the last chunk and any trailer fields
+func (writer *ChunkedWriter) Close() error {
+	if _, err := fmt.Fprint(writer.client, "0\r\n"); err != nil {
+		return err
+	}
+
+	for _, trailer := range writer.trailers {
+		if _, err := fmt.Fprintf(writer.client, "%s: %s\r\n", trailer.Name, trailer.Value); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(writer.client, "\r\n")
+	return err
+}
+
+type trailerField struct {
+	Name, Value string
+}
@@ -0,0 +1,47 @@
+package msg_test
+
+import (
+	"bytes"
+
+	"github.com/kkrull/gohttp/msg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChunkedWriter", func() {
+	var (
+		writer *msg.ChunkedWriter
+		client *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		client = &bytes.Buffer{}
+		writer = msg.NewChunkedWriter(client)
+	})
+
+	Describe("#Write", func() {
+		It("writes the data as a chunk, prefixed by its size in hexadecimal", func() {
+			Expect(writer.Write([]byte("0123456789ABCDEF!"))).To(Equal(17))
+			Expect(client.String()).To(Equal("11\r\n0123456789ABCDEF!\r\n"))
+		})
+
+		It("writes nothing for empty data, which would otherwise look like the last chunk", func() {
+			Expect(writer.Write([]byte{})).To(Equal(0))
+			Expect(client.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("#Close", func() {
+		It("writes the last chunk and an empty trailer section", func() {
+			writer.Write([]byte("Wiki"))
+			Expect(writer.Close()).To(Succeed())
+			Expect(client.String()).To(Equal("4\r\nWiki\r\n0\r\n\r\n"))
+		})
+
+		It("writes any trailer fields after the last chunk", func() {
+			writer.AddTrailer("Expires", "never")
+			Expect(writer.Close()).To(Succeed())
+			Expect(client.String()).To(Equal("0\r\nExpires: never\r\n\r\n"))
+		})
+	})
+})
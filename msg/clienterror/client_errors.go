@@ -1,21 +1,70 @@
 package clienterror
 
-import (
-	"io"
-
-	"github.com/kkrull/gohttp/msg"
-)
+import "github.com/kkrull/gohttp/msg"
 
 type BadRequest struct {
 	DisplayText string
 }
 
-func (badRequest *BadRequest) WriteTo(client io.Writer) error {
+func (badRequest *BadRequest) WriteTo(client msg.ResponseWriter) error {
 	return badRequest.WriteHeader(client)
 }
 
-func (badRequest *BadRequest) WriteHeader(client io.Writer) error {
+func (badRequest *BadRequest) WriteHeader(client msg.ResponseWriter) error {
 	msg.WriteStatus(client, BadRequestStatus)
-	//msg.WriteEndOfMessageHeader(client)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
+	return nil
+}
+
+type RequestTimeout struct{}
+
+func (timeout *RequestTimeout) WriteTo(client msg.ResponseWriter) error {
+	return timeout.WriteHeader(client)
+}
+
+func (timeout *RequestTimeout) WriteHeader(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, RequestTimeoutStatus)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
+	return nil
+}
+
+type PayloadTooLarge struct{}
+
+func (tooLarge *PayloadTooLarge) WriteTo(client msg.ResponseWriter) error {
+	return tooLarge.WriteHeader(client)
+}
+
+func (tooLarge *PayloadTooLarge) WriteHeader(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, PayloadTooLargeStatus)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
+	return nil
+}
+
+type URITooLong struct{}
+
+func (tooLong *URITooLong) WriteTo(client msg.ResponseWriter) error {
+	return tooLong.WriteHeader(client)
+}
+
+func (tooLong *URITooLong) WriteHeader(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, URITooLongStatus)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
+	return nil
+}
+
+type RequestHeaderFieldsTooLarge struct{}
+
+func (tooLarge *RequestHeaderFieldsTooLarge) WriteTo(client msg.ResponseWriter) error {
+	return tooLarge.WriteHeader(client)
+}
+
+func (tooLarge *RequestHeaderFieldsTooLarge) WriteHeader(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, RequestHeaderFieldsTooLargeStatus)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
 	return nil
 }
@@ -1,10 +1,6 @@
 package clienterror
 
-import (
-	"io"
-
-	"github.com/kkrull/gohttp/msg"
-)
+import "github.com/kkrull/gohttp/msg"
 
 func MethodNotAllowed(supportedMethods ...string) *methodNotAllowed {
 	return &methodNotAllowed{SupportedMethods: supportedMethods}
@@ -14,7 +10,20 @@ type methodNotAllowed struct {
 	SupportedMethods []string
 }
 
-func (notAllowed *methodNotAllowed) Handle(client io.Writer) error {
+func (notAllowed *methodNotAllowed) Handle(client msg.ResponseWriter) error {
 	msg.RespondWithAllowHeader(client, MethodNotAllowedStatus, notAllowed.SupportedMethods)
 	return nil
 }
+
+func NotFound(path string) *notFound {
+	return &notFound{Path: path}
+}
+
+type notFound struct {
+	Path string
+}
+
+func (notFound *notFound) Handle(client msg.ResponseWriter) error {
+	RespondNotFound(client, notFound.Path)
+	return nil
+}
@@ -1,18 +1,17 @@
 package clienterror_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("MethodNotAllowed", func() {
 	var (
 		request  http.Request
-		response = &bytes.Buffer{}
+		response = &httptest.ResponseBuffer{}
 	)
 
 	Describe("#Handle", func() {
@@ -26,3 +25,24 @@ var _ = Describe("MethodNotAllowed", func() {
 		It("sets Allow to GET and HEAD", httptest.ShouldAllowMethods(response, http.GET, http.HEAD))
 	})
 })
+
+var _ = Describe("NotFound", func() {
+	var (
+		request  http.Request
+		response = &httptest.ResponseBuffer{}
+	)
+
+	Describe("#Handle", func() {
+		BeforeEach(func() {
+			response.Reset()
+			request = clienterror.NotFound("/missing")
+			request.Handle(response)
+		})
+
+		It("responds 404 Not Found, with the path in the body", func() {
+			responseMessage := httptest.ParseResponse(response)
+			responseMessage.StatusShouldBe(404, "Not Found")
+			responseMessage.BodyShould(Equal("Not found: /missing"))
+		})
+	})
+})
@@ -2,16 +2,15 @@ package clienterror
 
 import (
 	"fmt"
-	"io"
 
 	"github.com/kkrull/gohttp/msg"
 )
 
-func RespondMethodNotAllowed(client io.Writer, allowedMethods []string) {
+func RespondMethodNotAllowed(client msg.ResponseWriter, allowedMethods []string) {
 	msg.RespondWithAllowHeader(client, MethodNotAllowedStatus, allowedMethods)
 }
 
-func RespondNotFound(client io.Writer, path string) {
+func RespondNotFound(client msg.ResponseWriter, path string) {
 	msg.WriteStatus(client, NotFoundStatus)
 	msg.WriteContentTypeHeader(client, "text/plain")
 
@@ -3,12 +3,16 @@ package clienterror
 import "github.com/kkrull/gohttp/msg"
 
 var (
-	BadRequestStatus          = msg.Status{400, "Bad Request"}
-	UnauthorizedStatus        = msg.Status{401, "Unauthorized"}
-	ForbiddenStatus           = msg.Status{403, "Forbidden"}
-	NotFoundStatus            = msg.Status{404, "Not Found"}
-	MethodNotAllowedStatus    = msg.Status{405, "Method Not Allowed"}
-	ConflictStatus            = msg.Status{409, "Conflict"}
-	PreconditionFailedStatus  = msg.Status{412, "Precondition Failed"}
-	RangeNotSatisfiableStatus = msg.Status{416, "Range Not Satisfiable"}
+	BadRequestStatus                  = msg.Status{400, "Bad Request"}
+	UnauthorizedStatus                = msg.Status{401, "Unauthorized"}
+	ForbiddenStatus                   = msg.Status{403, "Forbidden"}
+	NotFoundStatus                    = msg.Status{404, "Not Found"}
+	MethodNotAllowedStatus            = msg.Status{405, "Method Not Allowed"}
+	RequestTimeoutStatus              = msg.Status{408, "Request Timeout"}
+	ConflictStatus                    = msg.Status{409, "Conflict"}
+	PreconditionFailedStatus          = msg.Status{412, "Precondition Failed"}
+	PayloadTooLargeStatus             = msg.Status{413, "Payload Too Large"}
+	URITooLongStatus                  = msg.Status{414, "URI Too Long"}
+	RangeNotSatisfiableStatus         = msg.Status{416, "Range Not Satisfiable"}
+	RequestHeaderFieldsTooLargeStatus = msg.Status{431, "Request Header Fields Too Large"}
 )
@@ -0,0 +1,77 @@
+package msg
+
+import "strings"
+
+// Header fields of an HTTP message.  Field names are case-insensitive, and fields are kept in the order they were added.
+// The zero value is an empty header, ready to use.
+type Header struct {
+	fields []HeaderField
+}
+
+type HeaderField struct {
+	Name, Value string
+}
+
+// Adds a field, after any other fields with the same name
+func (header *Header) Add(name string, value string) {
+	header.fields = append(header.fields, HeaderField{Name: name, Value: value})
+}
+
+// Replaces any fields with the same name with a single field, in the position of the first one
+func (header *Header) Set(name string, value string) {
+	for i, field := range header.fields {
+		if strings.EqualFold(field.Name, name) {
+			header.fields[i] = HeaderField{Name: name, Value: value}
+			header.fields = append(header.fields[:i+1], removeFields(header.fields[i+1:], name)...)
+			return
+		}
+	}
+
+	header.Add(name, value)
+}
+
+func (header *Header) Del(name string) {
+	header.fields = removeFields(header.fields, name)
+}
+
+// The value of the first field with the given name, or "" if there are no such fields
+func (header *Header) Get(name string) string {
+	for _, field := range header.fields {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+
+	return ""
+}
+
+func (header *Header) Has(name string) bool {
+	return len(header.Values(name)) > 0
+}
+
+// The values of each field with the given name, in the order they were added
+func (header *Header) Values(name string) []string {
+	values := make([]string, 0)
+	for _, field := range header.fields {
+		if strings.EqualFold(field.Name, name) {
+			values = append(values, field.Value)
+		}
+	}
+
+	return values
+}
+
+func (header *Header) Fields() []HeaderField {
+	return append([]HeaderField(nil), header.fields...)
+}
+
+func removeFields(fields []HeaderField, name string) []HeaderField {
+	kept := make([]HeaderField, 0, len(fields))
+	for _, field := range fields {
+		if !strings.EqualFold(field.Name, name) {
+			kept = append(kept, field)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,63 @@
+package msg_test
+
+import (
+	"github.com/kkrull/gohttp/msg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header", func() {
+	var header *msg.Header
+
+	BeforeEach(func() {
+		header = &msg.Header{}
+	})
+
+	Describe("#Get", func() {
+		It("matches field names without regard to case", func() {
+			header.Add("Content-Type", "text/plain")
+			Expect(header.Get("content-type")).To(Equal("text/plain"))
+		})
+
+		It("returns the first value, when there is more than one field with the name", func() {
+			header.Add("Via", "one")
+			header.Add("Via", "two")
+			Expect(header.Get("Via")).To(Equal("one"))
+		})
+
+		It("returns an empty string for a missing field", func() {
+			Expect(header.Get("Missing")).To(BeEmpty())
+		})
+	})
+
+	Describe("#Set", func() {
+		It("replaces every field with the name, in the position of the first", func() {
+			header.Add("Via", "one")
+			header.Add("Content-Type", "text/plain")
+			header.Add("via", "two")
+			header.Set("VIA", "three")
+			Expect(header.Fields()).To(Equal([]msg.HeaderField{
+				{Name: "VIA", Value: "three"},
+				{Name: "Content-Type", Value: "text/plain"},
+			}))
+		})
+	})
+
+	Describe("#Del", func() {
+		It("removes every field with the name", func() {
+			header.Add("Via", "one")
+			header.Add("via", "two")
+			header.Del("VIA")
+			Expect(header.Has("Via")).To(BeFalse())
+		})
+	})
+
+	Describe("#Values", func() {
+		It("returns the value of each field with the name, in the order they were added", func() {
+			header.Add("Via", "one")
+			header.Add("Accept", "*/*")
+			header.Add("via", "two")
+			Expect(header.Values("Via")).To(Equal([]string{"one", "two"}))
+		})
+	})
+})
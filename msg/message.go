@@ -1,13 +1,14 @@
 package msg
 
 import (
-	"fmt"
+	"context"
 	"io"
 	"strconv"
+	"strings"
 )
 
-func WriteStatus(client io.Writer, status Status) {
-	fmt.Fprintf(client, "HTTP/1.1 %d %s\r\n", status.Code, status.Reason)
+func WriteStatus(client ResponseWriter, status Status) {
+	client.WriteStatus(status)
 }
 
 type Status struct {
@@ -15,26 +16,61 @@ type Status struct {
 	Reason string
 }
 
-func WriteContentLengthHeader(client io.Writer, numBytes int) {
+func WriteContentLengthHeader(client ResponseWriter, numBytes int) {
 	WriteHeader(client, "Content-Length", strconv.Itoa(numBytes))
 }
 
-func WriteContentTypeHeader(client io.Writer, value string) {
+func WriteContentTypeHeader(client ResponseWriter, value string) {
 	WriteHeader(client, "Content-Type", value)
 }
 
-func WriteHeader(client io.Writer, name string, value string) {
-	fmt.Fprintf(client, "%s: %s\r\n", name, value)
+// Declares that the body will be sent in chunks, instead of having a known Content-Length
+func WriteChunkedTransferEncodingHeader(client ResponseWriter) {
+	WriteHeader(client, "Transfer-Encoding", "chunked")
 }
 
-func WriteEndOfMessageHeader(client io.Writer) {
-	fmt.Fprint(client, "\r\n")
+// Declares the names of the fields that will be sent as trailers, after a chunked body
+func WriteTrailerHeader(client ResponseWriter, names ...string) {
+	WriteHeader(client, "Trailer", strings.Join(names, ", "))
 }
 
-func CopyToBody(client io.Writer, bodyReader io.Reader) {
+func WriteHeader(client ResponseWriter, name string, value string) {
+	client.AddHeader(name, value)
+}
+
+func WriteEndOfMessageHeader(client ResponseWriter) {
+	client.EndHeader()
+}
+
+func CopyToBody(client ResponseWriter, bodyReader io.Reader) {
 	io.Copy(client, bodyReader)
 }
 
-func WriteBody(client io.Writer, body string) {
-	fmt.Fprint(client, body)
+// Copies to the body a piece at a time, stopping early with the context's error once ctx is done
+func CopyToBodyContext(ctx context.Context, client ResponseWriter, bodyReader io.Reader) error {
+	buffer := make([]byte, copyBufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, readErr := bodyReader.Read(buffer)
+		if n > 0 {
+			if _, err := client.Write(buffer[:n]); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		} else if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+const copyBufferSize = 32 * 1024
+
+func WriteBody(client ResponseWriter, body string) {
+	io.WriteString(client, body)
 }
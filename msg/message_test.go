@@ -0,0 +1,33 @@
+package msg_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg"
+	"github.com/kkrull/gohttp/msg/success"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CopyToBodyContext", func() {
+	var response *httptest.ResponseBuffer
+
+	BeforeEach(func() {
+		response = &httptest.ResponseBuffer{}
+		msg.WriteStatus(response, success.OKStatus)
+	})
+
+	It("copies the whole reader to the body", func() {
+		Expect(msg.CopyToBodyContext(context.Background(), response, strings.NewReader("body"))).To(Succeed())
+		Expect(response.BodyBytesWritten()).To(BeEquivalentTo(4))
+	})
+
+	It("stops with the context's error once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(msg.CopyToBodyContext(ctx, response, strings.NewReader("body"))).To(MatchError(context.Canceled))
+		Expect(response.BodyBytesWritten()).To(BeEquivalentTo(0))
+	})
+})
@@ -0,0 +1,13 @@
+package msg_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMsg(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "msg")
+}
@@ -0,0 +1,27 @@
+package redirect
+
+import "github.com/kkrull/gohttp/msg"
+
+// Redirects to location with 301 Moved Permanently, which clients may follow with GET instead of the original method
+func MovedPermanently(location string) *relocated {
+	return &relocated{Status: MovedPermanentlyStatus, Location: location}
+}
+
+// Redirects to location with 308 Permanent Redirect, which clients follow with the original method and body.
+// See RFC 7538 (https://tools.ietf.org/html/rfc7538).
+func PermanentRedirect(location string) *relocated {
+	return &relocated{Status: PermanentRedirectStatus, Location: location}
+}
+
+type relocated struct {
+	Status   msg.Status
+	Location string
+}
+
+func (relocated *relocated) Handle(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, relocated.Status)
+	msg.WriteHeader(client, "Location", relocated.Location)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
+	return nil
+}
@@ -3,5 +3,7 @@ package redirect
 import "github.com/kkrull/gohttp/msg"
 
 var (
-	FoundStatus = msg.Status{Code: 302, Reason: "Found"}
+	MovedPermanentlyStatus  = msg.Status{Code: 301, Reason: "Moved Permanently"}
+	FoundStatus             = msg.Status{Code: 302, Reason: "Found"}
+	PermanentRedirectStatus = msg.Status{Code: 308, Reason: "Permanent Redirect"}
 )
@@ -1,11 +1,8 @@
 package msg
 
-import (
-	"io"
-	"strings"
-)
+import "strings"
 
-func RespondWithAllowHeader(client io.Writer, status Status, allowedMethods []string) {
+func RespondWithAllowHeader(client ResponseWriter, status Status, allowedMethods []string) {
 	WriteStatus(client, status)
 	WriteContentLengthHeader(client, 0)
 	WriteHeader(client, "Allow", strings.Join(allowedMethods, ","))
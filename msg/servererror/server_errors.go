@@ -1,20 +1,21 @@
 package servererror
 
 import (
-	"io"
+	"strconv"
 
 	"github.com/kkrull/gohttp/msg"
 )
 
 type InternalServerError struct{}
 
-func (internalError *InternalServerError) WriteTo(client io.Writer) error {
+func (internalError *InternalServerError) WriteTo(client msg.ResponseWriter) error {
 	return internalError.WriteHeader(client)
 }
 
-func (internalError *InternalServerError) WriteHeader(client io.Writer) error {
+func (internalError *InternalServerError) WriteHeader(client msg.ResponseWriter) error {
 	msg.WriteStatus(client, InternalServerErrorStatus)
-	//msg.WriteEndOfMessageHeader(client)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
 	return nil
 }
 
@@ -22,12 +23,30 @@ type NotImplemented struct {
 	Method string
 }
 
-func (notImplemented *NotImplemented) WriteTo(client io.Writer) error {
+func (notImplemented *NotImplemented) WriteTo(client msg.ResponseWriter) error {
 	return notImplemented.WriteHeader(client)
 }
 
-func (notImplemented *NotImplemented) WriteHeader(client io.Writer) error {
+func (notImplemented *NotImplemented) WriteHeader(client msg.ResponseWriter) error {
 	msg.WriteStatus(client, NotImplementedStatus)
-	//msg.WriteEndOfMessageHeader(client)
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
+	return nil
+}
+
+// Tells the client to try again later, after RetryAfterSeconds
+type ServiceUnavailable struct {
+	RetryAfterSeconds uint
+}
+
+func (unavailable *ServiceUnavailable) WriteTo(client msg.ResponseWriter) error {
+	return unavailable.WriteHeader(client)
+}
+
+func (unavailable *ServiceUnavailable) WriteHeader(client msg.ResponseWriter) error {
+	msg.WriteStatus(client, ServiceUnavailableStatus)
+	msg.WriteHeader(client, "Retry-After", strconv.FormatUint(uint64(unavailable.RetryAfterSeconds), 10))
+	msg.WriteContentLengthHeader(client, 0)
+	msg.WriteEndOfMessageHeader(client)
 	return nil
 }
@@ -5,4 +5,5 @@ import "github.com/kkrull/gohttp/msg"
 var (
 	InternalServerErrorStatus = msg.Status{500, "Internal Server Error"}
 	NotImplementedStatus      = msg.Status{501, "Not Implemented"}
+	ServiceUnavailableStatus  = msg.Status{503, "Service Unavailable"}
 )
@@ -1,13 +1,14 @@
 package success
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 
 	"github.com/kkrull/gohttp/msg"
 )
 
-func RespondOKWithKnownBody(client io.Writer, contentType string, body []byte) {
+func RespondOKWithKnownBody(client msg.ResponseWriter, contentType string, body []byte) {
 	msg.WriteStatus(client, OKStatus)
 	msg.WriteContentTypeHeader(client, contentType)
 	msg.WriteContentLengthHeader(client, len(body))
@@ -15,7 +16,18 @@ func RespondOKWithKnownBody(client io.Writer, contentType string, body []byte) {
 	msg.CopyToBody(client, bytes.NewReader(body))
 }
 
-func RespondOkWithoutBody(client io.Writer) {
+// Responds 200 OK with a body of unknown length, which is streamed to the client as writeBody writes it
+func RespondOKWithStreamedBody(client msg.ResponseWriter, contentType string, writeBody func(body io.Writer)) {
+	msg.WriteStatus(client, OKStatus)
+	msg.WriteContentTypeHeader(client, contentType)
+	msg.WriteEndOfMessageHeader(client)
+
+	buffered := bufio.NewWriter(client)
+	writeBody(buffered)
+	buffered.Flush()
+}
+
+func RespondOkWithoutBody(client msg.ResponseWriter) {
 	msg.WriteStatus(client, OKStatus)
 	msg.WriteContentLengthHeader(client, 0)
 	msg.WriteEndOfMessageHeader(client)
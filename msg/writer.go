@@ -0,0 +1,37 @@
+package msg
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	ErrStatusAlreadyWritten = errors.New("msg: status has already been written")
+	ErrMissingStatus        = errors.New("msg: status must be written before the message header or body")
+	ErrHeaderEnded          = errors.New("msg: message header has already ended")
+	ErrMessageEnded         = errors.New("msg: message has already ended")
+)
+
+// Writes a response message in order: the status, then the header fields, then the body.
+// Nothing is sent to the client until the body is written or the message is ended, so anything that wraps a
+// ResponseWriter may still inspect and change the status and header fields before then.
+// Writes that are out of order return an error instead of corrupting the message.
+type ResponseWriter interface {
+	// Writes to the message body, ending the message header if it has not ended already
+	io.Writer
+
+	Status() Status
+	WriteStatus(status Status) error
+
+	// Fields for the message header.  Changes made after the header ends are not sent.
+	Header() *Header
+	AddHeader(name string, value string) error
+	EndHeader() error
+
+	// Adds a field to send after a chunked body.  It is not sent when the body has some other framing.
+	AddTrailer(name string, value string) error
+	BodyBytesWritten() int64
+
+	// Finishes the message, framing a header-only response with a Content-Length of 0.  It is safe to call more than once.
+	EndMessage() error
+}
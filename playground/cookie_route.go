@@ -2,7 +2,6 @@ package playground
 
 import (
 	"fmt"
-	"io"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg"
@@ -48,7 +47,7 @@ func (monster *CookieMonster) Name() string {
 	return "Cookie Monster"
 }
 
-func (monster *CookieMonster) Get(client io.Writer, message http.RequestMessage) {
+func (monster *CookieMonster) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	sessionCookie, err := singleHeader(message, "Cookie")
 	if err != nil {
 		monster.badCookieState(client)
@@ -56,14 +55,16 @@ func (monster *CookieMonster) Get(client io.Writer, message http.RequestMessage)
 		cookieType := sessionCookie
 		monster.preferredCookieState(client, cookieType)
 	}
+
+	return nil
 }
 
-func (monster *CookieMonster) badCookieState(client io.Writer) {
+func (monster *CookieMonster) badCookieState(client msg.ResponseWriter) {
 	msg.WriteStatus(client, clienterror.BadRequestStatus)
 	msg.WriteEndOfMessageHeader(client)
 }
 
-func (monster *CookieMonster) preferredCookieState(client io.Writer, cookieType string) {
+func (monster *CookieMonster) preferredCookieState(client msg.ResponseWriter, cookieType string) {
 	msg.WriteStatus(client, success.OKStatus)
 	msg.WriteContentTypeHeader(client, "text/plain")
 
@@ -80,21 +81,23 @@ func (registrar *CookieRegistrar) Name() string {
 	return "Cookie registrar"
 }
 
-func (registrar *CookieRegistrar) Get(client io.Writer, message http.RequestMessage) {
+func (registrar *CookieRegistrar) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	cookieType, err := singleQueryParameter(message, "type")
 	if err != nil {
 		registrar.invalidTypeState(client)
 	} else {
 		registrar.typeSetState(client, cookieType)
 	}
+
+	return nil
 }
 
-func (registrar *CookieRegistrar) invalidTypeState(client io.Writer) {
+func (registrar *CookieRegistrar) invalidTypeState(client msg.ResponseWriter) {
 	msg.WriteStatus(client, clienterror.BadRequestStatus)
 	msg.WriteEndOfMessageHeader(client)
 }
 
-func (registrar *CookieRegistrar) typeSetState(client io.Writer, cookieType string) {
+func (registrar *CookieRegistrar) typeSetState(client msg.ResponseWriter, cookieType string) {
 	msg.WriteStatus(client, success.OKStatus)
 	msg.WriteHeader(client, "Set-Cookie", cookieType)
 	msg.WriteContentTypeHeader(client, "text/plain")
@@ -1,8 +1,6 @@
 package playground_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -45,7 +43,7 @@ var _ = Describe("CookieRoute", func() {
 		router    http.Route
 		registrar *playground.CookieRegistrar
 		monster   *playground.CookieMonster
-		response  = &bytes.Buffer{}
+		response  = &httptest.ResponseBuffer{}
 	)
 
 	Describe("#Route", func() {
@@ -3,7 +3,6 @@ package playground
 import (
 	"bytes"
 	"fmt"
-	"io"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg"
@@ -32,7 +31,7 @@ func (route *ParameterRoute) Route(requested http.RequestMessage) http.Request {
 
 type ParameterReporter interface {
 	Name() string
-	Get(client io.Writer, message http.RequestMessage)
+	Get(client msg.ResponseWriter, message http.RequestMessage) error
 }
 
 // Lists query parameters as simple assignment statements
@@ -42,7 +41,7 @@ func (reporter *AssignmentReporter) Name() string {
 	return "Parameter Report"
 }
 
-func (reporter *AssignmentReporter) Get(client io.Writer, message http.RequestMessage) {
+func (reporter *AssignmentReporter) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	msg.WriteStatus(client, success.OKStatus)
 	msg.WriteContentTypeHeader(client, "text/plain")
 
@@ -51,6 +50,7 @@ func (reporter *AssignmentReporter) Get(client io.Writer, message http.RequestMe
 	msg.WriteEndOfMessageHeader(client)
 
 	msg.WriteBody(client, body.String())
+	return nil
 }
 
 func (reporter *AssignmentReporter) makeBody(requestMessage http.RequestMessage) *bytes.Buffer {
@@ -1,8 +1,6 @@
 package playground_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -32,7 +30,7 @@ var _ = Describe("ParameterRoute", func() {
 		var (
 			router   http.Route
 			reporter *ParameterReporterMock
-			response = &bytes.Buffer{}
+			response = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
@@ -90,7 +88,7 @@ var _ = Describe("AssignmentReporter", func() {
 			request         *httptest.RequestMessage
 			responseMessage *httptest.ResponseMessage
 
-			response = &bytes.Buffer{}
+			response = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
@@ -1,12 +1,11 @@
 package playground_test
 
 import (
-	"bytes"
-	"io"
 	"testing"
 
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
+	"github.com/kkrull/gohttp/msg"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -24,7 +23,9 @@ func (mock *ParameterReporterMock) Name() string {
 	return "Parameter Reporter Mock"
 }
 
-func (mock *ParameterReporterMock) Get(client io.Writer, message http.RequestMessage) {}
+func (mock *ParameterReporterMock) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	return nil
+}
 
 /* ReadOnlyResourceMock */
 
@@ -37,16 +38,18 @@ func (mock *ReadOnlyResourceMock) Name() string {
 	return "Readonly Mock"
 }
 
-func (mock *ReadOnlyResourceMock) Get(client io.Writer, message http.RequestMessage) {
+func (mock *ReadOnlyResourceMock) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.getCalled = true
+	return nil
 }
 
 func (mock *ReadOnlyResourceMock) GetShouldHaveBeenCalled() {
 	ExpectWithOffset(1, mock.getCalled).To(BeTrue())
 }
 
-func (mock *ReadOnlyResourceMock) Head(client io.Writer, message http.RequestMessage) {
+func (mock *ReadOnlyResourceMock) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.headCalled = true
+	return nil
 }
 
 func (mock *ReadOnlyResourceMock) HeadShouldHaveBeenCalled() {
@@ -66,32 +69,36 @@ func (mock *ReadWriteResourceMock) Name() string {
 	return "Read/Write Mock"
 }
 
-func (mock *ReadWriteResourceMock) Get(client io.Writer, message http.RequestMessage) {
+func (mock *ReadWriteResourceMock) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.getCalled = true
+	return nil
 }
 
 func (mock *ReadWriteResourceMock) GetShouldHaveBeenCalled() {
 	ExpectWithOffset(1, mock.getCalled).To(BeTrue())
 }
 
-func (mock *ReadWriteResourceMock) Head(client io.Writer, message http.RequestMessage) {
+func (mock *ReadWriteResourceMock) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.headCalled = true
+	return nil
 }
 
 func (mock *ReadWriteResourceMock) HeadShouldHaveBeenCalled() {
 	ExpectWithOffset(1, mock.headCalled).To(BeTrue())
 }
 
-func (mock *ReadWriteResourceMock) Post(client io.Writer, message http.RequestMessage) {
+func (mock *ReadWriteResourceMock) Post(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.postCalled = true
+	return nil
 }
 
 func (mock *ReadWriteResourceMock) PostShouldHaveBeenCalled() {
 	ExpectWithOffset(1, mock.postCalled).To(BeTrue())
 }
 
-func (mock *ReadWriteResourceMock) Put(client io.Writer, message http.RequestMessage) {
+func (mock *ReadWriteResourceMock) Put(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.putCalled = true
+	return nil
 }
 
 func (mock *ReadWriteResourceMock) PutShouldHaveBeenCalled() {
@@ -101,17 +108,17 @@ func (mock *ReadWriteResourceMock) PutShouldHaveBeenCalled() {
 /* Helpers */
 
 func invokeResourceMethod(invokeMethod httpResourceMethod, request http.RequestMessage) *httptest.ResponseMessage {
-	response := &bytes.Buffer{}
+	response := &httptest.ResponseBuffer{}
 	invokeMethod(response, request)
 	return httptest.ParseResponse(response)
 }
 
-type httpResourceMethod = func(io.Writer, http.RequestMessage)
+type httpResourceMethod = func(msg.ResponseWriter, http.RequestMessage) error
 
 func handleRequest(router http.Route, method, path string) {
 	requested := http.NewRequestMessage(method, path)
 	routedRequest := router.Route(requested)
 	ExpectWithOffset(1, routedRequest).NotTo(BeNil())
 
-	routedRequest.Handle(&bytes.Buffer{})
+	routedRequest.Handle(&httptest.ResponseBuffer{})
 }
@@ -1,9 +1,8 @@
 package playground
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/success"
 )
 
@@ -34,6 +33,7 @@ func (*NopPostResource) Name() string {
 	return "NOP Post"
 }
 
-func (resource *NopPostResource) Post(client io.Writer, message http.RequestMessage) {
+func (resource *NopPostResource) Post(client msg.ResponseWriter, message http.RequestMessage) error {
 	success.RespondOkWithoutBody(client)
+	return nil
 }
@@ -1,9 +1,8 @@
 package playground
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/success"
 )
 
@@ -34,6 +33,7 @@ func (*NopPutResource) Name() string {
 	return "NOP Put"
 }
 
-func (resource *NopPutResource) Put(client io.Writer, message http.RequestMessage) {
+func (resource *NopPutResource) Put(client msg.ResponseWriter, message http.RequestMessage) error {
 	success.RespondOkWithoutBody(client)
+	return nil
 }
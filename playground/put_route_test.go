@@ -1,8 +1,6 @@
 package playground_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -28,7 +26,7 @@ var _ = Describe("NopPutRoute", func() {
 
 		var (
 			router   http.Route
-			response = &bytes.Buffer{}
+			response = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
@@ -70,7 +68,7 @@ var _ = Describe("NopPutResource", func() {
 		request         *httptest.RequestMessage
 		responseMessage *httptest.ResponseMessage
 
-		response = &bytes.Buffer{}
+		response = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -1,9 +1,8 @@
 package playground
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/success"
 )
 
@@ -29,8 +28,8 @@ func (route *ReadOnlyRoute) Route(requested http.RequestMessage) http.Request {
 
 type ReadOnlyResource interface {
 	Name() string
-	Get(client io.Writer, message http.RequestMessage)
-	Head(client io.Writer, message http.RequestMessage)
+	Get(client msg.ResponseWriter, message http.RequestMessage) error
+	Head(client msg.ResponseWriter, message http.RequestMessage) error
 }
 
 // Handles various read requests, but doesn't actually do anything
@@ -40,10 +39,12 @@ func (controller *ReadableNopResource) Name() string {
 	return "Readonly NOP"
 }
 
-func (controller *ReadableNopResource) Get(client io.Writer, message http.RequestMessage) {
+func (controller *ReadableNopResource) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	controller.Head(client, message)
+	return nil
 }
 
-func (controller *ReadableNopResource) Head(client io.Writer, message http.RequestMessage) {
+func (controller *ReadableNopResource) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	success.RespondOkWithoutBody(client)
+	return nil
 }
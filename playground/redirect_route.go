@@ -1,8 +1,6 @@
 package playground
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/redirect"
@@ -21,7 +19,7 @@ type RedirectRoute struct {
 }
 
 func (route *RedirectRoute) Route(requested http.RequestMessage) http.Request {
-	if requested.Path() != "/redirect" {
+	if requested.Path() != route.Path {
 		return nil
 	}
 
@@ -38,9 +36,10 @@ func (*GoBackHomeResource) Name() string {
 	return "Relocated Resource"
 }
 
-func (*GoBackHomeResource) Get(client io.Writer, message http.RequestMessage) {
+func (*GoBackHomeResource) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	msg.WriteStatus(client, redirect.FoundStatus)
 	msg.WriteContentLengthHeader(client, 0)
 	msg.WriteHeader(client, "Location", "/")
 	msg.WriteEndOfMessageHeader(client)
+	return nil
 }
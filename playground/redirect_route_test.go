@@ -1,8 +1,6 @@
 package playground_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -21,22 +19,22 @@ var _ = Describe("::NewRedirectRoute", func() {
 })
 
 var _ = Describe("RedirectRoute", func() {
-	const configuredPath = "/redirect"
+	const configuredPath = "/overthere"
 
 	Describe("#Route", func() {
 		var (
 			router   http.Route
 			resource *playground.GoBackHomeResource
-			response = &bytes.Buffer{}
+			response = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
 			resource = &playground.GoBackHomeResource{}
-			router = &playground.RedirectRoute{Resource: resource}
+			router = &playground.RedirectRoute{Path: configuredPath, Resource: resource}
 			response.Reset()
 		})
 
-		Context("when the path is /redirect", func() {
+		Context("when the path is the configured path", func() {
 			It("routes GET to RelocatedResource#Get", func() {
 				requestMessage := &httptest.RequestMessage{
 					PathReturns:                configuredPath,
@@ -66,7 +64,9 @@ var _ = Describe("RedirectRoute", func() {
 			})
 		})
 
-		It("returns nil for any other path", func() {
+		It("returns nil for any other path, including /redirect", func() {
+			Expect(router.Route(http.NewGetMessage("/redirect"))).To(BeNil())
+
 			requestMessage := http.NewGetMessage("/no-route-for-you")
 			Expect(router.Route(requestMessage)).To(BeNil())
 		})
@@ -80,7 +80,7 @@ var _ = Describe("GoBackHomeResource", func() {
 			request         *httptest.RequestMessage
 			responseMessage *httptest.ResponseMessage
 
-			response = &bytes.Buffer{}
+			response = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
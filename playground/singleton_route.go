@@ -1,7 +1,6 @@
 package playground
 
 import (
-	"io"
 	"strings"
 
 	"github.com/kkrull/gohttp/http"
@@ -50,7 +49,7 @@ func (singleton *SingletonResource) Name() string {
 	return "Singleton"
 }
 
-func (singleton *SingletonResource) Delete(client io.Writer, message http.RequestMessage) {
+func (singleton *SingletonResource) Delete(client msg.ResponseWriter, message http.RequestMessage) error {
 	if !singleton.isRequestForData(message) {
 		clienterror.RespondMethodNotAllowed(client, collectionMethods)
 	} else if singleton.hasData() {
@@ -59,21 +58,26 @@ func (singleton *SingletonResource) Delete(client io.Writer, message http.Reques
 	} else {
 		clienterror.RespondNotFound(client, message.Path())
 	}
+
+	return nil
 }
 
-func (singleton *SingletonResource) Get(client io.Writer, message http.RequestMessage) {
+func (singleton *SingletonResource) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	if singleton.hasData() && singleton.isRequestForData(message) {
 		success.RespondOKWithKnownBody(client, "text/plain", singleton.data)
 	} else {
 		clienterror.RespondNotFound(client, message.Path())
 	}
+
+	return nil
 }
 
-func (singleton *SingletonResource) Options(client io.Writer, message http.RequestMessage) {
+func (singleton *SingletonResource) Options(client msg.ResponseWriter, message http.RequestMessage) error {
 	msg.RespondWithAllowHeader(client, success.OKStatus, singleton.allowedMethods(message.Path()))
+	return nil
 }
 
-func (singleton *SingletonResource) Post(client io.Writer, message http.RequestMessage) {
+func (singleton *SingletonResource) Post(client msg.ResponseWriter, message http.RequestMessage) error {
 	switch message.Path() {
 	case singleton.CollectionPath:
 		singleton.setData(message.Body())
@@ -83,9 +87,11 @@ func (singleton *SingletonResource) Post(client io.Writer, message http.RequestM
 	case singleton.dataPath():
 		clienterror.RespondMethodNotAllowed(client, dataMethods)
 	}
+
+	return nil
 }
 
-func (singleton *SingletonResource) Put(client io.Writer, message http.RequestMessage) {
+func (singleton *SingletonResource) Put(client msg.ResponseWriter, message http.RequestMessage) error {
 	switch message.Path() {
 	case singleton.CollectionPath:
 		clienterror.RespondMethodNotAllowed(client, collectionMethods)
@@ -93,6 +99,8 @@ func (singleton *SingletonResource) Put(client io.Writer, message http.RequestMe
 		singleton.setData(message.Body())
 		success.RespondOkWithoutBody(client)
 	}
+
+	return nil
 }
 
 func (singleton *SingletonResource) deleteData() {
@@ -128,5 +136,5 @@ func (singleton *SingletonResource) allowedMethods(path string) []string {
 
 var (
 	collectionMethods = []string{http.OPTIONS, http.POST}
-	dataMethods       = []string{http.DELETE, http.GET, http.OPTIONS, http.PUT}
+	dataMethods       = []string{http.DELETE, http.GET, http.HEAD, http.OPTIONS, http.PUT}
 )
@@ -1,8 +1,6 @@
 package playground_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -31,7 +29,7 @@ var _ = Describe("SingletonRoute", func() {
 	Describe("#Route", func() {
 		var (
 			router   http.Route
-			response = &bytes.Buffer{}
+			response = &httptest.ResponseBuffer{}
 		)
 
 		BeforeEach(func() {
@@ -78,6 +76,7 @@ var _ = Describe("SingletonRoute", func() {
 				It("allows methods to read and modify existing data", httptest.AllowedMethodsShouldBe(response,
 					http.DELETE,
 					http.GET,
+					http.HEAD,
 					http.OPTIONS,
 					http.PUT,
 				))
@@ -91,6 +90,10 @@ var _ = Describe("SingletonRoute", func() {
 				requested := http.NewGetMessage(dataPath)
 				Expect(router.Route(requested)).NotTo(BeNil())
 			})
+			It("routes HEAD requests", func() {
+				requested := http.NewHeadMessage(dataPath)
+				Expect(router.Route(requested)).NotTo(BeNil())
+			})
 			It("routes PUT requests", func() {
 				requested := http.NewPutMessage(dataPath)
 				Expect(router.Route(requested)).NotTo(BeNil())
@@ -115,7 +118,7 @@ var _ = Describe("SingletonResource", func() {
 		request         *httptest.RequestMessage
 		responseMessage *httptest.ResponseMessage
 
-		response = &bytes.Buffer{}
+		response = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -1,9 +1,8 @@
 package playground
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/success"
 )
 
@@ -29,10 +28,10 @@ func (route *ReadWriteRoute) Route(requested http.RequestMessage) http.Request {
 
 type ReadWriteResource interface {
 	Name() string
-	Get(client io.Writer, message http.RequestMessage)
-	Head(client io.Writer, message http.RequestMessage)
-	Post(client io.Writer, message http.RequestMessage)
-	Put(client io.Writer, message http.RequestMessage)
+	Get(client msg.ResponseWriter, message http.RequestMessage) error
+	Head(client msg.ResponseWriter, message http.RequestMessage) error
+	Post(client msg.ResponseWriter, message http.RequestMessage) error
+	Put(client msg.ResponseWriter, message http.RequestMessage) error
 }
 
 // Handles various read/write requests, but doesn't actually do anything
@@ -42,18 +41,22 @@ func (controller *ReadWriteNopResource) Name() string {
 	return "Read/Write NOP"
 }
 
-func (controller *ReadWriteNopResource) Get(client io.Writer, message http.RequestMessage) {
+func (controller *ReadWriteNopResource) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	controller.Head(client, message)
+	return nil
 }
 
-func (controller *ReadWriteNopResource) Head(client io.Writer, message http.RequestMessage) {
+func (controller *ReadWriteNopResource) Head(client msg.ResponseWriter, message http.RequestMessage) error {
 	success.RespondOkWithoutBody(client)
+	return nil
 }
 
-func (controller *ReadWriteNopResource) Post(client io.Writer, message http.RequestMessage) {
+func (controller *ReadWriteNopResource) Post(client msg.ResponseWriter, message http.RequestMessage) error {
 	success.RespondOkWithoutBody(client)
+	return nil
 }
 
-func (controller *ReadWriteNopResource) Put(client io.Writer, message http.RequestMessage) {
+func (controller *ReadWriteNopResource) Put(client msg.ResponseWriter, message http.RequestMessage) error {
 	success.RespondOkWithoutBody(client)
+	return nil
 }
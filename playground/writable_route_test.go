@@ -1,8 +1,6 @@
 package playground_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
@@ -52,7 +50,7 @@ var _ = Describe("ReadWriteRoute", func() {
 			})
 
 			Context("when the method is OPTIONS", func() {
-				var response = &bytes.Buffer{}
+				var response = &httptest.ResponseBuffer{}
 
 				BeforeEach(func() {
 					requested := http.NewOptionsMessage(configuredPath)
@@ -96,7 +94,7 @@ var _ = Describe("ReadWriteRoute", func() {
 var _ = Describe("ReadWriteNopResource", func() {
 	var (
 		controller *playground.ReadWriteNopResource
-		response   = &bytes.Buffer{}
+		response   = &httptest.ResponseBuffer{}
 	)
 
 	BeforeEach(func() {
@@ -1,8 +1,6 @@
 package teapot
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/msg"
 	"github.com/kkrull/gohttp/msg/success"
@@ -24,23 +22,24 @@ func (teapot *IdentityTeapot) RespondsTo(path string) bool {
 	}
 }
 
-func (teapot *IdentityTeapot) Get(client io.Writer, message http.RequestMessage) {
-	var beverageRequestHandlers = map[string]func(writer io.Writer){
+func (teapot *IdentityTeapot) Get(client msg.ResponseWriter, message http.RequestMessage) error {
+	var beverageRequestHandlers = map[string]func(writer msg.ResponseWriter){
 		"/coffee": teapot.getCoffee,
 		"/tea":    teapot.getTea,
 	}
 
 	handler := beverageRequestHandlers[message.Path()]
 	handler(client)
+	return nil
 }
 
-func (teapot *IdentityTeapot) getCoffee(client io.Writer) {
+func (teapot *IdentityTeapot) getCoffee(client msg.ResponseWriter) {
 	body := "I'm a teapot"
 	writeHeaders(client, body)
 	msg.WriteBody(client, body)
 }
 
-func writeHeaders(client io.Writer, body string) {
+func writeHeaders(client msg.ResponseWriter, body string) {
 	teapotStatus := msg.Status{Code: 418, Reason: "I'm a teapot"}
 	msg.WriteStatus(client, teapotStatus)
 	msg.WriteContentTypeHeader(client, "text/plain")
@@ -48,6 +47,6 @@ func writeHeaders(client io.Writer, body string) {
 	msg.WriteEndOfMessageHeader(client)
 }
 
-func (teapot *IdentityTeapot) getTea(client io.Writer) {
+func (teapot *IdentityTeapot) getTea(client msg.ResponseWriter) {
 	success.RespondOkWithoutBody(client)
 }
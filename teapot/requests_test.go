@@ -1,8 +1,6 @@
 package teapot_test
 
 import (
-	"bytes"
-
 	"github.com/kkrull/gohttp/http"
 	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/teapot"
@@ -14,7 +12,7 @@ var _ = Describe("IdentityTeapot", func() {
 	var (
 		theTeapot      teapot.Teapot
 		response       *httptest.ResponseMessage
-		responseBuffer *bytes.Buffer
+		responseBuffer *httptest.ResponseBuffer
 	)
 
 	Describe("#RespondsTo", func() {
@@ -32,7 +30,7 @@ var _ = Describe("IdentityTeapot", func() {
 	Describe("#Get", func() {
 		Context("when the path is /coffee", func() {
 			BeforeEach(func() {
-				responseBuffer = &bytes.Buffer{}
+				responseBuffer = &httptest.ResponseBuffer{}
 				theTeapot = &teapot.IdentityTeapot{}
 
 				theTeapot.Get(responseBuffer, http.NewGetMessage("/coffee"))
@@ -58,7 +56,7 @@ var _ = Describe("IdentityTeapot", func() {
 
 		Context("when the path is /tea", func() {
 			BeforeEach(func() {
-				responseBuffer = &bytes.Buffer{}
+				responseBuffer = &httptest.ResponseBuffer{}
 				theTeapot = &teapot.IdentityTeapot{}
 
 				theTeapot.Get(responseBuffer, http.NewGetMessage("/tea"))
@@ -1,9 +1,8 @@
 package teapot
 
 import (
-	"io"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 )
 
 func NewRoute() http.Route {
@@ -25,6 +24,6 @@ func (route *Route) Route(message http.RequestMessage) http.Request {
 
 type Teapot interface {
 	Name() string
-	Get(client io.Writer, message http.RequestMessage)
+	Get(client msg.ResponseWriter, message http.RequestMessage) error
 	RespondsTo(path string) bool
 }
@@ -1,9 +1,8 @@
 package teapot_test
 
 import (
-	"bufio"
-
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/httptest"
 	"github.com/kkrull/gohttp/msg/clienterror"
 	"github.com/kkrull/gohttp/teapot"
 	. "github.com/onsi/ginkgo"
@@ -28,14 +27,14 @@ var _ = Describe("teapotRoute", func() {
 			It("routes GET requests to that path to the teapot", func() {
 				requested = http.NewGetMessage("/caffeine")
 				routedRequest = router.Route(requested)
-				routedRequest.Handle(&bufio.Writer{})
+				routedRequest.Handle(&httptest.ResponseBuffer{})
 				teapotMock.GetShouldHaveReceived("/caffeine")
 			})
 
 			It("returns MethodNotAllowed for any other method", func() {
 				requested = http.NewTraceMessage("/caffeine")
 				routedRequest = router.Route(requested)
-				Expect(routedRequest).To(BeEquivalentTo(clienterror.MethodNotAllowed(http.GET, http.OPTIONS)))
+				Expect(routedRequest).To(BeEquivalentTo(clienterror.MethodNotAllowed(http.GET, http.HEAD, http.OPTIONS)))
 			})
 		})
 
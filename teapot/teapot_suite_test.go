@@ -1,10 +1,10 @@
 package teapot_test
 
 import (
-	"io"
 	"testing"
 
 	"github.com/kkrull/gohttp/http"
+	"github.com/kkrull/gohttp/msg"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -27,8 +27,9 @@ func (mock *TeapotMock) RespondsTo(path string) bool {
 	return mock.RespondsToPath == path
 }
 
-func (mock *TeapotMock) Get(client io.Writer, message http.RequestMessage) {
+func (mock *TeapotMock) Get(client msg.ResponseWriter, message http.RequestMessage) error {
 	mock.getPath = message.Path()
+	return nil
 }
 
 func (mock *TeapotMock) GetShouldHaveReceived(path string) {